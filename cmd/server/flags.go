@@ -1,15 +1,34 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/biisal/rowsql/internal/auth"
 	"github.com/fatih/color"
 )
 
-func perseFlags(command string) (envPath string) {
+func perseFlags(command string) (envPath string, hashPassword bool) {
 	pathInstruction := color.CyanString(fmt.Sprintf("Path to the environment file\nExample: %s -env=./env", command))
-	envPath = *flag.String("env", "", pathInstruction)
+	env := flag.String("env", "", pathInstruction)
+	hash := flag.Bool("hash-password", false, color.CyanString("Read a password from stdin and print its bcrypt hash for AUTH_PASSWORD_HASH"))
 	flag.Parse()
-	return
+	return *env, *hash
+}
+
+func printPasswordHash() error {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return err
+	}
+	hash, err := auth.HashPassword(strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
 }
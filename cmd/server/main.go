@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/biisal/rowsql/configs"
 	"github.com/biisal/rowsql/internal/logger"
@@ -15,7 +18,13 @@ var (
 func main() {
 	command := os.Args[0]
 
-	envPath := perseFlags(command)
+	envPath, hashPassword := perseFlags(command)
+	if hashPassword {
+		if err := printPasswordHash(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	printLogo(version)
 	cfg := configs.MustLoad(envPath)
 
@@ -23,7 +32,10 @@ func main() {
 		logger.ErrorWriteOnlyFile("Error while updating: %s", err)
 	}
 
-	if err := mount(cfg); err != nil {
+	// an interrupt stops the server after the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := mount(ctx, cfg); err != nil {
 		log.Fatal("Failed to mount app:", err)
 		return
 	}
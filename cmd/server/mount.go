@@ -2,20 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/biisal/rowsql/configs"
-	"github.com/biisal/rowsql/internal/database/queries"
-	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/connection"
 	"github.com/biisal/rowsql/internal/logger"
 	"github.com/biisal/rowsql/internal/router"
-	"github.com/biisal/rowsql/internal/service"
+	"github.com/biisal/rowsql/internal/store"
 	"github.com/biisal/rowsql/internal/utils"
 	"github.com/fatih/color"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v5/stdlib"
-	"github.com/jmoiron/sqlx"
 	_ "modernc.org/sqlite"
 )
 
@@ -31,9 +32,7 @@ func printLogo(version string) {
 	fmt.Println(logo)
 }
 
-func mount(cfg *configs.Config) error {
-	ctx := context.Background()
-
+func mount(ctx context.Context, cfg *configs.Config) error {
 	logFilePath, err := utils.ReplaceTildeWithHomeDir(cfg.LogFilePath)
 	if err != nil {
 		return err
@@ -50,31 +49,63 @@ func mount(cfg *configs.Config) error {
 
 	logger.Info("All logs will be written in %s", cfg.LogFilePath)
 
-	driver, err := utils.DetectDriver(&cfg.DBString)
-	if err != nil {
+	if cfg.Auth.RolesFile, err = utils.ReplaceTildeWithHomeDir(cfg.Auth.RolesFile); err != nil {
 		return err
 	}
-	logger.Info("Database driver detected: %s", driver)
-	cfg.Driver = driver
-	dbConn, err := sqlx.ConnectContext(ctx, string(driver), cfg.DBString)
+	authManager, err := auth.FromConfig(cfg.Auth)
 	if err != nil {
-		logger.Errorln("Failed to connect to database:", err)
+		logger.Errorln("Failed to set up authentication:", err)
 		return err
 	}
+	if !authManager.Enabled() {
+		logger.Info("Authentication is disabled, set AUTH_USERNAME or AUTH_TOKENS to protect the server")
+	}
 
-	queryBuilder := queries.NewBuilder(cfg.Driver, cfg.MaxItemsPerPage)
-
-	dbRepo := repo.New(dbConn, cfg.Driver, queryBuilder, cfg.MaxItemsPerPage)
+	if cfg.MetadataPath, err = utils.ReplaceTildeWithHomeDir(cfg.MetadataPath); err != nil {
+		return err
+	}
+	// an unreachable database fails the start instead of hanging it
+	startCtx, cancelStart := ctx, context.CancelFunc(func() {})
+	if cfg.Timeouts.Connect > 0 {
+		startCtx, cancelStart = context.WithTimeout(ctx, cfg.Timeouts.Connect)
+	}
+	defer cancelStart()
+	metadata, err := store.Open(startCtx, cfg.MetadataPath)
+	if err != nil {
+		logger.Errorln("Failed to open metadata store:", err)
+		return err
+	}
+	defer metadata.Close()
+	historyStore := metadata
+	if cfg.LegacyHistory {
+		historyStore = nil
+	}
 
-	if err = dbRepo.Init(ctx); err != nil {
-		logger.Errorln("Failed to initialize database repository:", err)
+	connections := connection.NewManager(cfg.MaxItemsPerPage, cfg.Count, cfg.Cache, cfg.Timeouts, authManager.Policy(), cfg.ReadOnly, historyStore)
+	defer connections.Close()
+	info, err := connections.Add(startCtx, connection.DefaultID, "", cfg.DBString)
+	if err != nil {
+		logger.Errorln("Failed to connect to database:", err)
 		return err
 	}
+	cfg.Driver = info.Driver
+	for _, c := range cfg.Connections {
+		if _, err := connections.Add(startCtx, c.ID, "", c.DBString); err != nil {
+			logger.Error("Failed to connect to database '%s': %s", c.ID, err)
+			return err
+		}
+	}
 
-	dbService := service.NewService(dbRepo, queryBuilder, cfg.MaxItemsPerPage)
-	dbHandler := router.NewHandler(dbService, cfg.MaxItemsPerPage)
+	dbHandler := router.NewHandler(connections, authManager, metadata, cfg.MaxItemsPerPage)
 
-	mux, err := router.MountRouter(dbHandler)
+	if cfg.ReadOnly {
+		logger.Info("Running in read-only mode, writes are disabled")
+	}
+	manageConnections := authManager.Enabled() || cfg.RuntimeConnections
+	if !manageConnections {
+		logger.Info("Connections can't be changed at runtime without a login, set ALLOW_RUNTIME_CONNECTIONS=true to allow it")
+	}
+	mux, err := router.MountRouter(dbHandler, cfg.ReadOnly, manageConnections)
 	corsMux := router.CORS()(mux)
 	if err != nil {
 		logger.Errorln("Failed to mount router:", err)
@@ -82,13 +113,29 @@ func mount(cfg *configs.Config) error {
 	}
 
 	server := http.Server{
-		Addr:    cfg.Server.Port,
-		Handler: corsMux,
+		Addr:              cfg.Server.Port,
+		Handler:           corsMux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.Timeouts.HTTPRead,
+		WriteTimeout:      cfg.Timeouts.HTTPWrite,
+		IdleTimeout:       2 * time.Minute,
 	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		logger.Info("Shutting down, waiting for the requests in flight")
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorln("Failed to shut down server:", err)
+		}
+	}()
 	logger.Success("Running server on port %s", cfg.Server.Port)
-	if err := server.ListenAndServe(); err != nil {
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 		logger.Errorln("Failed to start server:", err)
 		return err
 	}
+	<-stopped
 	return nil
 }
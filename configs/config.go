@@ -7,6 +7,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/biisal/rowsql/internal/logger"
 	"github.com/fatih/color"
@@ -39,15 +40,110 @@ type AutoUpdateConfig struct {
 	DisableAutoUpdate bool `env:"DISABLE_AUTO_UPDATE" env-default:"false"`
 }
 
+// ConnectionConfig is an extra named database next to the default DBSTRING one.
+type ConnectionConfig struct {
+	ID       string
+	DBString string
+}
+
+// AuthConfig enables the login when a username or API token is set.
+type AuthConfig struct {
+	Username string `env:"AUTH_USERNAME"`
+	// PasswordHash is a bcrypt hash, `rowsql -hash-password` prints one
+	PasswordHash string `env:"AUTH_PASSWORD_HASH"`
+	// UsersEnv holds more logins as `username=bcrypt hash` pairs separated by `;`
+	UsersEnv string `env:"AUTH_USERS"`
+	Users    map[string]string
+	// TokensEnv holds API tokens as `name=token` pairs separated by `;`
+	TokensEnv  string `env:"AUTH_TOKENS"`
+	Tokens     map[string]string
+	SessionTTL time.Duration `env:"AUTH_SESSION_TTL" env-default:"24h"`
+	// RolesFile is a JSON file with custom roles and the role of each user
+	RolesFile   string `env:"AUTH_ROLES_FILE" env-default:"~/.rowsql/roles.json"`
+	DefaultRole string `env:"AUTH_DEFAULT_ROLE" env-default:"viewer"`
+}
+
+// CountConfig decides how the rows of a table are counted for paging.
+type CountConfig struct {
+	// EstimateThreshold is the catalog estimate above which tables aren't
+	// counted exactly, 0 always counts exactly
+	EstimateThreshold int64 `env:"COUNT_ESTIMATE_THRESHOLD" env-default:"1000000"`
+	// CacheTTL is how long an exact count is reused
+	CacheTTL time.Duration `env:"COUNT_CACHE_TTL" env-default:"1m"`
+}
+
+// CacheConfig sizes the caches every connection keeps.
+type CacheConfig struct {
+	// SchemaTTL is how long tables, columns and indexes are reused, 0 reads
+	// them every time
+	SchemaTTL time.Duration `env:"SCHEMA_CACHE_TTL" env-default:"5m"`
+	// RowEntries and RowBytes bound the recently listed rows kept for forms
+	// and relations, 0 is unbounded
+	RowEntries int           `env:"ROW_CACHE_ENTRIES" env-default:"1000"`
+	RowBytes   int64         `env:"ROW_CACHE_BYTES" env-default:"16777216"`
+	RowTTL     time.Duration `env:"ROW_CACHE_TTL" env-default:"10m"`
+}
+
+// TimeoutConfig bounds how long database operations and requests may run, 0
+// disables a timeout. Exports and imports run as long as they need.
+type TimeoutConfig struct {
+	// Read bounds listing rows, counts, lookups and reading the schema
+	Read time.Duration `env:"DB_READ_TIMEOUT" env-default:"30s"`
+	// Write bounds row changes, batches and bulk edits
+	Write   time.Duration `env:"DB_WRITE_TIMEOUT" env-default:"30s"`
+	DDL     time.Duration `env:"DB_DDL_TIMEOUT" env-default:"5m"`
+	Console time.Duration `env:"DB_CONSOLE_TIMEOUT" env-default:"5m"`
+	// Statement is enforced by Postgres (statement_timeout) and MySQL
+	// (max_execution_time, SELECT only) for every statement but those of
+	// exports and imports. It defaults to the longest of the timeouts above,
+	// so a statement whose operation timed out doesn't keep running in the
+	// database.
+	Statement time.Duration `env:"DB_STATEMENT_TIMEOUT"`
+	// Connect bounds opening the databases on startup
+	Connect   time.Duration `env:"DB_CONNECT_TIMEOUT" env-default:"30s"`
+	HTTPRead  time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"1m"`
+	HTTPWrite time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"6m"`
+}
+
+// longestOperation is the longest time an operation may run, 0 when one of
+// them is unbounded.
+func (t TimeoutConfig) longestOperation() time.Duration {
+	var longest time.Duration
+	for _, timeout := range []time.Duration{t.Read, t.Write, t.DDL, t.Console} {
+		if timeout <= 0 {
+			return 0
+		}
+		longest = max(longest, timeout)
+	}
+	return longest
+}
+
 type Config struct {
-	DBString        string `env:"DBSTRING" env-required:"true"`
+	DBString string `env:"DBSTRING" env-required:"true"`
+	// ConnectionsEnv holds extra databases as `id=dbstring` pairs separated by `;`
+	ConnectionsEnv  string `env:"CONNECTIONS"`
+	Connections     []ConnectionConfig
 	Server          ServerConfig
 	Update          AutoUpdateConfig
+	Auth            AuthConfig
+	Count           CountConfig
+	Cache           CacheConfig
+	Timeouts        TimeoutConfig
 	Driver          Driver
-	MaxItemsPerPage int    `env:"MAX_ITEMS_PER_PAGE" env-default:"10"`
-	Env             string `env:"ENV" env-default:"production"`
-	LogFilePath     string `env:"LOG_FILE_PATH" env-default:"~/.rowsql/rowsql.log"`
-	Logo            string
+	MaxItemsPerPage int `env:"MAX_ITEMS_PER_PAGE" env-default:"10"`
+	// ReadOnly opens every database read-only and disables the routes that write
+	ReadOnly    bool   `env:"READ_ONLY" env-default:"false"`
+	Env         string `env:"ENV" env-default:"production"`
+	LogFilePath string `env:"LOG_FILE_PATH" env-default:"~/.rowsql/rowsql.log"`
+	// MetadataPath is the SQLite file for history, saved queries and preferences
+	MetadataPath string `env:"METADATA_PATH" env-default:"~/.rowsql/metadata.db"`
+	// LegacyHistory keeps the history in a rowsql_history table inside each database
+	LegacyHistory bool `env:"LEGACY_HISTORY" env-default:"false"`
+	// RuntimeConnections allows adding, changing and removing connections
+	// through the API without a login, with a login they're always allowed to
+	// users with the manage grant
+	RuntimeConnections bool `env:"ALLOW_RUNTIME_CONNECTIONS" env-default:"false"`
+	Logo               string
 }
 
 func promptForDefaultEnv(dir, fileName string) {
@@ -128,6 +224,27 @@ func MustLoad(envPath ...string) *Config {
 		logger.Error("%s not found in .env", tagVal)
 		os.Exit(1)
 	}
+	connections, err := parseConnections(cfg.ConnectionsEnv)
+	if err != nil {
+		logger.Error("invalid CONNECTIONS: %s", err)
+		os.Exit(1)
+	}
+	cfg.Connections = connections
+	if cfg.Auth.Tokens, err = parsePairMap(cfg.Auth.TokensEnv, "name=token"); err != nil {
+		logger.Error("invalid AUTH_TOKENS: %s", err)
+		os.Exit(1)
+	}
+	if cfg.Auth.Users, err = parsePairMap(cfg.Auth.UsersEnv, "username=hash"); err != nil {
+		logger.Error("invalid AUTH_USERS: %s", err)
+		os.Exit(1)
+	}
+	if cfg.Auth.Username != "" && cfg.Auth.PasswordHash == "" {
+		logger.Error("AUTH_PASSWORD_HASH is required when AUTH_USERNAME is set")
+		os.Exit(1)
+	}
+	if _, ok := os.LookupEnv("DB_STATEMENT_TIMEOUT"); !ok {
+		cfg.Timeouts.Statement = cfg.Timeouts.longestOperation()
+	}
 	if !strings.HasPrefix(cfg.Server.Port, ":") {
 		cfg.Server.Port = ":" + cfg.Server.Port
 	}
@@ -138,3 +255,45 @@ func MustLoad(envPath ...string) *Config {
 	}
 	return &cfg
 }
+
+func parseConnections(raw string) ([]ConnectionConfig, error) {
+	pairs, err := parsePairs(raw, "id=dbstring")
+	if err != nil {
+		return nil, err
+	}
+	connections := make([]ConnectionConfig, 0, len(pairs))
+	for _, pair := range pairs {
+		connections = append(connections, ConnectionConfig{ID: pair[0], DBString: pair[1]})
+	}
+	return connections, nil
+}
+
+func parsePairMap(raw, format string) (map[string]string, error) {
+	pairs, err := parsePairs(raw, format)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		m[pair[0]] = pair[1]
+	}
+	return m, nil
+}
+
+// parsePairs splits `key=value` pairs separated by `;`, format is only used in errors.
+func parsePairs(raw, format string) ([][2]string, error) {
+	var pairs [][2]string
+	for pair := range strings.SplitSeq(raw, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%q should look like %s", pair, format)
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs, nil
+}
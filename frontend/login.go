@@ -0,0 +1,31 @@
+package frontend
+
+import (
+	_ "embed"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+//go:embed login.html
+var loginHTML string
+
+// LoginHandler renders the login form, it posts to the login API which
+// redirects back to `next`.
+func LoginHandler() http.Handler {
+	tmpl, err := template.New("login").Parse(loginHTML)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := tmpl.Execute(w, struct {
+			Next   string
+			Failed bool
+		}{query.Get("next"), query.Get("error") != ""})
+		if err != nil {
+			log.Println(err)
+		}
+	})
+}
@@ -31,6 +31,7 @@ export function Layout() {
 				refreshing={tablesRefreshing}
 				isAppending={tableAppending}
 				tables={tables}
+				onReloadSchema={() => refreshTables(false, true)}
 			/>
 			<SidebarInset className="min-w-0 overflow-hidden">
 				<header className="flex h-16 shrink-0 items-center gap-2 border-b px-4">
@@ -1,10 +1,11 @@
 import * as React from 'react';
 import { Link, useLocation } from 'react-router-dom';
-import { Table as TableIcon, Plus, Clock } from 'lucide-react';
+import { Table as TableIcon, Plus, Clock, RefreshCw } from 'lucide-react';
 import {
 	Sidebar as ShadcnSidebar,
 	SidebarContent,
 	SidebarGroup,
+	SidebarGroupAction,
 	SidebarGroupContent,
 	SidebarGroupLabel,
 	SidebarHeader,
@@ -18,21 +19,35 @@ import { Skeleton } from './ui/skeleton';
 interface Table {
 	tableName: string;
 	tableSchema: string;
+	name: string;
 }
 
 interface SidebarProps extends React.ComponentProps<typeof ShadcnSidebar> {
 	tables: Table[];
 	refreshing: boolean;
 	isAppending: boolean;
+	onReloadSchema: () => void;
 }
 
 export function AppSidebar({
 	tables,
 	refreshing: loading,
 	isAppending,
+	onReloadSchema,
 	...props
 }: SidebarProps) {
 	const location = useLocation();
+	const [schema, setSchema] = React.useState('');
+	const schemas = React.useMemo(
+		() =>
+			Array.isArray(tables)
+				? [...new Set(tables.map((table) => table.tableSchema))]
+				: [],
+		[tables],
+	);
+	const visibleTables = schema
+		? tables.filter((table) => table.tableSchema === schema)
+		: tables;
 
 	const isActiveTable = (tableName: string) => {
 		return location.pathname === `/tables/${tableName}`;
@@ -61,22 +76,45 @@ export function AppSidebar({
 			<SidebarContent>
 				<SidebarGroup>
 					<SidebarGroupLabel>Tables</SidebarGroupLabel>
+					<SidebarGroupAction
+						title="Reload schema"
+						onClick={onReloadSchema}
+						disabled={loading}
+					>
+						<RefreshCw />
+						<span className="sr-only">Reload schema</span>
+					</SidebarGroupAction>
+					{schemas.length > 1 && (
+						<select
+							className="mx-2 mb-2 h-8 rounded-md border bg-transparent px-2 text-sm"
+							value={schema}
+							onChange={(e) => setSchema(e.target.value)}
+							aria-label="Schema"
+						>
+							<option value="">All schemas</option>
+							{schemas.map((name) => (
+								<option key={name} value={name}>
+									{name}
+								</option>
+							))}
+						</select>
+					)}
 					<SidebarGroupContent>
 						<SidebarMenu>
 							{isAppending && <SkeletonTables count={1} />}
 							{loading ? (
 								<SkeletonTables count={3} />
 							) : (
-								Array.isArray(tables) &&
-								tables.map((table) => (
-									<SidebarMenuItem key={table.tableName}>
+								Array.isArray(visibleTables) &&
+								visibleTables.map((table) => (
+									<SidebarMenuItem key={table.name}>
 										<SidebarMenuButton
 											asChild
-											isActive={isActiveTable(table.tableName)}
+											isActive={isActiveTable(table.name)}
 										>
-											<Link to={`/tables/${table.tableName}`}>
+											<Link to={`/tables/${table.name}`}>
 												<TableIcon />
-												<span>{table.tableName}</span>
+												<span>{table.name}</span>
 											</Link>
 										</SidebarMenuButton>
 									</SidebarMenuItem>
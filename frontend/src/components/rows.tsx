@@ -22,7 +22,7 @@ interface RowsProps {
 	isSomeSelected: boolean;
 	toggleAllSelection: () => void;
 	toggleRowSelection: (index: number) => void;
-	deleteRow: (hash: string) => void;
+	deleteRow: (hash: string, version?: string) => void;
 }
 
 export const Rows = ({
@@ -129,7 +129,7 @@ export const Rows = ({
 											<Button
 												variant="destructive"
 												className="w-full"
-												onClick={() => deleteRow(hash)}
+												onClick={() => deleteRow(hash, data.versions?.[rowIndex])}
 											>
 												Delete Row
 											</Button>
@@ -6,7 +6,10 @@ import axios, { AxiosError } from 'axios';
 
 interface TablesStore {
 	tables: Table[];
-	refreshTables: (isAppending?: boolean) => Promise<void>;
+	refreshTables: (
+		isAppending?: boolean,
+		reloadSchema?: boolean,
+	) => Promise<void>;
 	tablesRefreshing: boolean;
 	tableDeleting: boolean;
 	tableCreating: boolean;
@@ -86,7 +89,7 @@ const useTableStore = create<TablesStore>((set, get) => ({
 		}
 		return false;
 	},
-	refreshTables: async (isAppending) => {
+	refreshTables: async (isAppending, reloadSchema) => {
 		console.log('Refreshing tables...');
 		if (isAppending) {
 			set({ tableAppending: true });
@@ -94,7 +97,10 @@ const useTableStore = create<TablesStore>((set, get) => ({
 			set({ tablesRefreshing: true });
 		}
 		try {
-			const response = await api.get('/tables');
+			// the server caches the schema, reloading picks up changes made elsewhere
+			const response = reloadSchema
+				? await api.post('/schema/refresh')
+				: await api.get('/tables');
 			if (response.data.success && Array.isArray(response.data.data)) {
 				const tables = response.data.data;
 				set({ tables: tables });
@@ -5,6 +5,8 @@ export interface Column {
 export interface Table {
 	tableName: string;
 	tableSchema: string;
+	// schema qualified name used in routes
+	name: string;
 }
 
 export interface FormInputType {
@@ -54,4 +56,8 @@ export interface TableData {
 	rowCount: number;
 	hasNextPage: boolean;
 	totalPages: number;
+	rowCountEstimated: boolean;
+	versions: string[];
+	nextCursor?: string;
+	prevCursor?: string;
 }
@@ -260,7 +260,7 @@ export function Home() {
 							</Link>
 
 							{!tablesRefreshing && tables.length > 0 && (
-								<Link to={`/tables/${tables[0].tableName}`}>
+								<Link to={`/tables/${tables[0].name}`}>
 									<div className="flex items-center justify-between p-4 rounded-lg border border-border/50 hover:bg-muted/30 transition-colors cursor-pointer group">
 										<div className="flex items-center gap-4">
 											<div className="p-2 rounded-md bg-primary/10 text-primary group-hover:bg-primary group-hover:text-primary-foreground transition-colors">
@@ -39,6 +39,7 @@ interface Column {
 
 interface FormData {
 	Action: string;
+	Version: string;
 	Tables: unknown[];
 	Cols: Column[];
 	ActiveTable: string;
@@ -142,6 +143,7 @@ export function RowForm() {
 		interface Payload {
 			tableName: string;
 			data: RowData[]
+			version?: string;
 		}
 
 		try {
@@ -162,7 +164,8 @@ export function RowForm() {
 
 			const payload: Payload = {
 				tableName: tableName,
-				data: colList
+				data: colList,
+				version: formData.Version || undefined,
 			}
 			console.log('Payload:', payload);
 
@@ -176,6 +179,33 @@ export function RowForm() {
 			navigate(`/tables/${tableName}?page=${page}`);
 		} catch (err) {
 			console.error('Error saving row:', err);
+			const conflict = (
+				err as {
+					response?: {
+						status?: number;
+						data?: { data?: { current: Record<string, unknown> | null; version?: string } };
+					};
+				}
+			).response;
+			if (conflict?.status === 409 && conflict.data?.data?.current) {
+				// keep the edits, the next save is made against the current row
+				const current = conflict.data.data.current;
+				const changed = formData.Cols.filter(
+					(col) => String(current[col.columnName] ?? '') !== String(col.value ?? ''),
+				).map((col) => col.columnName);
+				setFormData({
+					...formData,
+					Version: conflict.data.data.version ?? '',
+					Cols: formData.Cols.map((col) => ({
+						...col,
+						value: current[col.columnName] as Column['value'],
+					})),
+				});
+				toast.error(
+					`Row was changed by someone else${changed.length ? ` (${changed.join(', ')})` : ''}, save again to overwrite it`,
+				);
+				return;
+			}
 			const errorMessage =
 				(err as { response?: { data?: { error?: string } }; message?: string })
 					.response?.data?.error ||
@@ -58,9 +58,11 @@ export function TableRows() {
 		})();
 	}, [fetchData]);
 
-	const deleteRow = async (hash: string) => {
+	const deleteRow = async (hash: string, version?: string) => {
 		try {
-			const res = await api.delete(`/tables/${tableName}/row/${hash}`);
+			const res = await api.delete(`/tables/${tableName}/row/${hash}`, {
+				params: version ? { version } : undefined,
+			});
 			if (res.data.success) {
 				toast.success('Row deleted successfully');
 				setRefesh((r) => r + 1);
@@ -68,7 +70,11 @@ export function TableRows() {
 			}
 			toast.error('Failed to delete row');
 		} catch (err) {
-			if (axios.isAxiosError(err)) {
+			if (axios.isAxiosError(err) && err.response?.status === 409) {
+				// the row changed since the page was loaded, show the current rows
+				toast.error(err.response.data?.error || 'Row was changed since it was loaded');
+				setRefesh((r) => r + 1);
+			} else if (axios.isAxiosError(err)) {
 				setError(
 					err.response?.data?.error || err.message || 'Something went wrong',
 				);
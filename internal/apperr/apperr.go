@@ -18,6 +18,12 @@ var (
 	ErrorInvalidJSON             = errors.New("invalid JSON")
 	ErrorInvalidPlaceHolderIndex = errors.New("invalid placeholder provided! should be grather than 0")
 	ErrorNotSameRowColsSize      = errors.New("cols and rows aren't same in length")
+	ErrorInvalidFilter           = errors.New("invalid filter")
+	ErrorInvalidAlter            = errors.New("invalid alter operation")
+	ErrorInvalidDataType         = errors.New("invalid data type")
+	ErrorInvalidIndex            = errors.New("invalid index")
+	ErrorPermissionDenied        = errors.New("permission denied")
+	ErrorInvalidSelection        = errors.New("rows must be selected either by keys or by a filter")
 )
 
 func ErrorLimitTooLarge(max int) error {
@@ -0,0 +1,222 @@
+// Package auth authenticates users with a password login backed by sessions
+// or with API tokens.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/biisal/rowsql/configs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrorInvalidCredentials = errors.New("invalid username or password")
+	ErrorUnauthenticated    = errors.New("authentication required")
+	ErrorLoginDisabled      = errors.New("password login is not configured")
+)
+
+type User struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// Authenticator checks login credentials. PasswordAuth is the built-in one,
+// others can be passed to NewManager.
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (User, error)
+}
+
+// PasswordAuth accepts users with bcrypt password hashes.
+type PasswordAuth struct {
+	users map[string][]byte
+	// dummy is compared for unknown users so they take as long as a wrong password
+	dummy []byte
+}
+
+// NewPasswordAuth takes the bcrypt hash of every username.
+func NewPasswordAuth(users map[string]string) (*PasswordAuth, error) {
+	p := &PasswordAuth{users: make(map[string][]byte, len(users))}
+	for username, hash := range users {
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bcrypt password hash for '%s': %w", username, err)
+		}
+		p.users[username] = []byte(hash)
+		if p.dummy == nil {
+			if p.dummy, err = bcrypt.GenerateFromPassword(nil, cost); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *PasswordAuth) Authenticate(_ context.Context, username, password string) (User, error) {
+	hash, ok := p.users[username]
+	if !ok {
+		hash = p.dummy
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return User{}, ErrorInvalidCredentials
+	}
+	return User{Name: username}, nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+type Session struct {
+	ID        string    `json:"-"`
+	User      User      `json:"user"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Manager keeps the login sessions in memory, they are lost on restart.
+type Manager struct {
+	authenticator Authenticator
+	policy        *Policy
+	// tokens are keyed by their sha256 so lookups don't leak timing
+	tokens map[[sha256.Size]byte]User
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewManager creates a Manager, authenticator may be nil to allow only
+// tokens. Without both authentication is disabled. Users get their role
+// from policy.
+func NewManager(authenticator Authenticator, tokens map[string]string, ttl time.Duration, policy *Policy) *Manager {
+	m := &Manager{
+		authenticator: authenticator,
+		policy:        policy,
+		tokens:        make(map[[sha256.Size]byte]User, len(tokens)),
+		ttl:           ttl,
+		sessions:      make(map[string]Session),
+	}
+	for name, token := range tokens {
+		m.tokens[sha256.Sum256([]byte(token))] = User{Name: name, Role: policy.RoleOf(name)}
+	}
+	return m
+}
+
+func FromConfig(cfg configs.AuthConfig) (*Manager, error) {
+	policy, err := LoadPolicy(cfg.RolesFile, cfg.DefaultRole)
+	if err != nil {
+		return nil, err
+	}
+	users := maps.Clone(cfg.Users)
+	if cfg.Username != "" {
+		if users == nil {
+			users = make(map[string]string, 1)
+		}
+		users[cfg.Username] = cfg.PasswordHash
+		// the single configured user owns the server unless the roles file says otherwise
+		policy.assign(cfg.Username, RoleAdmin)
+	}
+	var authenticator Authenticator
+	if len(users) > 0 {
+		passwordAuth, err := NewPasswordAuth(users)
+		if err != nil {
+			return nil, err
+		}
+		authenticator = passwordAuth
+	}
+	return NewManager(authenticator, cfg.Tokens, cfg.SessionTTL, policy), nil
+}
+
+func (m *Manager) Policy() *Policy {
+	return m.policy
+}
+
+func (m *Manager) Enabled() bool {
+	return m.authenticator != nil || len(m.tokens) > 0
+}
+
+// Login checks the credentials and starts a new session.
+func (m *Manager) Login(ctx context.Context, username, password string) (Session, error) {
+	if m.authenticator == nil {
+		return Session{}, ErrorLoginDisabled
+	}
+	user, err := m.authenticator.Authenticate(ctx, username, password)
+	if err != nil {
+		return Session{}, err
+	}
+	user.Role = m.policy.RoleOf(user.Name)
+	id, err := newSessionID()
+	if err != nil {
+		return Session{}, err
+	}
+	now := time.Now()
+	session := Session{ID: id, User: user, ExpiresAt: now.Add(m.ttl)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+	m.sessions[session.ID] = session
+	return session, nil
+}
+
+func (m *Manager) Logout(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, sessionID)
+}
+
+// Session returns the user of an unexpired session.
+func (m *Manager) Session(sessionID string) (User, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return User{}, false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(m.sessions, sessionID)
+		return User{}, false
+	}
+	return session.User, true
+}
+
+func (m *Manager) Token(token string) (User, bool) {
+	user, ok := m.tokens[sha256.Sum256([]byte(token))]
+	return user, ok
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type userCtxKey struct{}
+
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userCtxKey{}, user)
+}
+
+// UserFromContext returns the authenticated user, ok is false when
+// authentication is disabled.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userCtxKey{}).(User)
+	return user, ok
+}
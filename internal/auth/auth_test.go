@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPasswordAuth(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPasswordAuth(map[string]string{"admin": "plain"}); err == nil {
+		t.Fatal("expected an error for a hash that isn't bcrypt")
+	}
+	p, err := NewPasswordAuth(map[string]string{"admin": hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		username, password string
+		err                error
+	}{
+		{"admin", "secret", nil},
+		{"admin", "wrong", ErrorInvalidCredentials},
+		{"root", "secret", ErrorInvalidCredentials},
+	}
+	for _, tt := range tests {
+		user, err := p.Authenticate(context.Background(), tt.username, tt.password)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s/%s: got %v, want %v", tt.username, tt.password, err, tt.err)
+		}
+		if err == nil && user.Name != "admin" {
+			t.Errorf("got user %q, want admin", user.Name)
+		}
+	}
+}
+
+type staticAuth struct{}
+
+func (staticAuth) Authenticate(_ context.Context, username, password string) (User, error) {
+	if password != "ok" {
+		return User{}, ErrorInvalidCredentials
+	}
+	return User{Name: username}, nil
+}
+
+func TestManager(t *testing.T) {
+	policy, err := NewPolicy(PolicyConfig{Users: map[string]string{"jo": RoleEditor}}, RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NewManager(nil, nil, time.Hour, policy).Enabled() {
+		t.Error("manager without authenticator and tokens should be disabled")
+	}
+	if _, err := NewManager(nil, map[string]string{"ci": "t"}, time.Hour, policy).Login(context.Background(), "a", "ok"); !errors.Is(err, ErrorLoginDisabled) {
+		t.Errorf("got %v, want %v", err, ErrorLoginDisabled)
+	}
+
+	m := NewManager(staticAuth{}, map[string]string{"ci": "token"}, time.Hour, policy)
+	if !m.Enabled() {
+		t.Fatal("manager should be enabled")
+	}
+	if user, ok := m.Token("token"); !ok || user != (User{Name: "ci", Role: RoleViewer}) {
+		t.Errorf("token: got %v %t, want ci viewer", user, ok)
+	}
+	if _, ok := m.Token("other"); ok {
+		t.Error("unknown token accepted")
+	}
+
+	if _, err := m.Login(context.Background(), "jo", "bad"); !errors.Is(err, ErrorInvalidCredentials) {
+		t.Errorf("got %v, want %v", err, ErrorInvalidCredentials)
+	}
+	session, err := m.Login(context.Background(), "jo", "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := m.Session(session.ID); !ok || user != (User{Name: "jo", Role: RoleEditor}) {
+		t.Errorf("session: got %v %t, want jo editor", user, ok)
+	}
+	m.Logout(session.ID)
+	if _, ok := m.Session(session.ID); ok {
+		t.Error("session still valid after logout")
+	}
+
+	expired := NewManager(staticAuth{}, nil, -time.Second, policy)
+	session, err = expired.Login(context.Background(), "jo", "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := expired.Session(session.ID); ok {
+		t.Error("expired session accepted")
+	}
+}
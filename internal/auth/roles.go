@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/biisal/rowsql/internal/apperr"
+)
+
+type Operation string
+
+const (
+	OpRead   Operation = "read"
+	OpInsert Operation = "insert"
+	OpUpdate Operation = "update"
+	OpDelete Operation = "delete"
+	OpDDL    Operation = "ddl"
+	OpSQL    Operation = "sql"
+	// OpManage covers adding, changing and removing connections.
+	OpManage Operation = "manage"
+)
+
+var operations = []Operation{OpRead, OpInsert, OpUpdate, OpDelete, OpDDL, OpSQL, OpManage}
+
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var ErrorInvalidPolicy = errors.New("invalid roles file")
+
+// Grant allows operations on the connections and tables matching its glob
+// patterns, an empty list matches all of them. Raw SQL and other operations
+// not bound to one table only match grants covering every table.
+type Grant struct {
+	Connections []string    `json:"connections,omitempty"`
+	Tables      []string    `json:"tables,omitempty"`
+	Operations  []Operation `json:"operations"`
+}
+
+func (g Grant) allows(connID, table string, op Operation) bool {
+	return slices.Contains(g.Operations, op) && matchAny(g.Connections, connID) && matchAny(g.Tables, table)
+}
+
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+		if name == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var builtinRoles = map[string][]Grant{
+	RoleViewer: {{Operations: []Operation{OpRead}}},
+	RoleEditor: {{Operations: []Operation{OpRead, OpInsert, OpUpdate, OpDelete}}},
+	RoleAdmin:  {{Operations: operations}},
+}
+
+// PolicyConfig is the content of the roles file.
+type PolicyConfig struct {
+	// Roles are custom roles next to viewer, editor and admin
+	Roles map[string][]Grant `json:"roles"`
+	// Users maps user and token names to their role
+	Users map[string]string `json:"users"`
+}
+
+// Policy resolves the role of users and checks their grants.
+type Policy struct {
+	roles       map[string][]Grant
+	users       map[string]string
+	defaultRole string
+}
+
+// NewPolicy validates cfg, users without an assigned role get defaultRole.
+func NewPolicy(cfg PolicyConfig, defaultRole string) (*Policy, error) {
+	p := &Policy{
+		roles:       make(map[string][]Grant, len(builtinRoles)+len(cfg.Roles)),
+		users:       make(map[string]string, len(cfg.Users)),
+		defaultRole: defaultRole,
+	}
+	for name, grants := range builtinRoles {
+		p.roles[name] = grants
+	}
+	for name, grants := range cfg.Roles {
+		if _, ok := builtinRoles[name]; ok {
+			return nil, fmt.Errorf("%w: role '%s' is built in", ErrorInvalidPolicy, name)
+		}
+		for _, g := range grants {
+			for _, op := range g.Operations {
+				if !slices.Contains(operations, op) {
+					return nil, fmt.Errorf("%w: unknown operation '%s' in role '%s'", ErrorInvalidPolicy, op, name)
+				}
+			}
+			for _, pattern := range append(slices.Clone(g.Connections), g.Tables...) {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("%w: bad pattern '%s' in role '%s'", ErrorInvalidPolicy, pattern, name)
+				}
+			}
+		}
+		p.roles[name] = grants
+	}
+	for user, role := range cfg.Users {
+		if _, ok := p.roles[role]; !ok {
+			return nil, fmt.Errorf("%w: unknown role '%s' for '%s'", ErrorInvalidPolicy, role, user)
+		}
+		p.users[user] = role
+	}
+	if _, ok := p.roles[defaultRole]; !ok {
+		return nil, fmt.Errorf("%w: unknown default role '%s'", ErrorInvalidPolicy, defaultRole)
+	}
+	return p, nil
+}
+
+// LoadPolicy reads a roles file, a missing file only has the built-in roles.
+func LoadPolicy(filePath, defaultRole string) (*Policy, error) {
+	var cfg PolicyConfig
+	data, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrorInvalidPolicy, err)
+		}
+	}
+	return NewPolicy(cfg, defaultRole)
+}
+
+// assign gives name a role unless the roles file already does.
+func (p *Policy) assign(name, role string) {
+	if _, ok := p.users[name]; !ok {
+		p.users[name] = role
+	}
+}
+
+func (p *Policy) RoleOf(name string) string {
+	if role, ok := p.users[name]; ok {
+		return role
+	}
+	return p.defaultRole
+}
+
+func (p *Policy) Allowed(user User, connID, table string, op Operation) bool {
+	for _, g := range p.roles[user.Role] {
+		if g.allows(connID, table, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess reports whether the user may do anything with table, an empty
+// table checks for any grant on the connection.
+func (p *Policy) CanAccess(ctx context.Context, connID, table string) bool {
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return true
+	}
+	for _, g := range p.roles[user.Role] {
+		if matchAny(g.Connections, connID) && (table == "" || matchAny(g.Tables, table)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks the user of ctx, requests without a user are allowed
+// since authentication is disabled then.
+func (p *Policy) Authorize(ctx context.Context, connID, table string, op Operation) error {
+	user, ok := UserFromContext(ctx)
+	if !ok || p.Allowed(user, connID, table, op) {
+		return nil
+	}
+	if table == "" {
+		return fmt.Errorf("%w: '%s' can't %s on '%s'", apperr.ErrorPermissionDenied, user.Name, op, connID)
+	}
+	return fmt.Errorf("%w: '%s' can't %s on '%s'", apperr.ErrorPermissionDenied, user.Name, op, table)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/biisal/rowsql/internal/apperr"
+)
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  PolicyConfig
+	}{
+		{name: "builtin role", cfg: PolicyConfig{Roles: map[string][]Grant{RoleAdmin: {}}}},
+		{name: "unknown operation", cfg: PolicyConfig{Roles: map[string][]Grant{"x": {{Operations: []Operation{"drop"}}}}}},
+		{name: "bad pattern", cfg: PolicyConfig{Roles: map[string][]Grant{"x": {{Tables: []string{"["}, Operations: []Operation{OpRead}}}}}},
+		{name: "unknown user role", cfg: PolicyConfig{Users: map[string]string{"jo": "x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolicy(tt.cfg, RoleViewer); !errors.Is(err, ErrorInvalidPolicy) {
+				t.Errorf("got %v, want %v", err, ErrorInvalidPolicy)
+			}
+		})
+	}
+	if _, err := NewPolicy(PolicyConfig{}, "x"); !errors.Is(err, ErrorInvalidPolicy) {
+		t.Errorf("unknown default role: got %v, want %v", err, ErrorInvalidPolicy)
+	}
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	policy, err := NewPolicy(PolicyConfig{
+		Roles: map[string][]Grant{
+			"analyst": {
+				{Tables: []string{"sales_*"}, Operations: []Operation{OpRead}},
+				{Connections: []string{"staging"}, Tables: []string{"sales_raw"}, Operations: []Operation{OpInsert}},
+			},
+		},
+	}, RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		role    string
+		connID  string
+		table   string
+		op      Operation
+		allowed bool
+	}{
+		{name: "viewer reads", role: RoleViewer, connID: "default", table: "users", op: OpRead, allowed: true},
+		{name: "viewer can't delete", role: RoleViewer, connID: "default", table: "users", op: OpDelete},
+		{name: "editor deletes", role: RoleEditor, connID: "default", table: "users", op: OpDelete, allowed: true},
+		{name: "editor can't drop", role: RoleEditor, connID: "default", table: "users", op: OpDDL},
+		{name: "admin runs sql", role: RoleAdmin, connID: "default", op: OpSQL, allowed: true},
+		{name: "pattern read", role: "analyst", connID: "default", table: "sales_2024", op: OpRead, allowed: true},
+		{name: "outside pattern", role: "analyst", connID: "default", table: "users", op: OpRead},
+		{name: "scoped to connection", role: "analyst", connID: "staging", table: "sales_raw", op: OpInsert, allowed: true},
+		{name: "other connection", role: "analyst", connID: "default", table: "sales_raw", op: OpInsert},
+		{name: "table pattern doesn't cover all tables", role: "analyst", connID: "default", op: OpRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithUser(context.Background(), User{Name: "jo", Role: tt.role})
+			err := policy.Authorize(ctx, tt.connID, tt.table, tt.op)
+			if tt.allowed && err != nil {
+				t.Errorf("expected allowed, got %s", err)
+			}
+			if !tt.allowed && !errors.Is(err, apperr.ErrorPermissionDenied) {
+				t.Errorf("got %v, want %v", err, apperr.ErrorPermissionDenied)
+			}
+		})
+	}
+
+	if err := policy.Authorize(context.Background(), "default", "users", OpDDL); err != nil {
+		t.Errorf("requests without a user should be allowed, got %s", err)
+	}
+	analyst := WithUser(context.Background(), User{Name: "jo", Role: "analyst"})
+	if !policy.CanAccess(analyst, "default", "sales_eu") || policy.CanAccess(analyst, "default", "users") {
+		t.Error("CanAccess should follow the table patterns")
+	}
+}
@@ -0,0 +1,239 @@
+// Package connection manages the named databases rowsql is connected to, each
+// with its own driver, query builder, repository and row cache.
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/database/queries"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/biisal/rowsql/internal/service"
+	"github.com/biisal/rowsql/internal/store"
+	"github.com/biisal/rowsql/internal/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultID is the ID of the connection configured with DBSTRING.
+const DefaultID = "default"
+
+var (
+	ErrorNotFound      = errors.New("connection not found")
+	ErrorExists        = errors.New("connection already exists")
+	ErrorInvalidID     = errors.New("invalid connection id, only letters, digits and '_' are allowed")
+	ErrorDeleteDefault = errors.New("default connection can't be removed")
+)
+
+// Info describes a connection to clients, the connection string is never
+// exposed since it usually contains credentials.
+type Info struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Driver    configs.Driver `json:"driver"`
+	IsDefault bool           `json:"isDefault"`
+}
+
+type conn struct {
+	info    Info
+	db      *sqlx.DB
+	service service.DBService
+}
+
+type Manager struct {
+	mu              sync.RWMutex
+	conns           map[string]*conn
+	order           []string
+	maxItemsPerPage int
+	counts          configs.CountConfig
+	cache           configs.CacheConfig
+	timeouts        configs.TimeoutConfig
+	policy          *auth.Policy
+	readOnly        bool
+	store           *store.Store
+}
+
+// NewManager creates a Manager whose services enforce policy, nil allows
+// everything. With readOnly every database is opened in read-only sessions.
+// The history is kept in historyStore, nil keeps it inside each database.
+// Every call to a service is bounded by timeouts.
+func NewManager(maxItemsPerPage int, counts configs.CountConfig, cache configs.CacheConfig, timeouts configs.TimeoutConfig, policy *auth.Policy, readOnly bool, historyStore *store.Store) *Manager {
+	return &Manager{
+		conns:           make(map[string]*conn),
+		maxItemsPerPage: maxItemsPerPage,
+		counts:          counts,
+		cache:           cache,
+		timeouts:        timeouts,
+		policy:          policy,
+		readOnly:        readOnly,
+		store:           historyStore,
+	}
+}
+
+// open connects to dbString and wires a service for it.
+func (m *Manager) open(ctx context.Context, info Info, dbString string) (*conn, error) {
+	driver, err := utils.DetectDriver(&dbString)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Database driver detected for '%s': %s", info.ID, driver)
+	if m.readOnly {
+		if dbString, err = utils.ReadOnlyDBString(driver, dbString); err != nil {
+			return nil, err
+		}
+	}
+	if dbString, err = utils.StatementTimeoutDBString(driver, dbString, m.timeouts.Statement); err != nil {
+		return nil, err
+	}
+	db, err := sqlx.ConnectContext(ctx, string(driver), dbString)
+	if err != nil {
+		logger.Errorln("Failed to connect to database:", err)
+		return nil, err
+	}
+
+	queryBuilder := queries.NewBuilder(driver, m.maxItemsPerPage)
+	var history repo.HistoryStore
+	if m.store != nil {
+		history = m.store.History(info.ID)
+	}
+	dbRepo := repo.New(db, driver, queryBuilder, m.maxItemsPerPage, m.counts, m.cache, m.readOnly, history)
+	if err = dbRepo.Init(ctx); err != nil {
+		logger.Errorln("Failed to initialize database repository:", err)
+		closeDB(db)
+		return nil, err
+	}
+
+	info.Driver = driver
+	svc := service.NewService(dbRepo, queryBuilder, m.maxItemsPerPage)
+	return &conn{
+		info:    info,
+		db:      db,
+		service: service.WithPolicy(service.WithTimeouts(svc, m.timeouts), info.ID, queryBuilder, m.policy),
+	}, nil
+}
+
+func closeDB(db *sqlx.DB) {
+	if err := db.Close(); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+// Add opens a new connection. The name defaults to the ID.
+func (m *Manager) Add(ctx context.Context, id, name, dbString string) (Info, error) {
+	if !utils.IsSafeIdentifier(id) {
+		return Info{}, ErrorInvalidID
+	}
+	if name == "" {
+		name = id
+	}
+	m.mu.RLock()
+	_, exists := m.conns[id]
+	m.mu.RUnlock()
+	if exists {
+		return Info{}, ErrorExists
+	}
+
+	c, err := m.open(ctx, Info{ID: id, Name: name, IsDefault: id == DefaultID}, dbString)
+	if err != nil {
+		return Info{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.conns[id]; exists {
+		closeDB(c.db)
+		return Info{}, ErrorExists
+	}
+	m.conns[id] = c
+	m.order = append(m.order, id)
+	return c.info, nil
+}
+
+// Update renames a connection and reconnects it when dbString is set.
+func (m *Manager) Update(ctx context.Context, id, name, dbString string) (Info, error) {
+	m.mu.RLock()
+	old, ok := m.conns[id]
+	m.mu.RUnlock()
+	if !ok {
+		return Info{}, ErrorNotFound
+	}
+	info := old.info
+	if name != "" {
+		info.Name = name
+	}
+	if dbString == "" {
+		m.mu.Lock()
+		old.info = info
+		m.mu.Unlock()
+		return info, nil
+	}
+
+	c, err := m.open(ctx, info, dbString)
+	if err != nil {
+		return Info{}, err
+	}
+	m.mu.Lock()
+	if _, ok := m.conns[id]; !ok {
+		m.mu.Unlock()
+		closeDB(c.db)
+		return Info{}, ErrorNotFound
+	}
+	m.conns[id] = c
+	m.mu.Unlock()
+	closeDB(old.db)
+	return c.info, nil
+}
+
+func (m *Manager) Remove(id string) error {
+	if id == DefaultID {
+		return ErrorDeleteDefault
+	}
+	m.mu.Lock()
+	c, ok := m.conns[id]
+	if ok {
+		delete(m.conns, id)
+		m.order = slices.DeleteFunc(m.order, func(o string) bool { return o == id })
+	}
+	m.mu.Unlock()
+	if !ok {
+		return ErrorNotFound
+	}
+	closeDB(c.db)
+	return nil
+}
+
+// Service returns the service of a connection.
+func (m *Manager) Service(id string) (service.DBService, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.conns[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrorNotFound, id)
+	}
+	return c.service, nil
+}
+
+func (m *Manager) List() []Info {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	items := make([]Info, 0, len(m.order))
+	for _, id := range m.order {
+		items = append(items, m.conns[id].info)
+	}
+	return items
+}
+
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.conns {
+		closeDB(c.db)
+	}
+	m.conns = make(map[string]*conn)
+	m.order = nil
+}
@@ -0,0 +1,29 @@
+package database
+
+type AlterAction string
+
+const (
+	AlterAddColumn    AlterAction = "add_column"
+	AlterDropColumn   AlterAction = "drop_column"
+	AlterRenameColumn AlterAction = "rename_column"
+	AlterModifyColumn AlterAction = "modify_column"
+	AlterRenameTable  AlterAction = "rename_table"
+)
+
+// AlterOp is a single change applied to an existing table.
+//
+//   - add_column uses Input
+//   - drop_column uses Column
+//   - rename_column uses Column and NewName
+//   - modify_column uses Column and any of DataType, IsNull, Default, DropDefault
+//   - rename_table uses NewName
+type AlterOp struct {
+	Action      AlterAction `json:"action"`
+	Column      string      `json:"column,omitempty"`
+	NewName     string      `json:"newName,omitempty"`
+	Input       *Input      `json:"input,omitempty"`
+	DataType    *DataType   `json:"dataType,omitempty"`
+	IsNull      *bool       `json:"isNull,omitempty"`
+	Default     *string     `json:"default,omitempty"`
+	DropDefault bool        `json:"dropDefault,omitempty"`
+}
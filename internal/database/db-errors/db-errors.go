@@ -2,6 +2,7 @@
 package dberrors
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -103,6 +104,10 @@ func UserFriendlyError(err error) (int, error) {
 		return http.StatusInternalServerError, errors.New("database transaction has already been committed or rolled back")
 	}
 
+	if IsTimeout(err) {
+		return http.StatusGatewayTimeout, errors.New("database operation timed out - please try again")
+	}
+
 	// Check error message for common patterns
 	errMsg := err.Error()
 	errMsgLower := strings.ToLower(errMsg)
@@ -160,6 +165,22 @@ func UserFriendlyError(err error) (int, error) {
 	return http.StatusInternalServerError, errors.New("an unexpected database error occurred - please try again later")
 }
 
+// IsTimeout reports whether err comes from an operation that ran out of time,
+// either its context deadline or the statement timeout of the database.
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	errMsgLower := strings.ToLower(err.Error())
+	// Postgres: canceling statement due to statement timeout,
+	// MySQL: maximum statement execution time exceeded
+	return strings.Contains(errMsgLower, "statement timeout") ||
+		strings.Contains(errMsgLower, "maximum statement execution time exceeded")
+}
+
 // Helper functions for common error scenarios
 
 // NotFound creates a "not found" error for a specific resource
@@ -282,6 +303,10 @@ func HandleError(err error) (int, error) {
 		return http.StatusInternalServerError, errors.New("transaction already completed. please start a new one")
 	}
 
+	if IsTimeout(err) {
+		return http.StatusGatewayTimeout, errors.New("query timed out. try simplifying your query or increasing timeout")
+	}
+
 	// Check error message for database-related patterns
 	errMsg := err.Error()
 	errMsgLower := strings.ToLower(errMsg)
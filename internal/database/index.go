@@ -0,0 +1,11 @@
+package database
+
+// IndexInput describes an index to create. Name is generated from the table
+// and columns when empty, Method and Where are only supported by some drivers.
+type IndexInput struct {
+	Name     string   `json:"name"`
+	Columns  []string `json:"columns"`
+	IsUnique bool     `json:"isUnique"`
+	Method   string   `json:"method,omitempty"`
+	Where    string   `json:"where,omitempty"`
+}
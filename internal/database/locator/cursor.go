@@ -0,0 +1,67 @@
+package locator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/utils"
+)
+
+var ErrorInvalidCursor = errors.New("invalid cursor")
+
+// Ordered reports whether rows can be paged by their key, which needs a key
+// that is unique, never NULL and doesn't change with the row.
+func (l Locator) Ordered() bool {
+	return l.Kind == KindPrimaryKey || l.Kind == KindRowID
+}
+
+type cursorToken struct {
+	Table   string   `json:"t"`
+	Columns []string `json:"c"`
+	Column  string   `json:"o,omitempty"`
+	Desc    bool     `json:"d,omitempty"`
+	Values  []any    `json:"v"`
+	Before  bool     `json:"b,omitempty"`
+}
+
+// EncodeCursor turns a keyset position into an opaque URL safe token, it's
+// bound to the table and its key like row keys.
+func (l Locator) EncodeCursor(tableName string, cursor models.Cursor) (string, error) {
+	data, err := json.Marshal(cursorToken{
+		Table:   tableName,
+		Columns: l.Columns,
+		Column:  cursor.Column,
+		Desc:    cursor.Desc,
+		Values:  cursor.Values,
+		Before:  cursor.Before,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor parses a token produced by EncodeCursor.
+func (l Locator) DecodeCursor(tableName, token string) (models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.Cursor{}, ErrorInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var c cursorToken
+	if err := dec.Decode(&c); err != nil || c.Table != tableName || len(c.Values) == 0 {
+		return models.Cursor{}, ErrorInvalidCursor
+	}
+	if !l.Ordered() || !slices.Equal(c.Columns, l.Columns) {
+		return models.Cursor{}, ErrorStaleKey
+	}
+	for i, v := range c.Values {
+		c.Values[i] = utils.NormalizeNumber(v)
+	}
+	return models.Cursor{Column: c.Column, Desc: c.Desc, Values: c.Values, Before: c.Before}, nil
+}
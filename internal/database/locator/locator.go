@@ -0,0 +1,158 @@
+// Package locator identifies table rows by their key so they can be fetched,
+// updated or deleted with a single indexed lookup.
+package locator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/utils"
+)
+
+type Kind string
+
+const (
+	// KindPrimaryKey locates rows by the table's primary key columns.
+	KindPrimaryKey Kind = "pk"
+	// KindUnique locates rows by a NOT NULL column with a unique constraint of
+	// its own when there is no primary key.
+	KindUnique Kind = "unique"
+	// KindCtid locates rows by the physical Postgres ctid.
+	KindCtid Kind = "ctid"
+	// KindRowID locates rows by the SQLite rowid.
+	KindRowID Kind = "rowid"
+	// KindRow matches every column of the row, used only when nothing better exists.
+	KindRow Kind = "row"
+)
+
+var (
+	ErrorInvalidKey = errors.New("invalid row key")
+	ErrorStaleKey   = errors.New("row key does not match the table anymore, please reload the rows")
+)
+
+type Locator struct {
+	Kind    Kind
+	Columns []string
+}
+
+// Discover picks the best way to address rows of a table from its column metadata.
+func Discover(driver configs.Driver, cols []models.ListDataCol) Locator {
+	var pk []string
+	for _, col := range cols {
+		if col.IsPrimaryKey {
+			pk = append(pk, col.ColumnName)
+		}
+	}
+	if len(pk) > 0 {
+		return Locator{Kind: KindPrimaryKey, Columns: pk}
+	}
+
+	// a nullable key matches every row where it's NULL
+	for _, col := range cols {
+		if col.IsUniqueKey && !col.IsNullable {
+			return Locator{Kind: KindUnique, Columns: []string{col.ColumnName}}
+		}
+	}
+
+	switch driver {
+	case configs.DriverPostgres:
+		return Locator{Kind: KindCtid, Columns: []string{"ctid"}}
+	case configs.DriverSQLite:
+		return Locator{Kind: KindRowID, Columns: []string{"rowid"}}
+	}
+
+	all := make([]string, 0, len(cols))
+	for _, col := range cols {
+		// json values can't be compared with a plain equality
+		if col.InputType == "json" || strings.HasPrefix(strings.ToLower(col.DataType), "json") {
+			continue
+		}
+		all = append(all, col.ColumnName)
+	}
+	return Locator{Kind: KindRow, Columns: all}
+}
+
+// PseudoColumn returns the select expression of a hidden key column that has to
+// be fetched in front of the table columns, or "" when the key is part of the row.
+func (l Locator) PseudoColumn() string {
+	switch l.Kind {
+	case KindCtid:
+		return "ctid::text"
+	case KindRowID:
+		return "rowid"
+	}
+	return ""
+}
+
+// Values extracts the key values from a scanned row. For pseudo column
+// locators the row must still contain the pseudo column at index 0.
+func (l Locator) Values(cols []models.ListDataCol, row []any) ([]any, error) {
+	if l.PseudoColumn() != "" {
+		if len(row) == 0 {
+			return nil, ErrorInvalidKey
+		}
+		return []any{row[0]}, nil
+	}
+	values := make([]any, 0, len(l.Columns))
+	for _, name := range l.Columns {
+		idx := slices.IndexFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == name })
+		if idx < 0 || idx >= len(row) {
+			return nil, fmt.Errorf("%w: column %s not found in row", ErrorInvalidKey, name)
+		}
+		values = append(values, row[idx])
+	}
+	return values, nil
+}
+
+type Key struct {
+	Table   string   `json:"t"`
+	Kind    Kind     `json:"k"`
+	Columns []string `json:"c"`
+	Values  []any    `json:"v"`
+}
+
+// Encode turns a row key into an opaque URL safe token.
+func (l Locator) Encode(tableName string, values []any) (string, error) {
+	data, err := json.Marshal(Key{
+		Table:   tableName,
+		Kind:    l.Kind,
+		Columns: l.Columns,
+		Values:  values,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decode parses a token produced by Encode and checks that it still matches
+// the locator of the table.
+func (l Locator) Decode(tableName, token string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrorInvalidKey
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var key Key
+	if err := dec.Decode(&key); err != nil {
+		return nil, ErrorInvalidKey
+	}
+	if key.Table != tableName || len(key.Values) != len(key.Columns) {
+		return nil, ErrorInvalidKey
+	}
+	if key.Kind != l.Kind || !slices.Equal(key.Columns, l.Columns) {
+		return nil, ErrorStaleKey
+	}
+	for i, v := range key.Values {
+		key.Values[i] = utils.NormalizeNumber(v)
+	}
+	return key.Values, nil
+}
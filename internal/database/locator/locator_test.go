@@ -0,0 +1,153 @@
+package locator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		cols   []models.ListDataCol
+		want   Locator
+	}{
+		{
+			name:   "composite primary key",
+			driver: configs.DriverMySQL,
+			cols: []models.ListDataCol{
+				{ColumnName: "org_id", IsPrimaryKey: true, IsUnique: true},
+				{ColumnName: "email", IsUnique: true, IsUniqueKey: true},
+				{ColumnName: "user_id", IsPrimaryKey: true},
+			},
+			want: Locator{Kind: KindPrimaryKey, Columns: []string{"org_id", "user_id"}},
+		},
+		{
+			name:   "unique column without primary key",
+			driver: configs.DriverPostgres,
+			cols: []models.ListDataCol{
+				{ColumnName: "name"},
+				{ColumnName: "email", IsUnique: true, IsUniqueKey: true},
+			},
+			want: Locator{Kind: KindUnique, Columns: []string{"email"}},
+		},
+		{
+			name:   "column of a composite unique constraint",
+			driver: configs.DriverPostgres,
+			cols: []models.ListDataCol{
+				{ColumnName: "org_id", IsUnique: true},
+				{ColumnName: "email", IsUnique: true},
+			},
+			want: Locator{Kind: KindCtid, Columns: []string{"ctid"}},
+		},
+		{
+			name:   "nullable unique column",
+			driver: configs.DriverSQLite,
+			cols: []models.ListDataCol{
+				{ColumnName: "email", IsUnique: true, IsUniqueKey: true, IsNullable: true},
+			},
+			want: Locator{Kind: KindRowID, Columns: []string{"rowid"}},
+		},
+		{
+			name:   "skips a nullable unique column",
+			driver: configs.DriverMySQL,
+			cols: []models.ListDataCol{
+				{ColumnName: "email", DataType: "varchar", IsUnique: true, IsUniqueKey: true, IsNullable: true},
+				{ColumnName: "code", DataType: "varchar", IsUnique: true, IsUniqueKey: true},
+			},
+			want: Locator{Kind: KindUnique, Columns: []string{"code"}},
+		},
+		{
+			name:   "postgres falls back to ctid",
+			driver: configs.DriverPostgres,
+			cols:   []models.ListDataCol{{ColumnName: "name"}},
+			want:   Locator{Kind: KindCtid, Columns: []string{"ctid"}},
+		},
+		{
+			name:   "sqlite falls back to rowid",
+			driver: configs.DriverSQLite,
+			cols:   []models.ListDataCol{{ColumnName: "name"}},
+			want:   Locator{Kind: KindRowID, Columns: []string{"rowid"}},
+		},
+		{
+			name:   "mysql falls back to the whole row without json columns",
+			driver: configs.DriverMySQL,
+			cols: []models.ListDataCol{
+				{ColumnName: "name", DataType: "varchar"},
+				{ColumnName: "meta", DataType: "json"},
+			},
+			want: Locator{Kind: KindRow, Columns: []string{"name"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Discover(tt.driver, tt.cols)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	loc := Locator{Kind: KindPrimaryKey, Columns: []string{"id", "name"}}
+	token, err := loc.Encode("users", []any{int64(9007199254740993), "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loc.Decode("users", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{int64(9007199254740993), "bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v want %#v", got, want)
+	}
+
+	if _, err := loc.Decode("orders", token); !errors.Is(err, ErrorInvalidKey) {
+		t.Errorf("expected %v for another table but got %v", ErrorInvalidKey, err)
+	}
+	other := Locator{Kind: KindCtid, Columns: []string{"ctid"}}
+	if _, err := other.Decode("users", token); !errors.Is(err, ErrorStaleKey) {
+		t.Errorf("expected %v for a changed locator but got %v", ErrorStaleKey, err)
+	}
+	if _, err := loc.Decode("users", "not a token"); !errors.Is(err, ErrorInvalidKey) {
+		t.Errorf("expected %v for garbage but got %v", ErrorInvalidKey, err)
+	}
+}
+
+func TestEncodeDecodeCursor(t *testing.T) {
+	loc := Locator{Kind: KindPrimaryKey, Columns: []string{"id"}}
+	cursor := models.Cursor{Column: "age", Desc: true, Values: []any{nil, int64(42)}, Before: true}
+	token, err := loc.EncodeCursor("users", cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loc.DecodeCursor("users", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cursor) {
+		t.Errorf("got %#v want %#v", got, cursor)
+	}
+
+	if _, err := loc.DecodeCursor("orders", token); !errors.Is(err, ErrorInvalidCursor) {
+		t.Errorf("expected %v for another table but got %v", ErrorInvalidCursor, err)
+	}
+	rowid := Locator{Kind: KindRowID, Columns: []string{"rowid"}}
+	if _, err := rowid.DecodeCursor("users", token); !errors.Is(err, ErrorStaleKey) {
+		t.Errorf("expected %v for a changed key but got %v", ErrorStaleKey, err)
+	}
+	ctid := Locator{Kind: KindCtid, Columns: []string{"id"}}
+	if _, err := ctid.DecodeCursor("users", token); !errors.Is(err, ErrorStaleKey) {
+		t.Errorf("expected %v for an unordered key but got %v", ErrorStaleKey, err)
+	}
+	if _, err := loc.DecodeCursor("users", "not a cursor"); !errors.Is(err, ErrorInvalidCursor) {
+		t.Errorf("expected %v for garbage but got %v", ErrorInvalidCursor, err)
+	}
+}
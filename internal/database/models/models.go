@@ -3,22 +3,101 @@ package models
 
 import "time"
 
+// ListDataCol describes a table column, IsUniqueKey is set only when a unique
+// constraint or index covers the column alone. Generated columns are computed
+// from the row, identity columns only take values with OVERRIDING SYSTEM VALUE.
 type ListDataCol struct {
 	IsUnique         bool   `json:"isUnique"`
+	IsUniqueKey      bool   `json:"isUniqueKey"`
+	IsNullable       bool   `json:"isNullable"`
+	IsPrimaryKey     bool   `json:"isPrimaryKey"`
+	IsGenerated      bool   `json:"isGenerated"`
+	IsIdentity       bool   `json:"isIdentity"`
 	Value            any    `json:"value"`
 	ColumnName       string `json:"columnName"`
 	DataType         string `json:"dataType"`
 	InputType        string `json:"inputType"`
 	HasAutoIncrement bool   `json:"hasAutoIncrement"`
 	HasDefault       bool   `json:"hasDefault"`
+	RefTable         string `json:"refTable,omitempty"`
+	RefColumn        string `json:"refColumn,omitempty"`
 }
 
 type ListDataProps struct {
-	TableName string `json:"tableName"`
-	Limit     int    `json:"limit"`
-	Offset    int    `json:"offset"`
-	Column    string `json:"column"`
-	Order     string `json:"order"`
+	TableName string  `json:"tableName"`
+	Limit     int     `json:"limit"`
+	Offset    int     `json:"offset"`
+	Column    string  `json:"column"`
+	Order     string  `json:"order"`
+	Filter    *Filter `json:"filter,omitempty"`
+}
+
+// Cursor is a position of keyset paging, Values are the sort column value
+// followed by the key of the row a page starts after, or ends before with
+// Before. Without values it's the first page.
+type Cursor struct {
+	Column string
+	Desc   bool
+	Values []any
+	Before bool
+}
+
+// RowsPage is a page of rows read with keyset paging, the cursors are empty
+// when there is no page in that direction.
+type RowsPage struct {
+	Rows       ListDataRow
+	NextCursor string
+	PrevCursor string
+	// Keyset is false when the table has no key to page by, the rows were
+	// read with offset paging instead
+	Keyset bool
+}
+
+// CacheStats describes the row cache of a connection.
+type CacheStats struct {
+	Entries    int   `json:"entries"`
+	Bytes      int64 `json:"bytes"`
+	Tables     int   `json:"tables"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Evictions  int64 `json:"evictions"`
+	MaxEntries int   `json:"maxEntries"`
+	MaxBytes   int64 `json:"maxBytes"`
+}
+
+// RowCount is the number of rows of a table, Estimated counts come from the
+// database catalog and may be off.
+type RowCount struct {
+	Count     int  `json:"count"`
+	Estimated bool `json:"estimated"`
+}
+
+type FilterOp string
+
+const (
+	FilterEq      FilterOp = "eq"
+	FilterNeq     FilterOp = "neq"
+	FilterLt      FilterOp = "lt"
+	FilterLte     FilterOp = "lte"
+	FilterGt      FilterOp = "gt"
+	FilterGte     FilterOp = "gte"
+	FilterBetween FilterOp = "between"
+	FilterIn      FilterOp = "in"
+	FilterLike    FilterOp = "like"
+	FilterILike   FilterOp = "ilike"
+	FilterIsNull  FilterOp = "isnull"
+	FilterNotNull FilterOp = "notnull"
+)
+
+// Filter is either a single condition on a column or a group of filters
+// combined with AND / OR.
+type Filter struct {
+	Column string   `json:"column,omitempty"`
+	Op     FilterOp `json:"op,omitempty"`
+	Value  any      `json:"value,omitempty"`
+	Values []any    `json:"values,omitempty"`
+	And    []Filter `json:"and,omitempty"`
+	Or     []Filter `json:"or,omitempty"`
 }
 
 type ListDataRow []any
@@ -41,19 +120,279 @@ type RowItem struct {
 type InsertRowRequest struct {
 	TableName string    `json:"tableName"`
 	Data      []RowItem `json:"data"`
+	// Version of the row being updated as it was loaded
+	Version string `json:"version,omitempty"`
 }
 type InsertDataProps struct {
 	TableName string
 	Values    []RowItem
 }
 
+// ListTablesRow is a table of the database, Name is how it's addressed in
+// routes: "schema.table" for Postgres tables outside the public schema and the
+// plain table name otherwise.
 type ListTablesRow struct {
 	TableSchema string `json:"tableSchema"`
 	TableName   string `json:"tableName"`
+	Name        string `json:"name"`
 }
 
+const (
+	OperationInsert      = "insert"
+	OperationUpdate      = "update"
+	OperationDelete      = "delete"
+	OperationImport      = "import"
+	OperationCreateTable = "create_table"
+	OperationAlterTable  = "alter_table"
+	OperationDropTable   = "drop_table"
+	OperationCreateIndex = "create_index"
+	OperationDropIndex   = "drop_index"
+	OperationQuery       = "query"
+)
+
+// History is one audit log entry. Entries moved from the legacy
+// rowsql_history table only have a message and time.
 type History struct {
-	ID      int       `json:"id"`
-	Message string    `json:"message"`
-	Time    time.Time `json:"time"`
+	ID        int            `json:"id"`
+	Message   string         `json:"message"`
+	Time      time.Time      `json:"time"`
+	Operation string         `json:"operation,omitempty"`
+	TableName string         `json:"tableName,omitempty"`
+	Query     string         `json:"query,omitempty"`
+	Args      []any          `json:"args,omitempty"`
+	OldRow    map[string]any `json:"oldRow,omitempty"`
+	NewValues map[string]any `json:"newValues,omitempty"`
+	User      string         `json:"user,omitempty"`
+	ClientIP  string         `json:"clientIp,omitempty"`
+	Duration  float64        `json:"durationMs"`
+	Success   bool           `json:"success"`
+	Error     string         `json:"error,omitempty"`
+	// RevertOf is the id of the entry this one reverted
+	RevertOf int `json:"revertOf,omitempty"`
+}
+
+// HistoryFilter narrows the audit log, zero values match everything.
+type HistoryFilter struct {
+	TableName string
+	Operation string
+	User      string
+	From      time.Time
+	To        time.Time
+	RevertOf  int
+}
+
+func (f HistoryFilter) IsZero() bool {
+	return f == HistoryFilter{}
+}
+
+// BatchOperation is one row change of a batch. Key is the row key of updates
+// and deletes, Version optionally the version of the row they were made against.
+type BatchOperation struct {
+	Operation string    `json:"operation"`
+	TableName string    `json:"tableName"`
+	Key       string    `json:"key,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	Data      []RowItem `json:"data,omitempty"`
+}
+
+type BatchOperationResult struct {
+	Index        int    `json:"index"`
+	Operation    string `json:"operation"`
+	TableName    string `json:"tableName"`
+	Success      bool   `json:"success"`
+	RowsAffected int64  `json:"rowsAffected"`
+	Error        string `json:"error,omitempty"`
+}
+
+// BatchResult lists the operations that ran, when one failed it's the last
+// one and nothing was committed.
+type BatchResult struct {
+	Committed bool                   `json:"committed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// BulkProps selects rows of a table by their keys or by a filter. Values are
+// only used by updates.
+type BulkProps struct {
+	TableName    string
+	Operation    string
+	Keys         []string
+	Filter       *Filter
+	Values       []RowItem
+	Confirmation string
+}
+
+// BulkResult is the preview of a bulk operation or its outcome. The
+// confirmation of a preview has to be sent back to run the statement.
+type BulkResult struct {
+	TableName    string `json:"tableName"`
+	Operation    string `json:"operation"`
+	Count        int64  `json:"count"`
+	Query        string `json:"query"`
+	Args         []any  `json:"args"`
+	Confirmation string `json:"confirmation,omitempty"`
+	Executed     bool   `json:"executed"`
+	RowsAffected int64  `json:"rowsAffected"`
+}
+
+// RevertResult is the statement that undoes a history entry.
+type RevertResult struct {
+	EntryID   int    `json:"entryId"`
+	TableName string `json:"tableName"`
+	// Operation is what the revert does to the row
+	Operation string `json:"operation"`
+	Query     string `json:"query"`
+	Args      []any  `json:"args"`
+	Executed  bool   `json:"executed"`
+}
+
+type SavedQuery struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Query       string    `json:"query" db:"query"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type QueryColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type QueryResult struct {
+	ID           string        `json:"id"`
+	Columns      []QueryColumn `json:"columns"`
+	Rows         []ListDataRow `json:"rows"`
+	RowsAffected int64         `json:"rowsAffected"`
+	Truncated    bool          `json:"truncated"`
+	Duration     float64       `json:"durationMs"`
+}
+
+// TableColumn is the full definition of a column as stored in the catalog,
+// used when a SQLite table has to be rebuilt or a MySQL column redefined.
+// Default is an SQL expression.
+type TableColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default *string
+	PK      int
+	Unique  bool
+	// Extra holds the MySQL attributes following the default, like
+	// auto_increment or on update CURRENT_TIMESTAMP.
+	Extra   string
+	Comment string
+}
+
+// ForeignKey is a foreign key constraint, RefColumns is empty when it
+// references the primary key of RefTable implicitly.
+type ForeignKey struct {
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnUpdate   string
+	OnDelete   string
+}
+
+type TableDefinition struct {
+	Name        string
+	Columns     []TableColumn
+	ForeignKeys []ForeignKey
+	// Uniques holds the UNIQUE constraints over more than one column, those of
+	// a single column are set on the column.
+	Uniques       [][]string
+	AutoIncrement bool
+	// Options are the table options after the column list, like WITHOUT ROWID.
+	Options string
+	// Extras holds the CREATE INDEX / CREATE TRIGGER statements of the table.
+	Extras []string
+}
+
+type Index struct {
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns"`
+	IsUnique  bool     `json:"isUnique"`
+	IsPrimary bool     `json:"isPrimary"`
+	Method    string   `json:"method"`
+	// Predicate is the WHERE condition of a partial index.
+	Predicate  string `json:"predicate,omitempty"`
+	Definition string `json:"definition,omitempty"`
+}
+
+// Reference is a foreign key column of Table pointing to RefColumn of another table.
+type Reference struct {
+	Table     string `json:"table"`
+	Column    string `json:"column"`
+	RefColumn string `json:"refColumn"`
+}
+
+// RelatedRows are the rows of Table whose Column matches LocalColumn of the
+// row the relations were requested for. Filter selects the same rows when
+// listing Table.
+type RelatedRows struct {
+	Table       string        `json:"table"`
+	Column      string        `json:"column"`
+	LocalColumn string        `json:"localColumn"`
+	Filter      *Filter       `json:"filter"`
+	Cols        []ListDataCol `json:"cols"`
+	Rows        ListDataRow   `json:"rows"`
+	RowCount    int           `json:"rowCount"`
+}
+
+type RowRelations struct {
+	Parents  []RelatedRows `json:"parents"`
+	Children []RelatedRows `json:"children"`
+}
+
+// LookupProps selects candidate values of Column from TableName, labelled by
+// LabelColumn and narrowed by Search.
+type LookupProps struct {
+	TableName   string
+	Column      string
+	LabelColumn string
+	Search      string
+	Limit       int
+	Offset      int
+}
+
+type LookupOption struct {
+	Value any `json:"value"`
+	Label any `json:"label"`
+}
+
+type LookupResult struct {
+	Table       string         `json:"table"`
+	Column      string         `json:"column"`
+	LabelColumn string         `json:"labelColumn"`
+	Options     []LookupOption `json:"options"`
+	RowCount    int            `json:"rowCount"`
+	HasNextPage bool           `json:"hasNextPage"`
+}
+
+// ExportProps selects the rows of a table to export. Every column is exported
+// when Columns is empty, Column and Order sort like in ListDataProps.
+type ExportProps struct {
+	TableName string
+	Columns   []string
+	Column    string
+	Order     string
+	Filter    *Filter
+}
+
+// ImportRowError points at a record of an imported file, Row counts records
+// starting at 1 without the CSV header.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportResult struct {
+	Total      int              `json:"total"`
+	Inserted   int              `json:"inserted"`
+	ErrorCount int              `json:"errorCount"`
+	Errors     []ImportRowError `json:"errors"`
+	DryRun     bool             `json:"dryRun"`
+	Committed  bool             `json:"committed"`
 }
@@ -0,0 +1,577 @@
+package queries
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+var (
+	validIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	validDataType   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9 _]*$`)
+)
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func formatDataType(dt database.DataType) (string, error) {
+	if !validDataType.MatchString(dt.Type) {
+		return "", fmt.Errorf("%w: %q", apperr.ErrorInvalidDataType, dt.Type)
+	}
+	if dt.HasSize && dt.Size > 0 {
+		return fmt.Sprintf("%s(%d)", dt.Type, dt.Size), nil
+	}
+	return dt.Type, nil
+}
+
+// NeedsRebuild reports whether op can't be expressed with ALTER TABLE and the
+// table has to be recreated instead. Only SQLite has such limitations.
+func (b *Builder) NeedsRebuild(op database.AlterOp) bool {
+	if b.driver != configs.DriverSQLite {
+		return false
+	}
+	switch op.Action {
+	case database.AlterDropColumn, database.AlterModifyColumn:
+		return true
+	case database.AlterAddColumn:
+		return op.Input != nil && (op.Input.IsPK || op.Input.IsUnique)
+	}
+	return false
+}
+
+func (b *Builder) validateAlter(op database.AlterOp, cols []models.ListDataCol) error {
+	hasCol := func(name string) bool {
+		return slices.ContainsFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == name })
+	}
+	switch op.Action {
+	case database.AlterAddColumn:
+		if op.Input == nil || op.Input.ColName == "" {
+			return fmt.Errorf("%w: add_column needs an input", apperr.ErrorInvalidAlter)
+		}
+		if !validIdentifier.MatchString(op.Input.ColName) {
+			return fmt.Errorf("%w: invalid column name %q", apperr.ErrorInvalidAlter, op.Input.ColName)
+		}
+		if hasCol(op.Input.ColName) {
+			return fmt.Errorf("%w: column %s already exists", apperr.ErrorInvalidAlter, op.Input.ColName)
+		}
+		if !validDataType.MatchString(op.Input.DataType.Type) {
+			return fmt.Errorf("%w: %q", apperr.ErrorInvalidDataType, op.Input.DataType.Type)
+		}
+	case database.AlterDropColumn, database.AlterRenameColumn, database.AlterModifyColumn:
+		if !hasCol(op.Column) {
+			return fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, op.Column)
+		}
+		if op.Action == database.AlterDropColumn && len(cols) == 1 {
+			return fmt.Errorf("%w: can't drop the only column of a table", apperr.ErrorInvalidAlter)
+		}
+		if op.Action == database.AlterRenameColumn {
+			if !validIdentifier.MatchString(op.NewName) {
+				return fmt.Errorf("%w: invalid column name %q", apperr.ErrorInvalidAlter, op.NewName)
+			}
+			if hasCol(op.NewName) {
+				return fmt.Errorf("%w: column %s already exists", apperr.ErrorInvalidAlter, op.NewName)
+			}
+		}
+		if op.Action == database.AlterModifyColumn && op.DataType == nil && op.IsNull == nil &&
+			op.Default == nil && !op.DropDefault {
+			return fmt.Errorf("%w: nothing to modify", apperr.ErrorInvalidAlter)
+		}
+		if op.Default != nil && op.DropDefault {
+			return fmt.Errorf("%w: can't set and drop the default at once", apperr.ErrorInvalidAlter)
+		}
+	case database.AlterRenameTable:
+		if !validIdentifier.MatchString(op.NewName) {
+			return fmt.Errorf("%w: invalid table name %q", apperr.ErrorInvalidAlter, op.NewName)
+		}
+	default:
+		return fmt.Errorf("%w: unknown action %q", apperr.ErrorInvalidAlter, op.Action)
+	}
+	return nil
+}
+
+// AlterTable builds the statements applying op to tableName. Operations for
+// which NeedsRebuild is true have to go through RebuildTable instead.
+func (b *Builder) AlterTable(tableName string, op database.AlterOp, cols []models.ListDataCol) ([]string, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return nil, err
+	}
+	if err := b.validateAlter(op, cols); err != nil {
+		return nil, err
+	}
+	if b.NeedsRebuild(op) {
+		return nil, fmt.Errorf("%w: %s needs a table rebuild on %s", apperr.ErrorInvalidAlter, op.Action, b.driver)
+	}
+	table, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+	column, err := b.quoteIdentifier(op.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Action {
+	case database.AlterAddColumn:
+		def, err := b.formatColumnDefinition(*op.Input)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, def)}, nil
+	case database.AlterDropColumn:
+		return []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)}, nil
+	case database.AlterRenameColumn:
+		return []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, column, op.NewName)}, nil
+	case database.AlterRenameTable:
+		return []string{fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, op.NewName)}, nil
+	case database.AlterModifyColumn:
+		if b.driver == configs.DriverMySQL {
+			return nil, fmt.Errorf("%w: MySQL needs the current column definition, see MySQLModifyColumn", apperr.ErrorInvalidAlter)
+		}
+		return b.postgresModifyColumn(table, column, op)
+	}
+	return nil, apperr.ErrorInvalidAlter
+}
+
+func (b *Builder) postgresModifyColumn(table, column string, op database.AlterOp) ([]string, error) {
+	var changes []string
+	if op.DataType != nil {
+		dataType, err := formatDataType(*op.DataType)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", column, dataType, column, dataType))
+	}
+	if op.IsNull != nil {
+		if *op.IsNull {
+			changes = append(changes, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", column))
+		} else {
+			changes = append(changes, fmt.Sprintf("ALTER COLUMN %s SET NOT NULL", column))
+		}
+	}
+	if op.Default != nil {
+		changes = append(changes, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %s", column, quoteLiteral(*op.Default)))
+	}
+	if op.DropDefault {
+		changes = append(changes, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", column))
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s %s", table, strings.Join(changes, ", "))}, nil
+}
+
+const mysqlColumnQuery = `
+SELECT
+    c.column_name,
+    c.column_type,
+    (c.is_nullable = 'NO') AS not_null,
+    CASE
+        WHEN c.column_default IS NULL THEN NULL
+        WHEN c.data_type = 'bit'
+             OR c.column_default = 'CURRENT_TIMESTAMP'
+             OR c.column_default LIKE 'CURRENT_TIMESTAMP(%' THEN c.column_default
+        WHEN c.extra LIKE '%DEFAULT_GENERATED%' THEN CONCAT('(', c.column_default, ')')
+        ELSE QUOTE(c.column_default)
+    END AS column_default,
+    TRIM(REPLACE(c.extra, 'DEFAULT_GENERATED', '')) AS extra,
+    c.column_comment
+FROM information_schema.columns c
+WHERE c.table_schema = DATABASE()
+  AND c.table_name = ?
+  AND c.column_name = ?;
+`
+
+// MySQLColumn returns the query reading the definition of a MySQL column as
+// a models.TableColumn without PK and Unique, see MySQLModifyColumn.
+func (b *Builder) MySQLColumn(tableName, column string) (string, []any, error) {
+	if b.driver != configs.DriverMySQL {
+		return "", nil, apperr.ErrorInvalidDriver
+	}
+	return mysqlColumnQuery, []any{tableName, column}, nil
+}
+
+// MySQLModifyColumn builds the MODIFY COLUMN of op. MySQL replaces the whole
+// column definition, so it starts from current and only changes what op sets.
+func (b *Builder) MySQLModifyColumn(tableName string, op database.AlterOp, cols []models.ListDataCol, current models.TableColumn) ([]string, error) {
+	if b.driver != configs.DriverMySQL || op.Action != database.AlterModifyColumn {
+		return nil, fmt.Errorf("%w: %s isn't a MySQL column modification", apperr.ErrorInvalidAlter, op.Action)
+	}
+	if err := b.validateAlter(op, cols); err != nil {
+		return nil, err
+	}
+	if strings.Contains(strings.ToUpper(current.Extra), "GENERATED") {
+		return nil, fmt.Errorf("%w: generated columns can't be modified", apperr.ErrorInvalidAlter)
+	}
+	table, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+	column, err := b.quoteIdentifier(op.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	dataType := current.Type
+	if op.DataType != nil {
+		if dataType, err = formatDataType(*op.DataType); err != nil {
+			return nil, err
+		}
+	}
+	notNull := current.NotNull
+	if op.IsNull != nil {
+		notNull = !*op.IsNull
+	}
+	dflt := current.Default
+	if op.Default != nil {
+		literal := quoteLiteral(*op.Default)
+		dflt = &literal
+	}
+	if op.DropDefault {
+		dflt = nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ALTER TABLE %s MODIFY COLUMN %s %s", table, column, dataType)
+	if notNull {
+		sb.WriteString(" NOT NULL")
+	} else {
+		sb.WriteString(" NULL")
+	}
+	if dflt != nil {
+		fmt.Fprintf(&sb, " DEFAULT %s", *dflt)
+	}
+	if current.Extra != "" {
+		fmt.Fprintf(&sb, " %s", current.Extra)
+	}
+	if current.Comment != "" {
+		fmt.Fprintf(&sb, " COMMENT %s", quoteLiteral(current.Comment))
+	}
+	return []string{sb.String()}, nil
+}
+
+// RebuildTable recreates a SQLite table with op applied: the new table is
+// created under a temporary name, the data copied over, the old table dropped
+// and the new one renamed. Indexes and triggers are recreated afterwards. The
+// statements are meant to run inside a single transaction.
+func (b *Builder) RebuildTable(def models.TableDefinition, op database.AlterOp, cols []models.ListDataCol) ([]string, error) {
+	if err := b.validateAlter(op, cols); err != nil {
+		return nil, err
+	}
+	columns := slices.Clone(def.Columns)
+	fks := slices.Clone(def.ForeignKeys)
+	uniques := slices.Clone(def.Uniques)
+	// columns whose data is carried over into the new table
+	copied := make([]string, 0, len(columns))
+	for _, c := range columns {
+		copied = append(copied, c.Name)
+	}
+
+	idx := slices.IndexFunc(columns, func(c models.TableColumn) bool { return c.Name == op.Column })
+	switch op.Action {
+	case database.AlterAddColumn:
+		dataType, err := formatDataType(op.Input.DataType)
+		if err != nil {
+			return nil, err
+		}
+		pk := 0
+		if op.Input.IsPK {
+			for _, c := range columns {
+				if c.PK > 0 {
+					return nil, fmt.Errorf("%w: table already has a primary key", apperr.ErrorInvalidAlter)
+				}
+			}
+			pk = 1
+		}
+		columns = append(columns, models.TableColumn{
+			Name:    op.Input.ColName,
+			Type:    dataType,
+			NotNull: !op.Input.IsNull,
+			PK:      pk,
+			Unique:  op.Input.IsUnique,
+		})
+		def.AutoIncrement = def.AutoIncrement || op.Input.DataType.AutoIncrement
+	case database.AlterDropColumn:
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, op.Column)
+		}
+		columns = slices.Delete(columns, idx, idx+1)
+		copied = slices.Delete(copied, idx, idx+1)
+		// like Postgres, constraints spanning the column go with it
+		fks = slices.DeleteFunc(fks, func(fk models.ForeignKey) bool { return slices.Contains(fk.Columns, op.Column) })
+		uniques = slices.DeleteFunc(uniques, func(u []string) bool { return slices.Contains(u, op.Column) })
+	case database.AlterModifyColumn:
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, op.Column)
+		}
+		if op.DataType != nil {
+			dataType, err := formatDataType(*op.DataType)
+			if err != nil {
+				return nil, err
+			}
+			columns[idx].Type = dataType
+		}
+		if op.IsNull != nil {
+			columns[idx].NotNull = !*op.IsNull
+		}
+		if op.Default != nil {
+			literal := quoteLiteral(*op.Default)
+			columns[idx].Default = &literal
+		}
+		if op.DropDefault {
+			columns[idx].Default = nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s doesn't need a table rebuild", apperr.ErrorInvalidAlter, op.Action)
+	}
+
+	table, err := b.getQuotedTableName(def.Name)
+	if err != nil {
+		return nil, err
+	}
+	tmpTable, err := b.quoteIdentifier("rowsql_tmp_" + def.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	def.Columns, def.ForeignKeys, def.Uniques = columns, fks, uniques
+	createTable, err := b.createTableFromDefinition(tmpTable, def)
+	if err != nil {
+		return nil, err
+	}
+	quoted := make([]string, 0, len(copied))
+	for _, c := range copied {
+		col, err := b.quoteIdentifier(c)
+		if err != nil {
+			return nil, err
+		}
+		quoted = append(quoted, col)
+	}
+	copyColumns := strings.Join(quoted, ", ")
+
+	statements := []string{
+		createTable,
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmpTable, copyColumns, copyColumns, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmpTable, table),
+	}
+	for _, extra := range def.Extras {
+		if op.Action == database.AlterDropColumn && referencesColumn(extra, op.Column) {
+			// the index or trigger would reference a column that is gone
+			continue
+		}
+		statements = append(statements, extra)
+	}
+	return statements, nil
+}
+
+func (b *Builder) createTableFromDefinition(table string, def models.TableDefinition) (string, error) {
+	columns := def.Columns
+	var pks []string
+	for _, c := range columns {
+		if c.PK > 0 {
+			pks = append(pks, c.Name)
+		}
+	}
+
+	defs := make([]string, 0, len(columns)+len(def.ForeignKeys)+len(def.Uniques)+1)
+	for _, c := range columns {
+		name, err := b.quoteIdentifier(c.Name)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		sb.WriteString(name)
+		if c.Type != "" {
+			fmt.Fprintf(&sb, " %s", c.Type)
+		}
+		if c.NotNull {
+			sb.WriteString(" NOT NULL")
+		}
+		if c.Default != nil {
+			fmt.Fprintf(&sb, " DEFAULT %s", *c.Default)
+		}
+		if c.PK > 0 && len(pks) == 1 {
+			sb.WriteString(" PRIMARY KEY")
+			if def.AutoIncrement && strings.EqualFold(c.Type, "integer") {
+				sb.WriteString(" AUTOINCREMENT")
+			}
+		}
+		if c.Unique && c.PK == 0 {
+			sb.WriteString(" UNIQUE")
+		}
+		defs = append(defs, sb.String())
+	}
+	if len(pks) > 1 {
+		slices.SortStableFunc(pks, func(a, c string) int {
+			return pkOrder(columns, a) - pkOrder(columns, c)
+		})
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pks, ", ")))
+	}
+	for _, unique := range def.Uniques {
+		cols, err := b.quoteIdentifiers(unique)
+		if err != nil {
+			return "", err
+		}
+		defs = append(defs, fmt.Sprintf("UNIQUE (%s)", cols))
+	}
+	for _, fk := range def.ForeignKeys {
+		cols, err := b.quoteIdentifiers(fk.Columns)
+		if err != nil {
+			return "", err
+		}
+		refTable, err := b.quoteIdentifier(fk.RefTable)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "FOREIGN KEY (%s) REFERENCES %s", cols, refTable)
+		if len(fk.RefColumns) > 0 {
+			refCols, err := b.quoteIdentifiers(fk.RefColumns)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&sb, " (%s)", refCols)
+		}
+		if fk.OnUpdate != "" && !strings.EqualFold(fk.OnUpdate, "NO ACTION") {
+			fmt.Fprintf(&sb, " ON UPDATE %s", fk.OnUpdate)
+		}
+		if fk.OnDelete != "" && !strings.EqualFold(fk.OnDelete, "NO ACTION") {
+			fmt.Fprintf(&sb, " ON DELETE %s", fk.OnDelete)
+		}
+		defs = append(defs, sb.String())
+	}
+	createTable := fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(defs, ", "))
+	if def.Options != "" {
+		createTable += " " + def.Options
+	}
+	return createTable, nil
+}
+
+func (b *Builder) quoteIdentifiers(names []string) (string, error) {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		q, err := b.quoteIdentifier(name)
+		if err != nil {
+			return "", err
+		}
+		quoted = append(quoted, q)
+	}
+	return strings.Join(quoted, ", "), nil
+}
+
+func pkOrder(columns []models.TableColumn, name string) int {
+	for _, c := range columns {
+		if c.Name == name {
+			return c.PK
+		}
+	}
+	return 0
+}
+
+// referencesColumn reports whether a CREATE INDEX or CREATE TRIGGER statement
+// uses column, the names of the index or trigger and of its table don't count.
+func referencesColumn(statement, column string) bool {
+	tokens := Tokenize(statement)
+	skip := map[int]bool{}
+	for i, t := range tokens {
+		if t.Quoted {
+			continue
+		}
+		if t.Text == "index" || t.Text == "trigger" {
+			j := i + 1
+			for j < len(tokens) && !tokens[j].Quoted && (tokens[j].Text == "if" || tokens[j].Text == "not" || tokens[j].Text == "exists") {
+				j++
+			}
+			skip[j] = true
+		}
+		if t.Text == "on" {
+			skip[i+1] = true
+			break
+		}
+	}
+	for i, t := range tokens {
+		if !skip[i] && strings.EqualFold(t.Text, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// SQLiteTableOptions reads what a rebuild keeps from the CREATE TABLE statement
+// of a table: AUTOINCREMENT and the options after the column list. Tables with
+// constraints the rebuild can't recreate are refused.
+func SQLiteTableOptions(createTable string) (autoIncrement bool, options string, err error) {
+	var without, strict bool
+	for _, t := range Tokenize(createTable) {
+		if t.Quoted {
+			continue
+		}
+		switch {
+		case t.Text == "autoincrement":
+			autoIncrement = true
+		case t.Text == "check", t.Text == "collate", t.Text == "conflict":
+			return false, "", fmt.Errorf("%w: the table has a %s clause that a rebuild would lose", apperr.ErrorInvalidAlter, strings.ToUpper(t.Text))
+		case t.Text == "generated", t.Text == "as" && t.Depth == 1:
+			return false, "", fmt.Errorf("%w: the table has generated columns that a rebuild would lose", apperr.ErrorInvalidAlter)
+		case t.Depth == 0 && t.Text == "without":
+			without = true
+		case t.Depth == 0 && t.Text == "strict":
+			strict = true
+		}
+	}
+	var opts []string
+	if without {
+		opts = append(opts, "WITHOUT ROWID")
+	}
+	if strict {
+		opts = append(opts, "STRICT")
+	}
+	return autoIncrement, strings.Join(opts, ", "), nil
+}
+
+const sqliteTableInfoQuery = `
+SELECT
+    p.name,
+    p.type,
+    p."notnull",
+    p.dflt_value,
+    p.pk
+FROM pragma_table_info(?) AS p
+ORDER BY p.cid;
+`
+
+const sqliteForeignKeysQuery = `
+SELECT id, "from", "table", "to", on_update, on_delete
+FROM pragma_foreign_key_list(?)
+ORDER BY id, seq;
+`
+
+// the UNIQUE constraints of the CREATE TABLE, unique indexes are kept as extras
+const sqliteUniquesQuery = `
+SELECT il.name, ii.name
+FROM pragma_index_list(?) il
+JOIN pragma_index_info(il.name) ii
+WHERE il.origin = 'u'
+ORDER BY il.seq, ii.seqno;
+`
+
+const sqliteTableExtrasQuery = `
+SELECT type, sql
+FROM sqlite_master
+WHERE tbl_name = ?
+  AND sql IS NOT NULL;
+`
+
+// SQLiteTableDefinition returns the queries needed to describe a SQLite table
+// for a rebuild: columns, foreign keys, unique constraints and the stored
+// CREATE statements.
+func (b *Builder) SQLiteTableDefinition() (columns, foreignKeys, uniques, extras string, err error) {
+	if b.driver != configs.DriverSQLite {
+		return "", "", "", "", apperr.ErrorInvalidDriver
+	}
+	return sqliteTableInfoQuery, sqliteForeignKeysQuery, sqliteUniquesQuery, sqliteTableExtrasQuery, nil
+}
@@ -0,0 +1,374 @@
+package queries
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+var alterCols = []models.ListDataCol{
+	{ColumnName: "id", IsPrimaryKey: true, IsUnique: true},
+	{ColumnName: "name"},
+	{ColumnName: "email", IsUnique: true},
+}
+
+func boolPtr(b bool) *bool    { return &b }
+func strPtr(s string) *string { return &s }
+
+func TestAlterTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		op     database.AlterOp
+		want   []string
+		err    error
+	}{
+		{
+			name:   "Postgres add column",
+			driver: configs.DriverPostgres,
+			op: database.AlterOp{
+				Action: database.AlterAddColumn,
+				Input:  &database.Input{ColName: "age", IsNull: true, DataType: database.DataType{Type: "INTEGER"}},
+			},
+			want: []string{"ALTER TABLE users ADD COLUMN age INTEGER"},
+		},
+		{
+			name:   "MySQL drop column",
+			driver: configs.DriverMySQL,
+			op:     database.AlterOp{Action: database.AlterDropColumn, Column: "name"},
+			want:   []string{"ALTER TABLE users DROP COLUMN name"},
+		},
+		{
+			name:   "SQLite rename column",
+			driver: configs.DriverSQLite,
+			op:     database.AlterOp{Action: database.AlterRenameColumn, Column: "name", NewName: "full_name"},
+			want:   []string{"ALTER TABLE users RENAME COLUMN name TO full_name"},
+		},
+		{
+			name:   "rename table",
+			driver: configs.DriverPostgres,
+			op:     database.AlterOp{Action: database.AlterRenameTable, NewName: "people"},
+			want:   []string{"ALTER TABLE users RENAME TO people"},
+		},
+		{
+			name:   "Postgres modify column",
+			driver: configs.DriverPostgres,
+			op: database.AlterOp{
+				Action:   database.AlterModifyColumn,
+				Column:   "name",
+				DataType: &database.DataType{Type: "VARCHAR", HasSize: true, Size: 50},
+				IsNull:   boolPtr(false),
+				Default:  strPtr("it's"),
+			},
+			want: []string{"ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(50) USING name::VARCHAR(50), " +
+				"ALTER COLUMN name SET NOT NULL, ALTER COLUMN name SET DEFAULT 'it''s'"},
+		},
+		{
+			name:   "MySQL modify needs the current column",
+			driver: configs.DriverMySQL,
+			op:     database.AlterOp{Action: database.AlterModifyColumn, Column: "name", IsNull: boolPtr(false)},
+			err:    apperr.ErrorInvalidAlter,
+		},
+		{
+			name:   "SQLite modify needs rebuild",
+			driver: configs.DriverSQLite,
+			op:     database.AlterOp{Action: database.AlterModifyColumn, Column: "name", IsNull: boolPtr(false)},
+			err:    apperr.ErrorInvalidAlter,
+		},
+		{
+			name:   "unknown column",
+			driver: configs.DriverPostgres,
+			op:     database.AlterOp{Action: database.AlterDropColumn, Column: "nope"},
+			err:    apperr.ErrorInvalidColumn,
+		},
+		{
+			name:   "rename to existing column",
+			driver: configs.DriverPostgres,
+			op:     database.AlterOp{Action: database.AlterRenameColumn, Column: "name", NewName: "email"},
+			err:    apperr.ErrorInvalidAlter,
+		},
+		{
+			name:   "invalid new table name",
+			driver: configs.DriverPostgres,
+			op:     database.AlterOp{Action: database.AlterRenameTable, NewName: "x; DROP TABLE users"},
+			err:    apperr.ErrorInvalidAlter,
+		},
+		{
+			name:   "invalid data type",
+			driver: configs.DriverPostgres,
+			op: database.AlterOp{
+				Action:   database.AlterModifyColumn,
+				Column:   "name",
+				DataType: &database.DataType{Type: "TEXT); DROP TABLE users; --"},
+			},
+			err: apperr.ErrorInvalidDataType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			got, err := builder.AlterTable("users", tt.op, alterCols)
+			assertErrIs(t, err, tt.err)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildTable(t *testing.T) {
+	def := models.TableDefinition{
+		Name: "users",
+		Columns: []models.TableColumn{
+			{Name: "id", Type: "INTEGER", PK: 1},
+			{Name: "name", Type: "TEXT", Default: strPtr("'x'")},
+			{Name: "email", Type: "TEXT", Unique: true},
+			{Name: "team_id", Type: "INTEGER"},
+		},
+		ForeignKeys:   []models.ForeignKey{{Columns: []string{"team_id"}, RefTable: "teams", RefColumns: []string{"id"}}},
+		AutoIncrement: true,
+		Extras: []string{
+			"CREATE INDEX idx_users_name ON users(name)",
+			"CREATE INDEX idx_users_team ON users(team_id)",
+		},
+	}
+	cols := append(alterCols, models.ListDataCol{ColumnName: "team_id"})
+	builder := NewBuilder(configs.DriverSQLite, 10)
+
+	t.Run("modify column", func(t *testing.T) {
+		got, err := builder.RebuildTable(def, database.AlterOp{
+			Action:      database.AlterModifyColumn,
+			Column:      "name",
+			IsNull:      boolPtr(false),
+			DropDefault: true,
+		}, cols)
+		assertErr(t, err, nil)
+		want := []string{
+			"CREATE TABLE rowsql_tmp_users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, email TEXT UNIQUE, " +
+				"team_id INTEGER, FOREIGN KEY (team_id) REFERENCES teams (id))",
+			"INSERT INTO rowsql_tmp_users (id, name, email, team_id) SELECT id, name, email, team_id FROM users",
+			"DROP TABLE users",
+			"ALTER TABLE rowsql_tmp_users RENAME TO users",
+			"CREATE INDEX idx_users_name ON users(name)",
+			"CREATE INDEX idx_users_team ON users(team_id)",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+
+	t.Run("drop column removes its foreign key and indexes", func(t *testing.T) {
+		got, err := builder.RebuildTable(def, database.AlterOp{Action: database.AlterDropColumn, Column: "team_id"}, cols)
+		assertErr(t, err, nil)
+		want := []string{
+			"CREATE TABLE rowsql_tmp_users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT DEFAULT 'x', email TEXT UNIQUE)",
+			"INSERT INTO rowsql_tmp_users (id, name, email) SELECT id, name, email FROM users",
+			"DROP TABLE users",
+			"ALTER TABLE rowsql_tmp_users RENAME TO users",
+			"CREATE INDEX idx_users_name ON users(name)",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+
+	t.Run("composite primary key", func(t *testing.T) {
+		def := models.TableDefinition{
+			Name: "members",
+			Columns: []models.TableColumn{
+				{Name: "user_id", Type: "INTEGER", PK: 2},
+				{Name: "team_id", Type: "INTEGER", PK: 1},
+				{Name: "role", Type: "TEXT"},
+			},
+		}
+		cols := []models.ListDataCol{{ColumnName: "user_id"}, {ColumnName: "team_id"}, {ColumnName: "role"}}
+		got, err := builder.RebuildTable(def, database.AlterOp{Action: database.AlterDropColumn, Column: "role"}, cols)
+		assertErr(t, err, nil)
+		assertQuery(t, got[0], "CREATE TABLE rowsql_tmp_members (user_id INTEGER, team_id INTEGER, PRIMARY KEY (team_id, user_id))")
+	})
+
+	t.Run("keeps composite constraints and table options", func(t *testing.T) {
+		def := models.TableDefinition{
+			Name: "members",
+			Columns: []models.TableColumn{
+				{Name: "id", Type: "INTEGER", PK: 1},
+				{Name: "org_id", Type: "INTEGER", NotNull: true},
+				{Name: "team_id", Type: "INTEGER"},
+				{Name: "email", Type: "TEXT"},
+			},
+			ForeignKeys: []models.ForeignKey{
+				{Columns: []string{"org_id", "team_id"}, RefTable: "teams", RefColumns: []string{"org_id", "id"}, OnUpdate: "NO ACTION", OnDelete: "CASCADE"},
+				{Columns: []string{"org_id"}, RefTable: "orgs", OnUpdate: "SET NULL", OnDelete: "NO ACTION"},
+			},
+			Uniques: [][]string{{"org_id", "email"}},
+			Options: "WITHOUT ROWID, STRICT",
+		}
+		cols := []models.ListDataCol{{ColumnName: "id"}, {ColumnName: "org_id"}, {ColumnName: "team_id"}, {ColumnName: "email"}}
+
+		got, err := builder.RebuildTable(def, database.AlterOp{Action: database.AlterModifyColumn, Column: "email", IsNull: boolPtr(false)}, cols)
+		assertErr(t, err, nil)
+		assertQuery(t, got[0], "CREATE TABLE rowsql_tmp_members (id INTEGER PRIMARY KEY, org_id INTEGER NOT NULL, team_id INTEGER, "+
+			"email TEXT NOT NULL, UNIQUE (org_id, email), FOREIGN KEY (org_id, team_id) REFERENCES teams (org_id, id) ON DELETE CASCADE, "+
+			"FOREIGN KEY (org_id) REFERENCES orgs ON UPDATE SET NULL) WITHOUT ROWID, STRICT")
+
+		got, err = builder.RebuildTable(def, database.AlterOp{Action: database.AlterDropColumn, Column: "team_id"}, cols)
+		assertErr(t, err, nil)
+		assertQuery(t, got[0], "CREATE TABLE rowsql_tmp_members (id INTEGER PRIMARY KEY, org_id INTEGER NOT NULL, email TEXT, "+
+			"UNIQUE (org_id, email), FOREIGN KEY (org_id) REFERENCES orgs ON UPDATE SET NULL) WITHOUT ROWID, STRICT")
+
+		got, err = builder.RebuildTable(def, database.AlterOp{Action: database.AlterDropColumn, Column: "email"}, cols)
+		assertErr(t, err, nil)
+		assertQuery(t, got[0], "CREATE TABLE rowsql_tmp_members (id INTEGER PRIMARY KEY, org_id INTEGER NOT NULL, team_id INTEGER, "+
+			"FOREIGN KEY (org_id, team_id) REFERENCES teams (org_id, id) ON DELETE CASCADE, "+
+			"FOREIGN KEY (org_id) REFERENCES orgs ON UPDATE SET NULL) WITHOUT ROWID, STRICT")
+	})
+
+	t.Run("drop column keeps indexes of other columns", func(t *testing.T) {
+		def := models.TableDefinition{
+			Name: "team",
+			Columns: []models.TableColumn{
+				{Name: "id", Type: "INTEGER", PK: 1},
+				{Name: "team", Type: "TEXT"},
+				{Name: "name", Type: "TEXT"},
+			},
+			Extras: []string{
+				"CREATE INDEX team_name ON team(name)",
+				`CREATE INDEX "team_label" ON "team" ("team")`,
+			},
+		}
+		cols := []models.ListDataCol{{ColumnName: "id"}, {ColumnName: "team"}, {ColumnName: "name"}}
+		got, err := builder.RebuildTable(def, database.AlterOp{Action: database.AlterDropColumn, Column: "team"}, cols)
+		assertErr(t, err, nil)
+		want := []string{
+			"CREATE TABLE rowsql_tmp_team (id INTEGER PRIMARY KEY, name TEXT)",
+			"INSERT INTO rowsql_tmp_team (id, name) SELECT id, name FROM team",
+			"DROP TABLE team",
+			"ALTER TABLE rowsql_tmp_team RENAME TO team",
+			"CREATE INDEX team_name ON team(name)",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+
+	t.Run("add primary key to a table that has one", func(t *testing.T) {
+		_, err := builder.RebuildTable(def, database.AlterOp{
+			Action: database.AlterAddColumn,
+			Input:  &database.Input{ColName: "uuid", IsPK: true, DataType: database.DataType{Type: "TEXT"}},
+		}, cols)
+		assertErrIs(t, err, apperr.ErrorInvalidAlter)
+	})
+}
+
+func TestReferencesColumn(t *testing.T) {
+	tests := []struct {
+		statement string
+		column    string
+		want      bool
+	}{
+		{statement: "CREATE INDEX idx_email ON users(email)", column: "email", want: true},
+		{statement: "CREATE INDEX idx_email ON users(lower(email)) WHERE name IS NOT NULL", column: "name", want: true},
+		{statement: "CREATE INDEX IF NOT EXISTS name ON users(email)", column: "name"},
+		{statement: "CREATE INDEX idx ON name(email)", column: "name"},
+		{statement: `CREATE UNIQUE INDEX "idx" ON "users" ("Email")`, column: "email", want: true},
+		{statement: "CREATE TRIGGER email AFTER UPDATE OF name ON users BEGIN SELECT 1; END", column: "email"},
+		{statement: "CREATE TRIGGER audit AFTER UPDATE OF name ON users BEGIN SELECT 1; END", column: "name", want: true},
+		{statement: "CREATE TRIGGER audit AFTER INSERT ON users BEGIN INSERT INTO log VALUES ('email', new.id); END", column: "email"},
+		{statement: "CREATE TRIGGER audit AFTER INSERT ON users BEGIN INSERT INTO log VALUES (new.email); END", column: "email", want: true},
+	}
+	for _, tt := range tests {
+		if got := referencesColumn(tt.statement, tt.column); got != tt.want {
+			t.Errorf("referencesColumn(%q, %q) = %v want %v", tt.statement, tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteTableOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		statement     string
+		autoIncrement bool
+		options       string
+		err           error
+	}{
+		{name: "plain", statement: "CREATE TABLE t (id INTEGER PRIMARY KEY, note TEXT DEFAULT 'check')"},
+		{name: "autoincrement", statement: "CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT)", autoIncrement: true},
+		{name: "options", statement: "CREATE TABLE t (id INTEGER PRIMARY KEY) WITHOUT ROWID, STRICT", options: "WITHOUT ROWID, STRICT"},
+		{name: "quoted names", statement: `CREATE TABLE "strict" ("check" TEXT, [collate] TEXT)`},
+		{name: "check", statement: "CREATE TABLE t (age INTEGER CHECK (age > 0))", err: apperr.ErrorInvalidAlter},
+		{name: "collate", statement: "CREATE TABLE t (name TEXT COLLATE NOCASE)", err: apperr.ErrorInvalidAlter},
+		{name: "on conflict", statement: "CREATE TABLE t (name TEXT UNIQUE ON CONFLICT REPLACE)", err: apperr.ErrorInvalidAlter},
+		{name: "generated", statement: "CREATE TABLE t (a INTEGER, b INTEGER AS (a * 2))", err: apperr.ErrorInvalidAlter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			autoIncrement, options, err := SQLiteTableOptions(tt.statement)
+			assertErrIs(t, err, tt.err)
+			if autoIncrement != tt.autoIncrement || options != tt.options {
+				t.Errorf("got %v, %q want %v, %q", autoIncrement, options, tt.autoIncrement, tt.options)
+			}
+		})
+	}
+}
+
+func TestMySQLModifyColumn(t *testing.T) {
+	current := models.TableColumn{
+		Name:    "name",
+		Type:    "varchar(20)",
+		NotNull: true,
+		Default: strPtr("'bob'"),
+		Extra:   "on update CURRENT_TIMESTAMP",
+		Comment: "it's",
+	}
+	tests := []struct {
+		name    string
+		op      database.AlterOp
+		current models.TableColumn
+		want    []string
+		err     error
+	}{
+		{
+			name: "type only keeps the rest",
+			op: database.AlterOp{
+				Action:   database.AlterModifyColumn,
+				Column:   "name",
+				DataType: &database.DataType{Type: "VARCHAR", HasSize: true, Size: 50},
+			},
+			current: current,
+			want: []string{"ALTER TABLE users MODIFY COLUMN name VARCHAR(50) NOT NULL DEFAULT 'bob' " +
+				"on update CURRENT_TIMESTAMP COMMENT 'it''s'"},
+		},
+		{
+			name:    "nullability keeps the type",
+			op:      database.AlterOp{Action: database.AlterModifyColumn, Column: "name", IsNull: boolPtr(true), DropDefault: true},
+			current: current,
+			want:    []string{"ALTER TABLE users MODIFY COLUMN name varchar(20) NULL on update CURRENT_TIMESTAMP COMMENT 'it''s'"},
+		},
+		{
+			name:    "keeps auto_increment",
+			op:      database.AlterOp{Action: database.AlterModifyColumn, Column: "id", DataType: &database.DataType{Type: "BIGINT"}},
+			current: models.TableColumn{Name: "id", Type: "int", NotNull: true, Extra: "auto_increment"},
+			want:    []string{"ALTER TABLE users MODIFY COLUMN id BIGINT NOT NULL auto_increment"},
+		},
+		{
+			name:    "generated column",
+			op:      database.AlterOp{Action: database.AlterModifyColumn, Column: "name", IsNull: boolPtr(true)},
+			current: models.TableColumn{Name: "name", Type: "varchar(20)", Extra: "VIRTUAL GENERATED"},
+			err:     apperr.ErrorInvalidAlter,
+		},
+	}
+	builder := NewBuilder(configs.DriverMySQL, 10)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := builder.MySQLModifyColumn("users", tt.op, alterCols, tt.current)
+			assertErrIs(t, err, tt.err)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
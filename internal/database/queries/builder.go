@@ -13,6 +13,7 @@ import (
 	"github.com/biisal/rowsql/configs"
 	"github.com/biisal/rowsql/internal/apperr"
 	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/locator"
 	"github.com/biisal/rowsql/internal/database/models"
 	"github.com/biisal/rowsql/internal/logger"
 )
@@ -37,7 +38,8 @@ func (b *Builder) CheckTableExitsQuery(tableName string) (string, []any, error)
 	case configs.DriverMySQL:
 		return `SELECT 1 FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?`, args, nil
 	case configs.DriverPostgres:
-		return `SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = $1`, args, nil
+		schema, table := b.SplitTableName(tableName)
+		return `SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2`, []any{schema, table}, nil
 	case configs.DriverSQLite:
 		return `SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?`, args, nil
 	}
@@ -54,10 +56,34 @@ SELECT
         bool_or(tc.constraint_type IN ('UNIQUE', 'PRIMARY KEY')),
         false
     ) AS is_unique,
+    EXISTS (
+        SELECT 1
+        FROM pg_index ix
+        JOIN pg_class ic
+            ON ic.oid = ix.indrelid
+        JOIN pg_namespace ins
+            ON ins.oid = ic.relnamespace
+        WHERE ic.relname = $1
+          AND ins.nspname = $2
+          AND ix.indisunique
+          AND ix.indnatts = 1
+          AND ix.indkey[0] = c.ordinal_position
+          AND ix.indpred IS NULL
+          AND ix.indexprs IS NULL
+    ) AS is_unique_key,
+    (c.is_nullable = 'YES') AS is_nullable,
     (
         c.is_identity = 'YES'
         OR c.column_default LIKE 'nextval(%'
-    ) AS is_auto_increment
+    ) AS is_auto_increment,
+    (c.is_generated = 'ALWAYS') AS is_generated,
+    COALESCE(c.identity_generation = 'ALWAYS', false) AS is_identity,
+    COALESCE(
+        bool_or(tc.constraint_type = 'PRIMARY KEY'),
+        false
+    ) AS is_primary_key,
+    COALESCE(MAX(fk.ref_table), '') AS ref_table,
+    COALESCE(MAX(fk.ref_column), '') AS ref_column
 FROM information_schema.columns c
 LEFT JOIN information_schema.key_column_usage kcu
     ON c.table_name = kcu.table_name
@@ -66,12 +92,47 @@ LEFT JOIN information_schema.key_column_usage kcu
 LEFT JOIN information_schema.table_constraints tc
     ON kcu.constraint_name = tc.constraint_name
     AND kcu.table_schema = tc.table_schema
+LEFT JOIN (
+    SELECT
+        ns.nspname AS table_schema,
+        a.attname AS column_name,
+        CASE
+            WHEN rns.nspname = $3 THEN rc.relname::text
+            ELSE rns.nspname || '.' || rc.relname
+        END AS ref_table,
+        ra.attname::text AS ref_column
+    FROM pg_constraint con
+    JOIN pg_class cl
+        ON cl.oid = con.conrelid
+    JOIN pg_namespace ns
+        ON ns.oid = cl.relnamespace
+    JOIN pg_class rc
+        ON rc.oid = con.confrelid
+    JOIN pg_namespace rns
+        ON rns.oid = rc.relnamespace
+    CROSS JOIN LATERAL unnest(con.conkey, con.confkey) AS k(attnum, ref_attnum)
+    JOIN pg_attribute a
+        ON a.attrelid = con.conrelid
+        AND a.attnum = k.attnum
+    JOIN pg_attribute ra
+        ON ra.attrelid = con.confrelid
+        AND ra.attnum = k.ref_attnum
+    WHERE con.contype = 'f'
+      AND cl.relname = $1
+      AND ns.nspname = $2
+) fk
+    ON fk.table_schema = c.table_schema
+    AND fk.column_name = c.column_name
 WHERE c.table_name = $1
+  AND c.table_schema = $2
 GROUP BY
     c.column_name,
     c.data_type,
     c.ordinal_position,
+    c.is_nullable,
     c.is_identity,
+    c.is_generated,
+    c.identity_generation,
     c.column_default
 ORDER BY c.ordinal_position;
 `
@@ -88,7 +149,35 @@ SELECT
         END) = 1,
         false
     ) AS is_unique,
-    (c.extra LIKE '%auto_increment%') AS is_auto_increment
+    EXISTS (
+        SELECT 1
+        FROM information_schema.statistics s
+        WHERE s.table_schema = DATABASE()
+          AND s.table_name = ?
+          AND s.column_name = c.column_name
+          AND s.non_unique = 0
+          AND NOT EXISTS (
+              SELECT 1
+              FROM information_schema.statistics s2
+              WHERE s2.table_schema = s.table_schema
+                AND s2.table_name = s.table_name
+                AND s2.index_name = s.index_name
+                AND s2.seq_in_index > 1
+          )
+    ) AS is_unique_key,
+    (c.is_nullable = 'YES') AS is_nullable,
+    (c.extra LIKE '%auto_increment%') AS is_auto_increment,
+    (c.extra LIKE '%VIRTUAL GENERATED%' OR c.extra LIKE '%STORED GENERATED%') AS is_generated,
+    false AS is_identity,
+    COALESCE(
+        MAX(CASE
+            WHEN tc.constraint_type = 'PRIMARY KEY' THEN 1
+            ELSE 0
+        END) = 1,
+        false
+    ) AS is_primary_key,
+    COALESCE(MAX(kcu.referenced_table_name), '') AS ref_table,
+    COALESCE(MAX(kcu.referenced_column_name), '') AS ref_column
 FROM information_schema.columns c
 LEFT JOIN information_schema.key_column_usage kcu
     ON c.table_name = kcu.table_name
@@ -103,6 +192,7 @@ GROUP BY
     c.column_name,
     c.data_type,
     c.ordinal_position,
+    c.is_nullable,
     c.extra,
     c.column_default
 ORDER BY c.ordinal_position;
@@ -124,23 +214,54 @@ SELECT
         ) THEN 1
         ELSE 0
     END AS is_unique,
+    EXISTS (
+        SELECT 1
+        FROM pragma_index_list(?) il
+        JOIN pragma_index_info(il.name) ii
+            ON ii.name = p.name
+        WHERE il."unique" = 1
+          AND il.partial = 0
+          AND (SELECT COUNT(*) FROM pragma_index_info(il.name)) = 1
+    ) AS is_unique_key,
+    (p."notnull" = 0) AS is_nullable,
     CASE
         WHEN p.pk = 1
              AND lower(p.type) = 'integer'
         THEN 1
         ELSE 0
-    END AS is_auto_increment
+    END AS is_auto_increment,
+    0 AS is_generated,
+    0 AS is_identity,
+    (p.pk > 0) AS is_primary_key,
+    COALESCE((
+        SELECT fk."table"
+        FROM pragma_foreign_key_list(?) fk
+        WHERE fk."from" = p.name
+        ORDER BY fk.id
+        LIMIT 1
+    ), '') AS ref_table,
+    COALESCE((
+        SELECT COALESCE(
+            fk."to",
+            (SELECT rp.name FROM pragma_table_info(fk."table") rp WHERE rp.pk = 1)
+        )
+        FROM pragma_foreign_key_list(?) fk
+        WHERE fk."from" = p.name
+        ORDER BY fk.id
+        LIMIT 1
+    ), '') AS ref_column
 FROM pragma_table_info(?) AS p;
 `
 
 func (b *Builder) ColumnsList(tableName string) (string, []any, error) {
 	switch b.driver {
 	case configs.DriverPostgres:
-		return postgresColumnsListsQuery, []any{tableName}, nil
+		schema, table := b.SplitTableName(tableName)
+		return postgresColumnsListsQuery, []any{table, schema, DefaultSchema}, nil
 	case configs.DriverMySQL:
-		return mysqlColumnsListsQuery, []any{tableName}, nil
+		return mysqlColumnsListsQuery, []any{tableName, tableName}, nil
 	case configs.DriverSQLite:
-		return sqliteColumnsListQuery, []any{tableName, tableName}, nil
+		return sqliteColumnsListQuery, []any{tableName, tableName, tableName, tableName, tableName}, nil
 	}
 
 	logger.Error("error : %s , %s", ErrUnknownDriver.Error(), b.driver)
@@ -185,7 +306,9 @@ func (b *Builder) ListTables() (string, error) {
 	return "", ErrUnknownDriver
 }
 
-func (b *Builder) ListRows(tableName, orderCol, orderBy string, limit, offset int) (string, []any, error) {
+func (b *Builder) ListRows(props models.ListDataProps, cols []models.ListDataCol, loc locator.Locator) (string, []any, error) {
+	tableName, orderCol, orderBy := props.TableName, props.Column, props.Order
+	limit, offset := props.Limit, props.Offset
 	if tableName == "" {
 		return "", nil, apperr.ErrorEmptyTableName
 	}
@@ -200,7 +323,22 @@ func (b *Builder) ListRows(tableName, orderCol, orderBy string, limit, offset in
 		logger.Errorln(err.Error())
 		return "", nil, err
 	}
-	parts := []string{fmt.Sprintf("SELECT * FROM %s", tableName)}
+	selection := "*"
+	if pseudo := loc.PseudoColumn(); pseudo != "" {
+		selection = fmt.Sprintf("%s, %s.*", pseudo, tableName)
+	}
+	parts := []string{fmt.Sprintf("SELECT %s FROM %s", selection, tableName)}
+
+	args := []any{}
+	where, whereArgs, err := b.Where(props.Filter, cols, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		parts = append(parts, "WHERE "+where)
+		args = append(args, whereArgs...)
+	}
+
 	if orderCol != "" {
 		order := "ASC"
 		if strings.ToLower(orderBy) == "desc" {
@@ -209,9 +347,8 @@ func (b *Builder) ListRows(tableName, orderCol, orderBy string, limit, offset in
 		parts = append(parts, fmt.Sprintf("ORDER BY %s %s", orderCol, order))
 	}
 
-	args := []any{}
 	if limit > 0 {
-		ph, err := b.placeHolder(1)
+		ph, err := b.placeHolder(len(args) + 1)
 		if err != nil {
 			return "", nil, err
 		}
@@ -219,16 +356,10 @@ func (b *Builder) ListRows(tableName, orderCol, orderBy string, limit, offset in
 		args = append(args, limit)
 	}
 	if offset > 0 {
-		placeholder, err := b.placeHolder(1)
+		placeholder, err := b.placeHolder(len(args) + 1)
 		if err != nil {
 			return "", nil, err
 		}
-		if limit > 0 {
-			placeholder, err = b.placeHolder(2)
-			if err != nil {
-				return "", nil, err
-			}
-		}
 		parts = append(parts, fmt.Sprintf("OFFSET %s", placeholder))
 		args = append(args, offset)
 	}
@@ -236,6 +367,25 @@ func (b *Builder) ListRows(tableName, orderCol, orderBy string, limit, offset in
 	return strings.Join(parts, " "), args, nil
 }
 
+func (b *Builder) CountRows(tableName string, filter *models.Filter, cols []models.ListDataCol) (string, []any, error) {
+	if tableName == "" {
+		return "", nil, apperr.ErrorEmptyTableName
+	}
+	tableName, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
+	where, args, err := b.Where(filter, cols, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		query += " WHERE " + where
+	}
+	return query, args, nil
+}
+
 func (b *Builder) InsertRow(tableName string, form []models.RowItem) (string, []any, error) {
 	if err := b.checkValidDriver(); err != nil {
 		return "", nil, err
@@ -312,111 +462,98 @@ func (b *Builder) InsertRow(tableName string, form []models.RowItem) (string, []
 	return query, args, nil
 }
 
-func (b *Builder) GetRows(tableName string, limit, offset int) (string, []any, error) {
-	if err := b.checkValidDriver(); err != nil {
-		return "", nil, err
+// KeyWhere builds the condition matching the row identified by key.
+func (b *Builder) KeyWhere(loc locator.Locator, key []any, argsIdx int) (string, []any, error) {
+	if len(loc.Columns) == 0 || len(loc.Columns) != len(key) {
+		return "", nil, locator.ErrorInvalidKey
 	}
-	if limit <= 0 || offset < 0 {
-		return "", nil, apperr.ErrorInvalidPagination
+	parts := make([]string, 0, len(key))
+	args := make([]any, 0, len(key))
+	for i, col := range loc.Columns {
+		if key[i] == nil {
+			parts = append(parts, fmt.Sprintf("%s IS NULL", col))
+			continue
+		}
+		ph, err := b.placeHolder(argsIdx + len(args))
+		if err != nil {
+			return "", nil, err
+		}
+		if loc.Kind == locator.KindCtid {
+			ph += "::tid"
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", col, ph))
+		args = append(args, key[i])
 	}
+	return strings.Join(parts, " AND "), args, nil
+}
 
-	tableName = strings.TrimSpace(tableName)
-	if tableName == "" {
-		return "", nil, apperr.ErrorInvalidTableName
+func (b *Builder) GetRowByKey(tableName string, loc locator.Locator, key []any) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
 	}
-
-	args := []any{limit}
-	ph, err := b.placeHolder(1)
+	tableName, err := b.getQuotedTableName(tableName)
 	if err != nil {
 		return "", nil, err
 	}
-	parts := []string{fmt.Sprintf("SELECT * FROM %s LIMIT %s", tableName, ph)}
-	if offset > 0 {
-		ph, err = b.placeHolder(2)
-		if err != nil {
-			return "", nil, err
-		}
-		parts = append(parts, fmt.Sprintf("OFFSET %s", ph))
-		args = append(args, offset)
+	clause, args, err := b.KeyWhere(loc, key, 1)
+	if err != nil {
+		return "", nil, err
 	}
-
-	return strings.Join(parts, " "), args, nil
+	return fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT 1", tableName, clause), args, nil
 }
 
-func (b *Builder) WhereCluse(cols []models.ListDataCol, rows []any, argsIdx int) (string, []any, error) {
-	if len(cols) != len(rows) {
-		return "", nil, apperr.ErrorNotSameRowColsSize
+func (b *Builder) DeleteRow(tableName string, loc locator.Locator, key []any) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
 	}
-
-	var mixed []string
-	var args []any
-	for i, val := range cols {
-		ph, err := b.placeHolder(argsIdx + i)
-		if err != nil {
-			return "", nil, err
-		}
-
-		if val.IsUnique {
-			return fmt.Sprintf("%s=%s", val.ColumnName, ph), []any{rows[i]}, nil
-		}
-		colVal := rows[i]
-		if val.DataType == "json" {
-			var jsonVal map[string]any
-			if err := json.Unmarshal([]byte(colVal.(string)), &jsonVal); err != nil {
-				logger.Errorln(err)
-				return "", nil, err
-			}
-			colVal = jsonVal
-			mixed = append(mixed, fmt.Sprintf("%s::jsonb @> %s::jsonb", val.ColumnName, ph))
-		} else {
-			mixed = append(mixed, fmt.Sprintf("%s=%s", val.ColumnName, ph))
-		}
-		args = append(args, colVal)
+	tableName, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return "", nil, err
 	}
-	return strings.Join(mixed, " AND "), args, nil
-}
-
-func (b *Builder) DeleteRow(tableName string, columns []models.ListDataCol, rows []any, argIdx int) (string, []any, error) {
-	clause, args, err := b.WhereCluse(columns, rows, argIdx)
+	clause, args, err := b.KeyWhere(loc, key, 1)
 	logger.Info("Clause: %s", clause)
 	if err != nil {
 		return "", nil, err
 	}
-	query := ""
-	switch b.driver {
-	case configs.DriverMySQL:
-		query = fmt.Sprintf("DELETE FROM %s WHERE %s LIMIT 1", tableName, clause)
-	case configs.DriverPostgres:
-		query = fmt.Sprintf("DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s LIMIT 1)", tableName, tableName, clause)
-	case configs.DriverSQLite:
-		query = fmt.Sprintf("DELETE FROM %s WHERE rowid IN (SELECT rowid FROM %s WHERE %s LIMIT 1)", tableName, tableName, clause)
-	default:
-		return "", nil, apperr.ErrorInvalidDriver
-
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, clause)
+	if loc.Kind == locator.KindRow {
+		// duplicate rows can only be told apart by position
+		query += " LIMIT 1"
 	}
 	return query, args, nil
 }
 
-func (b *Builder) UpdateRow(tableName string, form []models.RowItem, columns []models.ListDataCol, row []any) (string, []any, error) {
+func (b *Builder) UpdateRow(tableName string, form []models.RowItem, loc locator.Locator, key []any) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
+	}
+	if len(form) == 0 {
+		return "", nil, apperr.ErrorNoValuesProvided
+	}
+	tableName, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return "", nil, err
+	}
 	parts := make([]string, 0, len(form))
-	ph := "?"
-	index := 1
 	args := make([]any, 0, len(form))
-	for _, v := range form {
-		if b.driver == configs.DriverPostgres {
-			ph = "$" + strconv.Itoa(index)
+	for i, v := range form {
+		ph, err := b.placeHolder(i + 1)
+		if err != nil {
+			return "", nil, err
 		}
 		parts = append(parts, fmt.Sprintf("%s=%s", v.ColumnName, ph))
 		args = append(args, v.Value)
-		index++
 	}
 	updateQuery := strings.Join(parts, ",")
-	whereClause, whereClauseArgs, err := b.WhereCluse(columns, row, len(args)+1)
+	whereClause, whereClauseArgs, err := b.KeyWhere(loc, key, len(args)+1)
 	if err != nil {
 		return "", nil, err
 	}
 
 	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", tableName, updateQuery, whereClause)
+	if loc.Kind == locator.KindRow {
+		query += " LIMIT 1"
+	}
 	return query, append(args, whereClauseArgs...), nil
 }
 
@@ -436,7 +573,8 @@ func (b *Builder) CreateTable(tableName string, inputs []database.Input) (string
 	logger.Info("Query: %s", strings.Join(columnDefs, ", "))
 
 	validName := regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
-	if !validName.MatchString(tableName) {
+	schema, table := b.SplitTableName(tableName)
+	if !validName.MatchString(table) || (schema != "" && !validName.MatchString(schema)) {
 		logger.Error("invalid table name! Only alphanumeric and _ are allowed table_name=%s", tableName)
 		return "", errors.New("invalid table name! Only alphanumeric and _ are allowed")
 	}
@@ -449,3 +587,78 @@ func (b *Builder) CreateTable(tableName string, inputs []database.Input) (string
 func (b *Builder) DeleteTable(tableName string) string {
 	return fmt.Sprintf("DROP TABLE %s", tableName)
 }
+
+// every references query returns the referencing table, its column and the
+// referenced column of the given table
+const postgresReferencesListQuery = `
+SELECT
+    CASE
+        WHEN ns.nspname = $3 THEN cl.relname::text
+        ELSE ns.nspname || '.' || cl.relname
+    END AS table_name,
+    a.attname AS column_name,
+    ra.attname AS ref_column
+FROM pg_constraint con
+JOIN pg_class cl
+    ON cl.oid = con.conrelid
+JOIN pg_namespace ns
+    ON ns.oid = cl.relnamespace
+JOIN pg_class rc
+    ON rc.oid = con.confrelid
+JOIN pg_namespace rns
+    ON rns.oid = rc.relnamespace
+CROSS JOIN LATERAL unnest(con.conkey, con.confkey) AS k(attnum, ref_attnum)
+JOIN pg_attribute a
+    ON a.attrelid = con.conrelid
+    AND a.attnum = k.attnum
+JOIN pg_attribute ra
+    ON ra.attrelid = con.confrelid
+    AND ra.attnum = k.ref_attnum
+WHERE con.contype = 'f'
+  AND rc.relname = $1
+  AND rns.nspname = $2
+ORDER BY 1, a.attname;
+`
+
+const mysqlReferencesListQuery = `
+SELECT
+    kcu.table_name,
+    kcu.column_name,
+    kcu.referenced_column_name AS ref_column
+FROM information_schema.key_column_usage kcu
+WHERE kcu.referenced_table_schema = DATABASE()
+  AND kcu.referenced_table_name = ?
+ORDER BY kcu.table_name, kcu.column_name;
+`
+
+const sqliteReferencesListQuery = `
+SELECT
+    m.name AS table_name,
+    fk."from" AS column_name,
+    COALESCE(
+        fk."to",
+        (SELECT rp.name FROM pragma_table_info(fk."table") rp WHERE rp.pk = 1),
+        ''
+    ) AS ref_column
+FROM sqlite_master m
+JOIN pragma_foreign_key_list(m.name) fk
+WHERE m.type = 'table'
+  AND fk."table" = ?
+ORDER BY m.name, fk."from";
+`
+
+// ReferencesList lists the foreign key columns of other tables pointing to tableName.
+func (b *Builder) ReferencesList(tableName string) (string, []any, error) {
+	switch b.driver {
+	case configs.DriverPostgres:
+		schema, table := b.SplitTableName(tableName)
+		return postgresReferencesListQuery, []any{table, schema, DefaultSchema}, nil
+	case configs.DriverMySQL:
+		return mysqlReferencesListQuery, []any{tableName}, nil
+	case configs.DriverSQLite:
+		return sqliteReferencesListQuery, []any{tableName}, nil
+	}
+
+	logger.Error("error : %s , %s", ErrUnknownDriver.Error(), b.driver)
+	return "", nil, ErrUnknownDriver
+}
@@ -0,0 +1,155 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+// MaxBulkKeys keeps a key selection below the bind parameter limits.
+const MaxBulkKeys = 5000
+
+// Selection picks the rows of a bulk operation, either by their keys or by a
+// filter, never both.
+type Selection struct {
+	Loc    locator.Locator
+	Keys   [][]any
+	Filter *models.Filter
+}
+
+// SelectionWhere renders sel into a WHERE condition (without the keyword).
+// An empty selection is an error so a bulk operation never hits every row.
+func (b *Builder) SelectionWhere(sel Selection, cols []models.ListDataCol, argsIdx int) (string, []any, error) {
+	switch {
+	case len(sel.Keys) > 0 && sel.Filter != nil:
+		return "", nil, fmt.Errorf("%w: got both", apperr.ErrorInvalidSelection)
+	case sel.Filter != nil:
+		return b.Where(sel.Filter, cols, argsIdx)
+	case len(sel.Keys) == 0:
+		return "", nil, fmt.Errorf("%w: no rows selected", apperr.ErrorInvalidSelection)
+	case len(sel.Keys) > MaxBulkKeys:
+		return "", nil, fmt.Errorf("%w: more than %d keys", apperr.ErrorInvalidSelection, MaxBulkKeys)
+	}
+
+	if len(sel.Loc.Columns) == 1 && !containsNilKey(sel.Keys) {
+		phs := make([]string, 0, len(sel.Keys))
+		args := make([]any, 0, len(sel.Keys))
+		for _, key := range sel.Keys {
+			if len(key) != 1 {
+				return "", nil, locator.ErrorInvalidKey
+			}
+			ph, err := b.placeHolder(argsIdx + len(args))
+			if err != nil {
+				return "", nil, err
+			}
+			if sel.Loc.Kind == locator.KindCtid {
+				ph += "::tid"
+			}
+			phs = append(phs, ph)
+			args = append(args, key[0])
+		}
+		return fmt.Sprintf("%s IN (%s)", sel.Loc.Columns[0], strings.Join(phs, ", ")), args, nil
+	}
+
+	parts := make([]string, 0, len(sel.Keys))
+	var args []any
+	for _, key := range sel.Keys {
+		clause, keyArgs, err := b.KeyWhere(sel.Loc, key, argsIdx+len(args))
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "("+clause+")")
+		args = append(args, keyArgs...)
+	}
+	return strings.Join(parts, " OR "), args, nil
+}
+
+func containsNilKey(keys [][]any) bool {
+	for _, key := range keys {
+		for _, v := range key {
+			if v == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *Builder) BulkCount(tableName string, sel Selection, cols []models.ListDataCol) (string, []any, error) {
+	table, err := b.bulkTable(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	where, args, err := b.SelectionWhere(sel, cols, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, where), args, nil
+}
+
+func (b *Builder) BulkDelete(tableName string, sel Selection, cols []models.ListDataCol) (string, []any, error) {
+	table, err := b.bulkTable(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	where, args, err := b.SelectionWhere(sel, cols, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), args, nil
+}
+
+// BulkUpdate sets the same values on every selected row.
+func (b *Builder) BulkUpdate(tableName string, values []models.RowItem, sel Selection, cols []models.ListDataCol) (string, []any, error) {
+	table, err := b.bulkTable(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(values) == 0 {
+		return "", nil, apperr.ErrorNoValuesProvided
+	}
+	known := make(map[string]bool, len(cols))
+	for _, col := range cols {
+		known[col.ColumnName] = true
+	}
+	seen := make(map[string]bool, len(values))
+	parts := make([]string, 0, len(values))
+	args := make([]any, 0, len(values))
+	for _, v := range values {
+		if !known[v.ColumnName] {
+			return "", nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, v.ColumnName)
+		}
+		if seen[v.ColumnName] {
+			return "", nil, apperr.ErrorDuplicateColumn
+		}
+		seen[v.ColumnName] = true
+		col, err := b.quoteIdentifier(v.ColumnName)
+		if err != nil {
+			return "", nil, err
+		}
+		ph, err := b.placeHolder(len(args) + 1)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", col, ph))
+		args = append(args, v.Value)
+	}
+	where, whereArgs, err := b.SelectionWhere(sel, cols, len(args)+1)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(parts, ","), where), append(args, whereArgs...), nil
+}
+
+func (b *Builder) bulkTable(tableName string) (string, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", err
+	}
+	if tableName == "" {
+		return "", apperr.ErrorEmptyTableName
+	}
+	return b.getQuotedTableName(tableName)
+}
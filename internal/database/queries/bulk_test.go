@@ -0,0 +1,150 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+func TestBulkDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		sel    Selection
+		want   string
+		args   Arg
+		err    error
+	}{
+		{
+			name:   "Postgres keys use IN",
+			driver: configs.DriverPostgres,
+			sel:    Selection{Loc: pkLocator, Keys: [][]any{{int64(1)}, {int64(2)}}},
+			want:   "DELETE FROM users WHERE id IN ($1, $2)",
+			args:   Arg{int64(1), int64(2)},
+		},
+		{
+			name:   "Postgres ctid keys are cast",
+			driver: configs.DriverPostgres,
+			sel:    Selection{Loc: ctidLocator, Keys: [][]any{{"(0,1)"}}},
+			want:   "DELETE FROM users WHERE ctid IN ($1::tid)",
+			args:   Arg{"(0,1)"},
+		},
+		{
+			name:   "MySQL composite keys",
+			driver: configs.DriverMySQL,
+			sel:    Selection{Loc: compositePK, Keys: [][]any{{int64(1), int64(2)}, {int64(1), int64(3)}}},
+			want:   "DELETE FROM users WHERE (org_id=? AND user_id=?) OR (org_id=? AND user_id=?)",
+			args:   Arg{int64(1), int64(2), int64(1), int64(3)},
+		},
+		{
+			name:   "SQLite filter",
+			driver: configs.DriverSQLite,
+			sel:    Selection{Loc: rowIDLocator, Filter: &models.Filter{Column: "age", Op: models.FilterLt, Value: int64(18)}},
+			want:   "DELETE FROM users WHERE age < $1",
+			args:   Arg{int64(18)},
+		},
+		{
+			name:   "Nothing selected",
+			driver: configs.DriverPostgres,
+			sel:    Selection{Loc: pkLocator},
+			err:    apperr.ErrorInvalidSelection,
+		},
+		{
+			name:   "Keys and filter",
+			driver: configs.DriverPostgres,
+			sel:    Selection{Loc: pkLocator, Keys: [][]any{{int64(1)}}, Filter: &models.Filter{Column: "age", Op: models.FilterIsNull}},
+			err:    apperr.ErrorInvalidSelection,
+		},
+		{
+			name:   "Key of the wrong size",
+			driver: configs.DriverPostgres,
+			sel:    Selection{Loc: compositePK, Keys: [][]any{{int64(1)}}},
+			err:    locator.ErrorInvalidKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.BulkDelete("users", tt.sel, filterCols)
+			assertErrIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
+
+func TestBulkUpdate(t *testing.T) {
+	keys := Selection{Loc: pkLocator, Keys: [][]any{{int64(1)}, {int64(2)}}}
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		values []models.RowItem
+		sel    Selection
+		want   string
+		args   Arg
+		err    error
+	}{
+		{
+			name:   "Postgres placeholders continue after values",
+			driver: configs.DriverPostgres,
+			values: []models.RowItem{{ColumnName: "name", Value: "x"}, {ColumnName: "first name", Value: "y"}},
+			sel:    keys,
+			want:   `UPDATE users SET name=$1,"first name"=$2 WHERE id IN ($3, $4)`,
+			args:   Arg{"x", "y", int64(1), int64(2)},
+		},
+		{
+			name:   "MySQL filter",
+			driver: configs.DriverMySQL,
+			values: []models.RowItem{{ColumnName: "age", Value: "0"}},
+			sel:    Selection{Loc: pkLocator, Filter: &models.Filter{Column: "age", Op: models.FilterIsNull}},
+			want:   "UPDATE users SET age=? WHERE age IS NULL",
+			args:   Arg{"0"},
+		},
+		{
+			name:   "Unknown column",
+			driver: configs.DriverPostgres,
+			values: []models.RowItem{{ColumnName: "nope", Value: "x"}},
+			sel:    keys,
+			err:    apperr.ErrorInvalidColumn,
+		},
+		{
+			name:   "Duplicate column",
+			driver: configs.DriverPostgres,
+			values: []models.RowItem{{ColumnName: "name", Value: "x"}, {ColumnName: "name", Value: "y"}},
+			sel:    keys,
+			err:    apperr.ErrorDuplicateColumn,
+		},
+		{
+			name:   "No values",
+			driver: configs.DriverPostgres,
+			sel:    keys,
+			err:    apperr.ErrorNoValuesProvided,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.BulkUpdate("users", tt.values, tt.sel, filterCols)
+			assertErrIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
+
+func TestBulkCount(t *testing.T) {
+	builder := NewBuilder(configs.DriverPostgres, 10)
+	query, args, err := builder.BulkCount("users", Selection{Loc: pkLocator, Keys: [][]any{{int64(7)}}}, filterCols)
+	assertErr(t, err, nil)
+	assertQuery(t, query, "SELECT COUNT(*) FROM users WHERE id IN ($1)")
+	assertArgs(t, args, Arg{int64(7)})
+}
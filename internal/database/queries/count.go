@@ -0,0 +1,27 @@
+package queries
+
+import (
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+)
+
+// EstimateRows reads the row count the database keeps in its catalog for the
+// planner. The query returns one text or number column whose first number is
+// the estimate, or no row when the table was never analyzed.
+func (b *Builder) EstimateRows(tableName string) (string, []any, error) {
+	if tableName == "" {
+		return "", nil, apperr.ErrorEmptyTableName
+	}
+	switch b.driver {
+	case configs.DriverPostgres:
+		schema, table := b.SplitTableName(tableName)
+		return "SELECT c.reltuples::bigint FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = $2", []any{schema, table}, nil
+	case configs.DriverMySQL:
+		return "SELECT table_rows FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", []any{tableName}, nil
+	case configs.DriverSQLite:
+		// sqlite_stat1 only exists after ANALYZE, every row of a table starts
+		// with its row count
+		return "SELECT stat FROM sqlite_stat1 WHERE tbl = $1 LIMIT 1", []any{tableName}, nil
+	}
+	return "", nil, apperr.ErrorInvalidDriver
+}
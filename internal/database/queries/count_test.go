@@ -0,0 +1,72 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+)
+
+func TestEstimateRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  configs.Driver
+		table   string
+		want    string
+		args    Arg
+		wantErr error
+	}{
+		{
+			name:   "Postgres",
+			driver: configs.DriverPostgres,
+			table:  "users",
+			want:   "SELECT c.reltuples::bigint FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = $2",
+			args:   Arg{"public", "users"},
+		},
+		{
+			name:   "Postgres schema",
+			driver: configs.DriverPostgres,
+			table:  "sales.orders",
+			want:   "SELECT c.reltuples::bigint FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = $2",
+			args:   Arg{"sales", "orders"},
+		},
+		{
+			name:   "MySQL",
+			driver: configs.DriverMySQL,
+			table:  "users",
+			want:   "SELECT table_rows FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+			args:   Arg{"users"},
+		},
+		{
+			name:   "SQLite",
+			driver: configs.DriverSQLite,
+			table:  "users",
+			want:   "SELECT stat FROM sqlite_stat1 WHERE tbl = $1 LIMIT 1",
+			args:   Arg{"users"},
+		},
+		{
+			name:    "Empty table name",
+			driver:  configs.DriverPostgres,
+			wantErr: apperr.ErrorEmptyTableName,
+		},
+		{
+			name:    "Unknown driver",
+			driver:  "oracle",
+			table:   "users",
+			wantErr: apperr.ErrorInvalidDriver,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.EstimateRows(tt.table)
+			if tt.wantErr != nil {
+				assertErrIs(t, err, tt.wantErr)
+				return
+			}
+			assertErr(t, err, nil)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package queries
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+// ExportRows selects every matching row without a limit, the caller is
+// expected to stream the result.
+func (b *Builder) ExportRows(props models.ExportProps, cols []models.ListDataCol) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
+	}
+	if props.TableName == "" {
+		return "", nil, apperr.ErrorEmptyTableName
+	}
+	hasCol := func(name string) bool {
+		return slices.ContainsFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == name })
+	}
+	tableName, err := b.getQuotedTableName(props.TableName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	selection := "*"
+	if len(props.Columns) > 0 {
+		selected := make([]string, 0, len(props.Columns))
+		for i, name := range props.Columns {
+			if !hasCol(name) {
+				return "", nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, name)
+			}
+			if slices.Contains(props.Columns[:i], name) {
+				return "", nil, fmt.Errorf("%w: %s", apperr.ErrorDuplicateColumn, name)
+			}
+			col, err := b.quoteIdentifier(name)
+			if err != nil {
+				return "", nil, err
+			}
+			selected = append(selected, col)
+		}
+		selection = strings.Join(selected, ", ")
+	}
+	parts := []string{fmt.Sprintf("SELECT %s FROM %s", selection, tableName)}
+
+	where, args, err := b.Where(props.Filter, cols, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		parts = append(parts, "WHERE "+where)
+	}
+
+	if props.Column != "" {
+		if !hasCol(props.Column) {
+			return "", nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, props.Column)
+		}
+		col, err := b.quoteIdentifier(props.Column)
+		if err != nil {
+			return "", nil, err
+		}
+		order := "ASC"
+		if strings.ToLower(props.Order) == "desc" {
+			order = "DESC"
+		}
+		parts = append(parts, fmt.Sprintf("ORDER BY %s %s", col, order))
+	}
+	return strings.Join(parts, " "), args, nil
+}
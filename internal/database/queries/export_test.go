@@ -0,0 +1,69 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+func TestExportRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		props  models.ExportProps
+		want   string
+		args   []any
+		err    error
+	}{
+		{
+			name:   "whole table",
+			driver: configs.DriverPostgres,
+			props:  models.ExportProps{TableName: "users"},
+			want:   "SELECT * FROM users",
+		},
+		{
+			name:   "columns, filter and order",
+			driver: configs.DriverMySQL,
+			props: models.ExportProps{
+				TableName: "users",
+				Columns:   []string{"id", "email"},
+				Column:    "email",
+				Order:     "DESC",
+				Filter:    &models.Filter{Column: "name", Op: models.FilterEq, Value: "jo"},
+			},
+			want: "SELECT id, email FROM users WHERE name = ? ORDER BY email DESC",
+			args: []any{"jo"},
+		},
+		{
+			name:   "unknown column",
+			driver: configs.DriverSQLite,
+			props:  models.ExportProps{TableName: "users", Columns: []string{"nope"}},
+			err:    apperr.ErrorInvalidColumn,
+		},
+		{
+			name:   "duplicate column",
+			driver: configs.DriverSQLite,
+			props:  models.ExportProps{TableName: "users", Columns: []string{"id", "id"}},
+			err:    apperr.ErrorDuplicateColumn,
+		},
+		{
+			name:   "unknown sort column",
+			driver: configs.DriverSQLite,
+			props:  models.ExportProps{TableName: "users", Column: "id; DROP TABLE users"},
+			err:    apperr.ErrorInvalidColumn,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.ExportRows(tt.props, alterCols)
+			assertErrIs(t, err, tt.err)
+			assertQuery(t, query, tt.want)
+			if len(tt.args) > 0 {
+				assertArgs(t, args, tt.args)
+			}
+		})
+	}
+}
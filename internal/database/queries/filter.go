@@ -0,0 +1,172 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/utils"
+)
+
+const maxFilterDepth = 8
+
+var comparisonOps = map[models.FilterOp]string{
+	models.FilterEq:  "=",
+	models.FilterNeq: "<>",
+	models.FilterLt:  "<",
+	models.FilterLte: "<=",
+	models.FilterGt:  ">",
+	models.FilterGte: ">=",
+}
+
+type whereBuilder struct {
+	b       *Builder
+	cols    map[string]bool
+	argsIdx int
+	args    []any
+}
+
+// Where renders a filter into a WHERE condition (without the keyword). Every
+// column of the filter has to exist in cols. An empty string is returned for a
+// nil filter.
+func (b *Builder) Where(filter *models.Filter, cols []models.ListDataCol, argsIdx int) (string, []any, error) {
+	if filter == nil {
+		return "", nil, nil
+	}
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
+	}
+	w := whereBuilder{
+		b:       b,
+		cols:    make(map[string]bool, len(cols)),
+		argsIdx: argsIdx,
+	}
+	for _, col := range cols {
+		w.cols[col.ColumnName] = true
+	}
+	clause, err := w.render(*filter, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return clause, w.args, nil
+}
+
+func (w *whereBuilder) placeHolder(value any) (string, error) {
+	ph, err := w.b.placeHolder(w.argsIdx + len(w.args))
+	if err != nil {
+		return "", err
+	}
+	w.args = append(w.args, utils.NormalizeNumber(value))
+	return ph, nil
+}
+
+func (w *whereBuilder) render(f models.Filter, depth int) (string, error) {
+	if depth > maxFilterDepth {
+		return "", fmt.Errorf("%w: nested deeper than %d levels", apperr.ErrorInvalidFilter, maxFilterDepth)
+	}
+	isGroup := len(f.And) > 0 || len(f.Or) > 0
+	switch {
+	case len(f.And) > 0 && len(f.Or) > 0:
+		return "", fmt.Errorf("%w: a group can't have both and & or", apperr.ErrorInvalidFilter)
+	case isGroup && (f.Column != "" || f.Op != ""):
+		return "", fmt.Errorf("%w: a group can't have a column condition", apperr.ErrorInvalidFilter)
+	case len(f.And) > 0:
+		return w.group(f.And, " AND ", depth)
+	case len(f.Or) > 0:
+		return w.group(f.Or, " OR ", depth)
+	}
+	return w.condition(f)
+}
+
+func (w *whereBuilder) group(filters []models.Filter, sep string, depth int) (string, error) {
+	parts := make([]string, 0, len(filters))
+	for _, f := range filters {
+		part, err := w.render(f, depth+1)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, sep) + ")", nil
+}
+
+func (w *whereBuilder) condition(f models.Filter) (string, error) {
+	if !w.cols[f.Column] {
+		return "", fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, f.Column)
+	}
+	col, err := w.b.quoteIdentifier(f.Column)
+	if err != nil {
+		return "", err
+	}
+
+	if sqlOp, ok := comparisonOps[f.Op]; ok {
+		if f.Value == nil {
+			return "", fmt.Errorf("%w: %s needs a value, use isnull or notnull for NULL", apperr.ErrorInvalidFilter, f.Op)
+		}
+		ph, err := w.placeHolder(f.Value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", col, sqlOp, ph), nil
+	}
+
+	switch f.Op {
+	case models.FilterBetween:
+		if len(f.Values) != 2 || f.Values[0] == nil || f.Values[1] == nil {
+			return "", fmt.Errorf("%w: between needs exactly two values", apperr.ErrorInvalidFilter)
+		}
+		from, err := w.placeHolder(f.Values[0])
+		if err != nil {
+			return "", err
+		}
+		to, err := w.placeHolder(f.Values[1])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", col, from, to), nil
+	case models.FilterIn:
+		if len(f.Values) == 0 {
+			return "", fmt.Errorf("%w: in needs at least one value", apperr.ErrorInvalidFilter)
+		}
+		phs := make([]string, 0, len(f.Values))
+		for _, v := range f.Values {
+			ph, err := w.placeHolder(v)
+			if err != nil {
+				return "", err
+			}
+			phs = append(phs, ph)
+		}
+		return fmt.Sprintf("%s IN (%s)", col, strings.Join(phs, ", ")), nil
+	case models.FilterLike, models.FilterILike:
+		pattern, ok := f.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("%w: %s needs a string pattern", apperr.ErrorInvalidFilter, f.Op)
+		}
+		ph, err := w.placeHolder(pattern)
+		if err != nil {
+			return "", err
+		}
+		if w.b.driver == configs.DriverPostgres {
+			// cast so patterns also work on non text columns
+			op := "LIKE"
+			if f.Op == models.FilterILike {
+				op = "ILIKE"
+			}
+			return fmt.Sprintf("%s::text %s %s", col, op, ph), nil
+		}
+		if f.Op == models.FilterILike {
+			return fmt.Sprintf("LOWER(%s) LIKE LOWER(%s)", col, ph), nil
+		}
+		return fmt.Sprintf("%s LIKE %s", col, ph), nil
+	case models.FilterIsNull:
+		return fmt.Sprintf("%s IS NULL", col), nil
+	case models.FilterNotNull:
+		return fmt.Sprintf("%s IS NOT NULL", col), nil
+	}
+	return "", fmt.Errorf("%w: unknown operator %q", apperr.ErrorInvalidFilter, f.Op)
+}
@@ -0,0 +1,250 @@
+package queries
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+var filterCols = []models.ListDataCol{
+	{ColumnName: "id"},
+	{ColumnName: "name"},
+	{ColumnName: "age"},
+	{ColumnName: "deleted_at"},
+	{ColumnName: "first name"},
+}
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  configs.Driver
+		filter  *models.Filter
+		argsIdx int
+		want    string
+		args    Arg
+		err     error
+	}{
+		{
+			name:   "nil filter",
+			driver: configs.DriverPostgres,
+			filter: nil,
+		},
+		{
+			name:    "Postgres eq",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "id", Op: models.FilterEq, Value: json.Number("5")},
+			argsIdx: 1,
+			want:    "id = $1",
+			args:    Arg{int64(5)},
+		},
+		{
+			name:    "MySQL neq",
+			driver:  configs.DriverMySQL,
+			filter:  &models.Filter{Column: "name", Op: models.FilterNeq, Value: "bob"},
+			argsIdx: 1,
+			want:    "name <> ?",
+			args:    Arg{"bob"},
+		},
+		{
+			name:    "SQLite placeholders start from argsIdx",
+			driver:  configs.DriverSQLite,
+			filter:  &models.Filter{Column: "age", Op: models.FilterGte, Value: json.Number("1.5")},
+			argsIdx: 3,
+			want:    "age >= $3",
+			args:    Arg{1.5},
+		},
+		{
+			name:    "between",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "age", Op: models.FilterBetween, Values: []any{json.Number("18"), json.Number("30")}},
+			argsIdx: 1,
+			want:    "age BETWEEN $1 AND $2",
+			args:    Arg{int64(18), int64(30)},
+		},
+		{
+			name:    "in",
+			driver:  configs.DriverMySQL,
+			filter:  &models.Filter{Column: "name", Op: models.FilterIn, Values: []any{"a", "b", "c"}},
+			argsIdx: 1,
+			want:    "name IN (?, ?, ?)",
+			args:    Arg{"a", "b", "c"},
+		},
+		{
+			name:    "Postgres ilike casts to text",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "name", Op: models.FilterILike, Value: "%bo%"},
+			argsIdx: 1,
+			want:    "name::text ILIKE $1",
+			args:    Arg{"%bo%"},
+		},
+		{
+			name:    "MySQL ilike lowers both sides",
+			driver:  configs.DriverMySQL,
+			filter:  &models.Filter{Column: "name", Op: models.FilterILike, Value: "%Bo%"},
+			argsIdx: 1,
+			want:    "LOWER(name) LIKE LOWER(?)",
+			args:    Arg{"%Bo%"},
+		},
+		{
+			name:    "SQLite like",
+			driver:  configs.DriverSQLite,
+			filter:  &models.Filter{Column: "name", Op: models.FilterLike, Value: "b%"},
+			argsIdx: 1,
+			want:    "name LIKE $1",
+			args:    Arg{"b%"},
+		},
+		{
+			name:    "is null and is not null",
+			driver:  configs.DriverPostgres,
+			argsIdx: 1,
+			filter: &models.Filter{And: []models.Filter{
+				{Column: "deleted_at", Op: models.FilterIsNull},
+				{Column: "name", Op: models.FilterNotNull},
+			}},
+			want: "(deleted_at IS NULL AND name IS NOT NULL)",
+		},
+		{
+			name:    "nested groups keep placeholder order",
+			driver:  configs.DriverPostgres,
+			argsIdx: 1,
+			filter: &models.Filter{And: []models.Filter{
+				{Column: "age", Op: models.FilterGt, Value: json.Number("18")},
+				{Or: []models.Filter{
+					{Column: "name", Op: models.FilterEq, Value: "a"},
+					{Column: "name", Op: models.FilterEq, Value: "b"},
+				}},
+			}},
+			want: "(age > $1 AND (name = $2 OR name = $3))",
+			args: Arg{int64(18), "a", "b"},
+		},
+		{
+			name:    "column with spaces is quoted",
+			driver:  configs.DriverMySQL,
+			filter:  &models.Filter{Column: "first name", Op: models.FilterEq, Value: "x"},
+			argsIdx: 1,
+			want:    "`first name` = ?",
+			args:    Arg{"x"},
+		},
+		{
+			name:    "unknown column",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "id; DROP TABLE users", Op: models.FilterEq, Value: "1"},
+			argsIdx: 1,
+			err:     apperr.ErrorInvalidColumn,
+		},
+		{
+			name:    "unknown operator",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "id", Op: "regex", Value: "1"},
+			argsIdx: 1,
+			err:     apperr.ErrorInvalidFilter,
+		},
+		{
+			name:    "eq with null value",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "id", Op: models.FilterEq},
+			argsIdx: 1,
+			err:     apperr.ErrorInvalidFilter,
+		},
+		{
+			name:    "between with one value",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "id", Op: models.FilterBetween, Values: []any{json.Number("1")}},
+			argsIdx: 1,
+			err:     apperr.ErrorInvalidFilter,
+		},
+		{
+			name:    "empty in",
+			driver:  configs.DriverPostgres,
+			filter:  &models.Filter{Column: "id", Op: models.FilterIn},
+			argsIdx: 1,
+			err:     apperr.ErrorInvalidFilter,
+		},
+		{
+			name:    "group with and & or",
+			driver:  configs.DriverPostgres,
+			argsIdx: 1,
+			filter: &models.Filter{
+				And: []models.Filter{{Column: "id", Op: models.FilterIsNull}},
+				Or:  []models.Filter{{Column: "id", Op: models.FilterIsNull}},
+			},
+			err: apperr.ErrorInvalidFilter,
+		},
+		{
+			name:    "invalid driver",
+			driver:  configs.Driver("oracle"),
+			filter:  &models.Filter{Column: "id", Op: models.FilterIsNull},
+			argsIdx: 1,
+			err:     apperr.ErrorInvalidDriver,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.Where(tt.filter, filterCols, tt.argsIdx)
+			assertErrIs(t, err, tt.err)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
+
+func TestListRowsWithFilter(t *testing.T) {
+	builder := NewBuilder(configs.DriverPostgres, 10)
+	props := models.ListDataProps{
+		TableName: "users",
+		Column:    "id",
+		Order:     "desc",
+		Limit:     10,
+		Offset:    20,
+		Filter:    &models.Filter{Column: "name", Op: models.FilterEq, Value: "bob"},
+	}
+	query, args, err := builder.ListRows(props, filterCols, locator.Locator{})
+	assertErr(t, err, nil)
+	assertQuery(t, query, "SELECT * FROM users WHERE name = $1 ORDER BY id DESC LIMIT $2 OFFSET $3")
+	assertArgs(t, args, Arg{"bob", 10, 20})
+}
+
+func TestCountRows(t *testing.T) {
+	filter := &models.Filter{Column: "age", Op: models.FilterLt, Value: json.Number("30")}
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		filter *models.Filter
+		want   string
+		args   Arg
+	}{
+		{
+			name:   "without filter",
+			driver: configs.DriverPostgres,
+			want:   "SELECT COUNT(*) FROM users",
+		},
+		{
+			name:   "Postgres with filter",
+			driver: configs.DriverPostgres,
+			filter: filter,
+			want:   "SELECT COUNT(*) FROM users WHERE age < $1",
+			args:   Arg{int64(30)},
+		},
+		{
+			name:   "MySQL with filter",
+			driver: configs.DriverMySQL,
+			filter: filter,
+			want:   "SELECT COUNT(*) FROM users WHERE age < ?",
+			args:   Arg{int64(30)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.CountRows("users", tt.filter, filterCols)
+			assertErr(t, err, nil)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+)
+
+// maxImportParams keeps batched inserts below the bind parameter limits of
+// every driver (SQLite 32766, Postgres and MySQL 65535).
+const maxImportParams = 30000
+
+// ImportBatchSize returns how many rows of numCols columns fit in one
+// InsertRows statement.
+func ImportBatchSize(numCols int) int {
+	return max(1, min(500, maxImportParams/max(numCols, 1)))
+}
+
+// InsertRows builds a single multi row INSERT, every row must hold a value
+// for each of columns.
+func (b *Builder) InsertRows(tableName string, columns []string, rows [][]any) (string, []any, error) {
+	return b.insertRows(tableName, columns, rows, false)
+}
+
+// insertRows builds a multi-row insert, with overriding identity columns
+// take the given values on Postgres.
+func (b *Builder) insertRows(tableName string, columns []string, rows [][]any, overriding bool) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
+	}
+	if tableName == "" {
+		return "", nil, apperr.ErrorEmptyTableName
+	}
+	if len(columns) == 0 || len(rows) == 0 {
+		return "", nil, apperr.ErrorNoValuesProvided
+	}
+	table, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	quoted := make([]string, 0, len(columns))
+	for _, name := range columns {
+		col, err := b.quoteIdentifier(name)
+		if err != nil {
+			return "", nil, err
+		}
+		quoted = append(quoted, col)
+	}
+
+	args := make([]any, 0, len(columns)*len(rows))
+	values := make([]string, 0, len(rows))
+	placeholders := make([]string, len(columns))
+	for _, row := range rows {
+		if len(row) != len(columns) {
+			return "", nil, apperr.ErrorNotSameRowColsSize
+		}
+		for i, v := range row {
+			ph, err := b.placeHolder(len(args) + 1)
+			if err != nil {
+				return "", nil, err
+			}
+			placeholders[i] = ph
+			args = append(args, v)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+	}
+	override := ""
+	if overriding && b.driver == configs.DriverPostgres {
+		override = " OVERRIDING SYSTEM VALUE"
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s)%s VALUES %s", table, strings.Join(quoted, ", "), override, strings.Join(values, ", "))
+	return query, args, nil
+}
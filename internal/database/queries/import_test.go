@@ -0,0 +1,69 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+)
+
+func TestInsertRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  configs.Driver
+		columns []string
+		rows    [][]any
+		want    string
+		args    []any
+		err     error
+	}{
+		{
+			name:    "postgres",
+			driver:  configs.DriverPostgres,
+			columns: []string{"id", "name"},
+			rows:    [][]any{{1, "a"}, {2, "b"}},
+			want:    "INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)",
+			args:    []any{1, "a", 2, "b"},
+		},
+		{
+			name:    "mysql",
+			driver:  configs.DriverMySQL,
+			columns: []string{"name"},
+			rows:    [][]any{{"a"}, {nil}},
+			want:    "INSERT INTO users (name) VALUES (?), (?)",
+			args:    []any{"a", nil},
+		},
+		{
+			name:    "no rows",
+			driver:  configs.DriverSQLite,
+			columns: []string{"name"},
+			err:     apperr.ErrorNoValuesProvided,
+		},
+		{
+			name:    "row size mismatch",
+			driver:  configs.DriverSQLite,
+			columns: []string{"id", "name"},
+			rows:    [][]any{{1, "a"}, {2}},
+			err:     apperr.ErrorNotSameRowColsSize,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.InsertRows("users", tt.columns, tt.rows)
+			assertErrIs(t, err, tt.err)
+			assertQuery(t, query, tt.want)
+			if len(tt.args) > 0 {
+				assertArgs(t, args, tt.args)
+			}
+		})
+	}
+}
+
+func TestImportBatchSize(t *testing.T) {
+	for numCols, want := range map[int]int{0: 500, 3: 500, 100: 300, 40000: 1} {
+		if got := ImportBatchSize(numCols); got != want {
+			t.Errorf("ImportBatchSize(%d) = %d, want %d", numCols, got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package queries
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+)
+
+// every index query returns one row per index column:
+// name, column, is_unique, is_primary, method, predicate, definition
+const postgresIndexesListQuery = `
+SELECT
+    i.indexname,
+    COALESCE(a.attname, pg_get_indexdef(ix.indexrelid, k.n::int, true)) AS column_name,
+    ix.indisunique AS is_unique,
+    ix.indisprimary AS is_primary,
+    am.amname AS method,
+    COALESCE(pg_get_expr(ix.indpred, ix.indrelid), '') AS predicate,
+    i.indexdef AS definition
+FROM pg_indexes i
+JOIN pg_namespace ns
+    ON ns.nspname = i.schemaname
+JOIN pg_class ic
+    ON ic.relname = i.indexname
+    AND ic.relnamespace = ns.oid
+JOIN pg_index ix
+    ON ix.indexrelid = ic.oid
+JOIN pg_am am
+    ON am.oid = ic.relam
+CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, n)
+LEFT JOIN pg_attribute a
+    ON a.attrelid = ix.indrelid
+    AND a.attnum = k.attnum
+    AND k.attnum > 0
+WHERE i.schemaname = $2
+  AND i.tablename = $1
+  AND k.n <= ix.indnkeyatts
+ORDER BY i.indexname, k.n;
+`
+
+const mysqlIndexesListQuery = `
+SELECT
+    s.index_name,
+    COALESCE(s.column_name, '') AS column_name,
+    (s.non_unique = 0) AS is_unique,
+    (s.index_name = 'PRIMARY') AS is_primary,
+    s.index_type AS method,
+    '' AS predicate,
+    '' AS definition
+FROM information_schema.statistics s
+WHERE s.table_schema = DATABASE()
+  AND s.table_name = ?
+ORDER BY s.index_name, s.seq_in_index;
+`
+
+const sqliteIndexesListQuery = `
+SELECT
+    il.name,
+    COALESCE(ii.name, '') AS column_name,
+    il."unique" AS is_unique,
+    (il.origin = 'pk') AS is_primary,
+    'btree' AS method,
+    CASE
+        WHEN il.partial = 1
+        THEN trim(substr(m.sql, instr(upper(m.sql), ' WHERE ') + 7))
+        ELSE ''
+    END AS predicate,
+    COALESCE(m.sql, '') AS definition
+FROM pragma_index_list(?) il
+JOIN pragma_index_info(il.name) ii
+LEFT JOIN sqlite_master m
+    ON m.type = 'index'
+    AND m.name = il.name
+ORDER BY il.name, ii.seqno;
+`
+
+func (b *Builder) ListIndexes(tableName string) (string, []any, error) {
+	switch b.driver {
+	case configs.DriverPostgres:
+		schema, table := b.SplitTableName(tableName)
+		return postgresIndexesListQuery, []any{table, schema}, nil
+	case configs.DriverMySQL:
+		return mysqlIndexesListQuery, []any{tableName}, nil
+	case configs.DriverSQLite:
+		return sqliteIndexesListQuery, []any{tableName}, nil
+	}
+
+	logger.Error("error : %s , %s", ErrUnknownDriver.Error(), b.driver)
+	return "", nil, ErrUnknownDriver
+}
+
+var indexMethods = map[configs.Driver][]string{
+	configs.DriverPostgres: {"btree", "hash", "gist", "spgist", "gin", "brin"},
+	configs.DriverMySQL:    {"btree", "hash"},
+	configs.DriverSQLite:   {"btree"},
+}
+
+// maxIdentifierLength is the longest name Postgres keeps, MySQL allows one more.
+const maxIdentifierLength = 63
+
+// defaultIndexName names an index after its table and columns, characters an
+// identifier can't hold become underscores.
+func defaultIndexName(table string, columns []string) string {
+	name := []byte(strings.Join(append([]string{"idx", table}, columns...), "_"))
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name[:min(len(name), maxIdentifierLength)])
+}
+
+func (b *Builder) validateIndex(tableName string, input database.IndexInput, cols []models.ListDataCol) (database.IndexInput, error) {
+	if len(input.Columns) == 0 {
+		return input, fmt.Errorf("%w: at least one column is required", apperr.ErrorInvalidIndex)
+	}
+	for i, name := range input.Columns {
+		if !slices.ContainsFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == name }) {
+			return input, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, name)
+		}
+		if slices.Contains(input.Columns[:i], name) {
+			return input, fmt.Errorf("%w: %s", apperr.ErrorDuplicateColumn, name)
+		}
+	}
+	if input.Name == "" {
+		_, table := b.SplitTableName(tableName)
+		input.Name = defaultIndexName(table, input.Columns)
+	}
+	if !validIdentifier.MatchString(input.Name) {
+		return input, fmt.Errorf("%w: invalid index name %q", apperr.ErrorInvalidIndex, input.Name)
+	}
+	input.Method = strings.ToLower(input.Method)
+	if input.Method != "" && !slices.Contains(indexMethods[b.driver], input.Method) {
+		return input, fmt.Errorf("%w: method %q is not supported on %s", apperr.ErrorInvalidIndex, input.Method, b.driver)
+	}
+	input.Where = strings.TrimSpace(input.Where)
+	if input.Where != "" {
+		if b.driver == configs.DriverMySQL {
+			return input, fmt.Errorf("%w: partial indexes are not supported on %s", apperr.ErrorInvalidIndex, b.driver)
+		}
+		if strings.Contains(input.Where, ";") {
+			return input, fmt.Errorf("%w: predicate can't contain ';'", apperr.ErrorInvalidIndex)
+		}
+	}
+	return input, nil
+}
+
+// CreateIndex builds the CREATE INDEX statement for input. Every column has
+// to exist in cols.
+func (b *Builder) CreateIndex(tableName string, input database.IndexInput, cols []models.ListDataCol) (string, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", err
+	}
+	input, err := b.validateIndex(tableName, input, cols)
+	if err != nil {
+		return "", err
+	}
+	table, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return "", err
+	}
+	columns := make([]string, 0, len(input.Columns))
+	for _, name := range input.Columns {
+		col, err := b.quoteIdentifier(name)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, col)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CREATE ")
+	if input.IsUnique {
+		sb.WriteString("UNIQUE ")
+	}
+	fmt.Fprintf(&sb, "INDEX %s ON %s", input.Name, table)
+	if input.Method != "" && b.driver == configs.DriverPostgres {
+		fmt.Fprintf(&sb, " USING %s", input.Method)
+	}
+	fmt.Fprintf(&sb, " (%s)", strings.Join(columns, ", "))
+	if input.Method != "" && b.driver == configs.DriverMySQL {
+		fmt.Fprintf(&sb, " USING %s", strings.ToUpper(input.Method))
+	}
+	if input.Where != "" {
+		fmt.Fprintf(&sb, " WHERE %s", input.Where)
+	}
+	return sb.String(), nil
+}
+
+func (b *Builder) DropIndex(tableName, indexName string) (string, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", err
+	}
+	if indexName == "" {
+		return "", fmt.Errorf("%w: index name cannot be empty", apperr.ErrorInvalidIndex)
+	}
+	index, err := b.quoteIdentifier(indexName)
+	if err != nil {
+		return "", err
+	}
+	if b.driver == configs.DriverPostgres {
+		// indexes live in the schema of their table
+		if schema, _ := b.SplitTableName(tableName); schema != DefaultSchema {
+			if schema, err = b.quoteIdentifier(schema); err != nil {
+				return "", err
+			}
+			index = schema + "." + index
+		}
+	}
+	if b.driver != configs.DriverMySQL {
+		return fmt.Sprintf("DROP INDEX %s", index), nil
+	}
+	table, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("DROP INDEX %s ON %s", index, table), nil
+}
@@ -0,0 +1,152 @@
+package queries
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database"
+)
+
+func TestCreateIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		table  string
+		input  database.IndexInput
+		want   string
+		err    error
+	}{
+		{
+			name:   "generated name",
+			driver: configs.DriverSQLite,
+			input:  database.IndexInput{Columns: []string{"name", "email"}},
+			want:   "CREATE INDEX idx_users_name_email ON users (name, email)",
+		},
+		{
+			name:   "generated name without the schema",
+			driver: configs.DriverPostgres,
+			table:  "sales.users",
+			input:  database.IndexInput{Columns: []string{"name"}},
+			want:   "CREATE INDEX idx_users_name ON sales.users (name)",
+		},
+		{
+			name:   "Postgres unique partial with method",
+			driver: configs.DriverPostgres,
+			input: database.IndexInput{
+				Name:     "users_email_key",
+				Columns:  []string{"email"},
+				IsUnique: true,
+				Method:   "BTREE",
+				Where:    "email IS NOT NULL",
+			},
+			want: "CREATE UNIQUE INDEX users_email_key ON users USING btree (email) WHERE email IS NOT NULL",
+		},
+		{
+			name:   "MySQL method",
+			driver: configs.DriverMySQL,
+			input:  database.IndexInput{Columns: []string{"name"}, Method: "hash"},
+			want:   "CREATE INDEX idx_users_name ON users (name) USING HASH",
+		},
+		{
+			name:   "MySQL partial index",
+			driver: configs.DriverMySQL,
+			input:  database.IndexInput{Columns: []string{"name"}, Where: "name <> ''"},
+			err:    apperr.ErrorInvalidIndex,
+		},
+		{
+			name:   "unsupported method",
+			driver: configs.DriverSQLite,
+			input:  database.IndexInput{Columns: []string{"name"}, Method: "gin"},
+			err:    apperr.ErrorInvalidIndex,
+		},
+		{
+			name:   "no columns",
+			driver: configs.DriverPostgres,
+			input:  database.IndexInput{Name: "idx"},
+			err:    apperr.ErrorInvalidIndex,
+		},
+		{
+			name:   "unknown column",
+			driver: configs.DriverPostgres,
+			input:  database.IndexInput{Columns: []string{"nope"}},
+			err:    apperr.ErrorInvalidColumn,
+		},
+		{
+			name:   "duplicate column",
+			driver: configs.DriverPostgres,
+			input:  database.IndexInput{Columns: []string{"name", "name"}},
+			err:    apperr.ErrorDuplicateColumn,
+		},
+		{
+			name:   "invalid name",
+			driver: configs.DriverPostgres,
+			input:  database.IndexInput{Name: "idx; DROP TABLE users", Columns: []string{"name"}},
+			err:    apperr.ErrorInvalidIndex,
+		},
+		{
+			name:   "statement in predicate",
+			driver: configs.DriverPostgres,
+			input:  database.IndexInput{Columns: []string{"name"}, Where: "true; DROP TABLE users"},
+			err:    apperr.ErrorInvalidIndex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			table := tt.table
+			if table == "" {
+				table = "users"
+			}
+			got, err := builder.CreateIndex(table, tt.input, alterCols)
+			assertErrIs(t, err, tt.err)
+			assertQuery(t, got, tt.want)
+		})
+	}
+}
+
+func TestDefaultIndexName(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		columns []string
+		want    string
+	}{
+		{name: "plain", table: "users", columns: []string{"name"}, want: "idx_users_name"},
+		{name: "odd characters", table: "order items", columns: []string{"e-mail", "näme"}, want: "idx_order_items_e_mail_n__me"},
+		{name: "too long", table: strings.Repeat("t", 70), columns: []string{"id"}, want: "idx_" + strings.Repeat("t", 59)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultIndexName(tt.table, tt.columns); got != tt.want {
+				t.Errorf("defaultIndexName() = %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		table  string
+		index  string
+		want   string
+		err    error
+	}{
+		{name: "Postgres", driver: configs.DriverPostgres, table: "users", index: "idx_users_name", want: "DROP INDEX idx_users_name"},
+		{name: "Postgres schema", driver: configs.DriverPostgres, table: "sales.users", index: "idx_users_name", want: "DROP INDEX sales.idx_users_name"},
+		{name: "SQLite", driver: configs.DriverSQLite, table: "users", index: "idx_users_name", want: "DROP INDEX idx_users_name"},
+		{name: "MySQL needs table", driver: configs.DriverMySQL, table: "users", index: "idx_users_name", want: "DROP INDEX idx_users_name ON users"},
+		{name: "empty name", driver: configs.DriverPostgres, table: "users", err: apperr.ErrorInvalidIndex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			got, err := builder.DropIndex(tt.table, tt.index)
+			assertErrIs(t, err, tt.err)
+			assertQuery(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package queries
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+// KeysetColumns returns the columns rows are ordered by with keyset paging:
+// the sort column, if any, followed by the key as tiebreaker.
+func KeysetColumns(column string, loc locator.Locator) []string {
+	if column == "" || slices.Equal(loc.Columns, []string{column}) {
+		return loc.Columns
+	}
+	return append([]string{column}, loc.Columns...)
+}
+
+// KeysetSortable reports whether rows can be paged by column and the key of
+// loc with keyset paging. SQLite stores dates as text that the driver reads as
+// time.Time, a cursor value wouldn't compare like the stored text anymore.
+func (b *Builder) KeysetSortable(column string, cols []models.ListDataCol, loc locator.Locator) bool {
+	if !loc.Ordered() {
+		return false
+	}
+	if b.driver != configs.DriverSQLite {
+		return true
+	}
+	for _, name := range KeysetColumns(column, loc) {
+		idx := slices.IndexFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == name })
+		if idx < 0 {
+			continue
+		}
+		dataType := strings.ToLower(cols[idx].DataType)
+		if strings.Contains(dataType, "date") || strings.Contains(dataType, "time") {
+			return false
+		}
+	}
+	return true
+}
+
+// KeysetRows lists the rows after the cursor position, or before it with
+// cursor.Before, instead of skipping them with an offset. One row more than
+// props.Limit is read to tell if there is another page, the rows of a Before
+// page come in reverse order.
+func (b *Builder) KeysetRows(props models.ListDataProps, cols []models.ListDataCol, loc locator.Locator, cursor models.Cursor) (string, []any, error) {
+	if props.TableName == "" {
+		return "", nil, apperr.ErrorEmptyTableName
+	}
+	if props.Limit <= 0 {
+		return "", nil, apperr.ErrorInvalidPagination
+	}
+	if props.Limit > b.maxLimit {
+		return "", nil, apperr.ErrorLimitTooLarge(b.maxLimit)
+	}
+	if !loc.Ordered() {
+		return "", nil, fmt.Errorf("%w: table has no key to page by", locator.ErrorInvalidCursor)
+	}
+	if cursor.Column != "" && !slices.ContainsFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == cursor.Column }) {
+		return "", nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, cursor.Column)
+	}
+	order := KeysetColumns(cursor.Column, loc)
+	if len(cursor.Values) != 0 && len(cursor.Values) != len(order) {
+		return "", nil, locator.ErrorInvalidCursor
+	}
+	tableName, err := b.getQuotedTableName(props.TableName)
+	if err != nil {
+		return "", nil, err
+	}
+	selection := "*"
+	if pseudo := loc.PseudoColumn(); pseudo != "" {
+		selection = fmt.Sprintf("%s, %s.*", pseudo, tableName)
+	}
+	parts := []string{fmt.Sprintf("SELECT %s FROM %s", selection, tableName)}
+
+	var conditions []string
+	args := []any{}
+	where, whereArgs, err := b.Where(props.Filter, cols, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		conditions = append(conditions, where)
+		args = append(args, whereArgs...)
+	}
+	// the rows before the cursor are read backwards from it
+	desc := cursor.Desc != cursor.Before
+	if len(cursor.Values) > 0 {
+		condition, conditionArgs, err := b.keysetCondition(order, len(order) > len(loc.Columns), cursor.Values, desc, len(args)+1)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+	if len(conditions) > 0 {
+		parts = append(parts, "WHERE "+strings.Join(conditions, " AND "))
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	orderBy := make([]string, 0, len(order))
+	for _, col := range order {
+		orderBy = append(orderBy, fmt.Sprintf("%s %s", col, direction))
+	}
+	parts = append(parts, "ORDER BY "+strings.Join(orderBy, ", "))
+
+	ph, err := b.placeHolder(len(args) + 1)
+	if err != nil {
+		return "", nil, err
+	}
+	parts = append(parts, "LIMIT "+ph)
+	args = append(args, props.Limit+1)
+	return strings.Join(parts, " "), args, nil
+}
+
+// keysetCondition matches the rows coming after values in the order of
+// columns. A nullable sort column in front of the key is compared on its own,
+// since NULL never compares and the databases sort it differently.
+func (b *Builder) keysetCondition(columns []string, nullable bool, values []any, desc bool, argsIdx int) (string, []any, error) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	compare := func(columns []string, values []any) (string, []any, error) {
+		phs := make([]string, 0, len(values))
+		for i := range values {
+			ph, err := b.placeHolder(argsIdx + i)
+			if err != nil {
+				return "", nil, err
+			}
+			phs = append(phs, ph)
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, strings.Join(phs, ", ")), values, nil
+	}
+	if !nullable {
+		return compare(columns, values)
+	}
+
+	column, nullsFirst := columns[0], b.nullsFirst(desc)
+	if values[0] == nil {
+		condition, args, err := compare(columns[1:], values[1:])
+		if err != nil {
+			return "", nil, err
+		}
+		condition = fmt.Sprintf("%s IS NULL AND %s", column, condition)
+		if nullsFirst {
+			return fmt.Sprintf("((%s) OR %s IS NOT NULL)", condition, column), args, nil
+		}
+		return fmt.Sprintf("(%s)", condition), args, nil
+	}
+	condition, args, err := compare(columns, values)
+	if err != nil {
+		return "", nil, err
+	}
+	if nullsFirst {
+		return condition, args, nil
+	}
+	return fmt.Sprintf("(%s OR %s IS NULL)", condition, column), args, nil
+}
+
+// nullsFirst reports where the database sorts NULL: Postgres treats it as the
+// largest value, MySQL and SQLite as the smallest.
+func (b *Builder) nullsFirst(desc bool) bool {
+	if b.driver == configs.DriverPostgres {
+		return desc
+	}
+	return !desc
+}
@@ -0,0 +1,173 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+func TestKeysetRows(t *testing.T) {
+	cols := []models.ListDataCol{
+		{ColumnName: "id", IsPrimaryKey: true},
+		{ColumnName: "name"},
+		{ColumnName: "age"},
+		{ColumnName: "email"},
+	}
+	tests := []struct {
+		name    string
+		driver  configs.Driver
+		loc     locator.Locator
+		filter  *models.Filter
+		cursor  models.Cursor
+		want    string
+		args    Arg
+		wantErr error
+	}{
+		{
+			name:   "First page by key",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			want:   "SELECT * FROM users ORDER BY id ASC LIMIT $1",
+			args:   Arg{11},
+		},
+		{
+			name:   "Next page by key",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			cursor: models.Cursor{Values: []any{int64(10)}},
+			want:   "SELECT * FROM users WHERE (id) > ($1) ORDER BY id ASC LIMIT $2",
+			args:   Arg{int64(10), 11},
+		},
+		{
+			name:   "Previous page by key",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			cursor: models.Cursor{Values: []any{int64(10)}, Before: true},
+			want:   "SELECT * FROM users WHERE (id) < ($1) ORDER BY id DESC LIMIT $2",
+			args:   Arg{int64(10), 11},
+		},
+		{
+			name:   "Sort column with key tiebreaker after a filter",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			filter: &models.Filter{Column: "name", Op: models.FilterEq, Value: "bob"},
+			cursor: models.Cursor{Column: "email", Desc: true, Values: []any{"b@x.io", int64(4)}},
+			want:   "SELECT * FROM users WHERE name = $1 AND (email, id) < ($2, $3) ORDER BY email DESC, id DESC LIMIT $4",
+			args:   Arg{"bob", "b@x.io", int64(4), 11},
+		},
+		{
+			name:   "Composite key",
+			driver: configs.DriverMySQL,
+			loc:    compositePK,
+			cursor: models.Cursor{Values: []any{int64(1), int64(2)}},
+			want:   "SELECT * FROM users WHERE (org_id, user_id) > (?, ?) ORDER BY org_id ASC, user_id ASC LIMIT ?",
+			args:   Arg{int64(1), int64(2), 11},
+		},
+		{
+			name:   "SQLite rowid",
+			driver: configs.DriverSQLite,
+			loc:    rowIDLocator,
+			cursor: models.Cursor{Values: []any{int64(7)}},
+			want:   "SELECT rowid, users.* FROM users WHERE (rowid) > ($1) ORDER BY rowid ASC LIMIT $2",
+			args:   Arg{int64(7), 11},
+		},
+		{
+			name:   "Postgres nullable column sorts NULL last",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			cursor: models.Cursor{Column: "age", Values: []any{int64(30), int64(4)}},
+			want:   "SELECT * FROM users WHERE ((age, id) > ($1, $2) OR age IS NULL) ORDER BY age ASC, id ASC LIMIT $3",
+			args:   Arg{int64(30), int64(4), 11},
+		},
+		{
+			name:   "Postgres after a NULL",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			cursor: models.Cursor{Column: "age", Values: []any{nil, int64(4)}},
+			want:   "SELECT * FROM users WHERE (age IS NULL AND (id) > ($1)) ORDER BY age ASC, id ASC LIMIT $2",
+			args:   Arg{int64(4), 11},
+		},
+		{
+			name:   "SQLite nullable column sorts NULL first",
+			driver: configs.DriverSQLite,
+			loc:    pkLocator,
+			cursor: models.Cursor{Column: "age", Values: []any{nil, int64(4)}},
+			want:   "SELECT * FROM users WHERE ((age IS NULL AND (id) > ($1)) OR age IS NOT NULL) ORDER BY age ASC, id ASC LIMIT $2",
+			args:   Arg{int64(4), 11},
+		},
+		{
+			name:   "SQLite descending past the NULLs",
+			driver: configs.DriverSQLite,
+			loc:    pkLocator,
+			cursor: models.Cursor{Column: "age", Desc: true, Values: []any{int64(30), int64(4)}},
+			want:   "SELECT * FROM users WHERE ((age, id) < ($1, $2) OR age IS NULL) ORDER BY age DESC, id DESC LIMIT $3",
+			args:   Arg{int64(30), int64(4), 11},
+		},
+		{
+			name:    "Table without a key",
+			driver:  configs.DriverPostgres,
+			loc:     ctidLocator,
+			wantErr: locator.ErrorInvalidCursor,
+		},
+		{
+			name:    "Unknown sort column",
+			driver:  configs.DriverPostgres,
+			loc:     pkLocator,
+			cursor:  models.Cursor{Column: "nope"},
+			wantErr: apperr.ErrorInvalidColumn,
+		},
+		{
+			name:    "Values not matching the order",
+			driver:  configs.DriverPostgres,
+			loc:     pkLocator,
+			cursor:  models.Cursor{Column: "name", Values: []any{int64(1)}},
+			wantErr: locator.ErrorInvalidCursor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			props := models.ListDataProps{TableName: "users", Limit: 10, Filter: tt.filter}
+			query, args, err := builder.KeysetRows(props, cols, tt.loc, tt.cursor)
+			if tt.wantErr != nil {
+				assertErrIs(t, err, tt.wantErr)
+				return
+			}
+			assertErr(t, err, nil)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
+
+func TestKeysetSortable(t *testing.T) {
+	cols := []models.ListDataCol{
+		{ColumnName: "id", DataType: "INTEGER", IsPrimaryKey: true},
+		{ColumnName: "name", DataType: "TEXT"},
+		{ColumnName: "created_at", DataType: "DATETIME"},
+	}
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		column string
+		loc    locator.Locator
+		want   bool
+	}{
+		{name: "SQLite by key", driver: configs.DriverSQLite, loc: pkLocator, want: true},
+		{name: "SQLite by text", driver: configs.DriverSQLite, column: "name", loc: pkLocator, want: true},
+		{name: "SQLite by datetime", driver: configs.DriverSQLite, column: "created_at", loc: pkLocator, want: false},
+		{name: "SQLite datetime key", driver: configs.DriverSQLite, loc: locator.Locator{Kind: locator.KindPrimaryKey, Columns: []string{"created_at"}}, want: false},
+		{name: "Postgres by datetime", driver: configs.DriverPostgres, column: "created_at", loc: pkLocator, want: true},
+		{name: "Unordered key", driver: configs.DriverPostgres, loc: locator.Locator{Kind: locator.KindCtid, Columns: []string{"ctid"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewBuilder(tt.driver, 100).KeysetSortable(tt.column, cols, tt.loc); got != tt.want {
+				t.Errorf("KeysetSortable() = %v want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+var (
+	pkLocator    = locator.Locator{Kind: locator.KindPrimaryKey, Columns: []string{"id"}}
+	compositePK  = locator.Locator{Kind: locator.KindPrimaryKey, Columns: []string{"org_id", "user_id"}}
+	ctidLocator  = locator.Locator{Kind: locator.KindCtid, Columns: []string{"ctid"}}
+	rowIDLocator = locator.Locator{Kind: locator.KindRowID, Columns: []string{"rowid"}}
+	rowLocator   = locator.Locator{Kind: locator.KindRow, Columns: []string{"name", "email"}}
+)
+
+func TestListRowsWithLocator(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		loc    locator.Locator
+		want   string
+	}{
+		{
+			name:   "primary key selects only table columns",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			want:   "SELECT * FROM users LIMIT $1",
+		},
+		{
+			name:   "postgres ctid",
+			driver: configs.DriverPostgres,
+			loc:    ctidLocator,
+			want:   "SELECT ctid::text, users.* FROM users LIMIT $1",
+		},
+		{
+			name:   "sqlite rowid",
+			driver: configs.DriverSQLite,
+			loc:    rowIDLocator,
+			want:   "SELECT rowid, users.* FROM users LIMIT $1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.ListRows(models.ListDataProps{TableName: "users", Limit: 10}, nil, tt.loc)
+			assertErr(t, err, nil)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, Arg{10})
+		})
+	}
+}
+
+func TestGetRowByKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		loc    locator.Locator
+		key    []any
+		want   string
+		args   Arg
+		err    error
+	}{
+		{
+			name:   "Postgres primary key",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			key:    []any{int64(7)},
+			want:   "SELECT * FROM users WHERE id=$1 LIMIT 1",
+			args:   Arg{int64(7)},
+		},
+		{
+			name:   "MySQL composite primary key",
+			driver: configs.DriverMySQL,
+			loc:    compositePK,
+			key:    []any{int64(1), int64(2)},
+			want:   "SELECT * FROM users WHERE org_id=? AND user_id=? LIMIT 1",
+			args:   Arg{int64(1), int64(2)},
+		},
+		{
+			name:   "Postgres ctid",
+			driver: configs.DriverPostgres,
+			loc:    ctidLocator,
+			key:    []any{"(0,1)"},
+			want:   "SELECT * FROM users WHERE ctid=$1::tid LIMIT 1",
+			args:   Arg{"(0,1)"},
+		},
+		{
+			name:   "Null key value",
+			driver: configs.DriverMySQL,
+			loc:    rowLocator,
+			key:    []any{nil, "a@b.com"},
+			want:   "SELECT * FROM users WHERE name IS NULL AND email=? LIMIT 1",
+			args:   Arg{"a@b.com"},
+		},
+		{
+			name:   "Key size mismatch",
+			driver: configs.DriverPostgres,
+			loc:    compositePK,
+			key:    []any{int64(1)},
+			err:    locator.ErrorInvalidKey,
+		},
+		{
+			name:   "Invalid driver",
+			driver: configs.Driver("oracle"),
+			loc:    pkLocator,
+			key:    []any{int64(1)},
+			err:    apperr.ErrorInvalidDriver,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.GetRowByKey("users", tt.loc, tt.key)
+			assertErr(t, err, tt.err)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
+
+func TestDeleteRowByKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		loc    locator.Locator
+		key    []any
+		want   string
+		args   Arg
+	}{
+		{
+			name:   "SQLite rowid",
+			driver: configs.DriverSQLite,
+			loc:    rowIDLocator,
+			key:    []any{int64(3)},
+			want:   "DELETE FROM users WHERE rowid=$1",
+			args:   Arg{int64(3)},
+		},
+		{
+			name:   "MySQL full row match is limited",
+			driver: configs.DriverMySQL,
+			loc:    rowLocator,
+			key:    []any{"a", "a@b.com"},
+			want:   "DELETE FROM users WHERE name=? AND email=? LIMIT 1",
+			args:   Arg{"a", "a@b.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.DeleteRow("users", tt.loc, tt.key)
+			assertErr(t, err, nil)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
+
+func TestUpdateRowByKey(t *testing.T) {
+	form := []models.RowItem{
+		{ColumnName: "name", Value: "bob"},
+		{ColumnName: "email", Value: "bob@b.com"},
+	}
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		loc    locator.Locator
+		key    []any
+		form   []models.RowItem
+		want   string
+		args   Arg
+		err    error
+	}{
+		{
+			name:   "Postgres primary key",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			key:    []any{int64(1)},
+			form:   form,
+			want:   "UPDATE users SET name=$1,email=$2 WHERE id=$3",
+			args:   Arg{"bob", "bob@b.com", int64(1)},
+		},
+		{
+			name:   "SQLite placeholders continue after values",
+			driver: configs.DriverSQLite,
+			loc:    rowIDLocator,
+			key:    []any{int64(9)},
+			form:   form,
+			want:   "UPDATE users SET name=$1,email=$2 WHERE rowid=$3",
+			args:   Arg{"bob", "bob@b.com", int64(9)},
+		},
+		{
+			name:   "MySQL full row match is limited",
+			driver: configs.DriverMySQL,
+			loc:    rowLocator,
+			key:    []any{"a", "a@b.com"},
+			form:   form[:1],
+			want:   "UPDATE users SET name=? WHERE name=? AND email=? LIMIT 1",
+			args:   Arg{"bob", "a", "a@b.com"},
+		},
+		{
+			name:   "No values",
+			driver: configs.DriverPostgres,
+			loc:    pkLocator,
+			key:    []any{int64(1)},
+			err:    apperr.ErrorNoValuesProvided,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.UpdateRow("users", tt.form, tt.loc, tt.key)
+			assertErr(t, err, tt.err)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
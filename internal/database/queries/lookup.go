@@ -0,0 +1,102 @@
+package queries
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+// lookupFilter matches the search text against the value and the label column.
+func lookupFilter(props models.LookupProps) *models.Filter {
+	search := strings.TrimSpace(props.Search)
+	if search == "" {
+		return nil
+	}
+	pattern := "%" + search + "%"
+	filter := &models.Filter{Or: []models.Filter{
+		{Column: props.Column, Op: models.FilterILike, Value: pattern},
+	}}
+	if props.LabelColumn != props.Column {
+		filter.Or = append(filter.Or, models.Filter{Column: props.LabelColumn, Op: models.FilterILike, Value: pattern})
+	}
+	return filter
+}
+
+func (b *Builder) validateLookup(props models.LookupProps, cols []models.ListDataCol) error {
+	if props.TableName == "" {
+		return apperr.ErrorEmptyTableName
+	}
+	for _, name := range []string{props.Column, props.LabelColumn} {
+		if !slices.ContainsFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == name }) {
+			return fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, name)
+		}
+	}
+	return nil
+}
+
+// Lookup selects value and label pairs of the referenced table ordered by label.
+func (b *Builder) Lookup(props models.LookupProps, cols []models.ListDataCol) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
+	}
+	if err := b.validateLookup(props, cols); err != nil {
+		return "", nil, err
+	}
+	if props.Limit <= 0 || props.Offset < 0 {
+		return "", nil, apperr.ErrorInvalidPagination
+	}
+	if props.Limit > b.maxLimit {
+		return "", nil, apperr.ErrorLimitTooLarge(b.maxLimit)
+	}
+	tableName, err := b.getQuotedTableName(props.TableName)
+	if err != nil {
+		return "", nil, err
+	}
+	value, err := b.quoteIdentifier(props.Column)
+	if err != nil {
+		return "", nil, err
+	}
+	label, err := b.quoteIdentifier(props.LabelColumn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	parts := []string{fmt.Sprintf("SELECT %s, %s FROM %s", value, label, tableName)}
+	where, args, err := b.Where(lookupFilter(props), cols, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		parts = append(parts, "WHERE "+where)
+	}
+	if label == value {
+		parts = append(parts, fmt.Sprintf("ORDER BY %s", value))
+	} else {
+		parts = append(parts, fmt.Sprintf("ORDER BY %s, %s", label, value))
+	}
+	limitPh, err := b.placeHolder(len(args) + 1)
+	if err != nil {
+		return "", nil, err
+	}
+	offsetPh, err := b.placeHolder(len(args) + 2)
+	if err != nil {
+		return "", nil, err
+	}
+	parts = append(parts, fmt.Sprintf("LIMIT %s OFFSET %s", limitPh, offsetPh))
+	args = append(args, props.Limit, props.Offset)
+	return strings.Join(parts, " "), args, nil
+}
+
+// CountLookup counts the rows Lookup pages through.
+func (b *Builder) CountLookup(props models.LookupProps, cols []models.ListDataCol) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
+	}
+	if err := b.validateLookup(props, cols); err != nil {
+		return "", nil, err
+	}
+	return b.CountRows(props.TableName, lookupFilter(props), cols)
+}
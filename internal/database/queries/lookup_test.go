@@ -0,0 +1,81 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		props  models.LookupProps
+		want   string
+		args   []any
+		err    error
+	}{
+		{
+			name:   "Postgres without search",
+			driver: configs.DriverPostgres,
+			props:  models.LookupProps{TableName: "users", Column: "id", LabelColumn: "name", Limit: 10},
+			want:   "SELECT id, name FROM users ORDER BY name, id LIMIT $1 OFFSET $2",
+			args:   []any{10, 0},
+		},
+		{
+			name:   "Postgres with search",
+			driver: configs.DriverPostgres,
+			props:  models.LookupProps{TableName: "users", Column: "id", LabelColumn: "name", Search: " jo ", Limit: 10, Offset: 20},
+			want:   "SELECT id, name FROM users WHERE (id::text ILIKE $1 OR name::text ILIKE $2) ORDER BY name, id LIMIT $3 OFFSET $4",
+			args:   []any{"%jo%", "%jo%", 10, 20},
+		},
+		{
+			name:   "MySQL label is the value",
+			driver: configs.DriverMySQL,
+			props:  models.LookupProps{TableName: "users", Column: "id", LabelColumn: "id", Search: "4", Limit: 5},
+			want:   "SELECT id, id FROM users WHERE LOWER(id) LIKE LOWER(?) ORDER BY id LIMIT ? OFFSET ?",
+			args:   []any{"%4%", 5, 0},
+		},
+		{
+			name:   "unknown label column",
+			driver: configs.DriverSQLite,
+			props:  models.LookupProps{TableName: "users", Column: "id", LabelColumn: "nope", Limit: 5},
+			err:    apperr.ErrorInvalidColumn,
+		},
+		{
+			name:   "no limit",
+			driver: configs.DriverSQLite,
+			props:  models.LookupProps{TableName: "users", Column: "id", LabelColumn: "name"},
+			err:    apperr.ErrorInvalidPagination,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.Lookup(tt.props, alterCols)
+			assertErrIs(t, err, tt.err)
+			assertQuery(t, query, tt.want)
+			if tt.err == nil {
+				assertArgs(t, args, tt.args)
+			}
+		})
+	}
+
+	t.Run("limit too large", func(t *testing.T) {
+		builder := NewBuilder(configs.DriverSQLite, 10)
+		_, _, err := builder.Lookup(models.LookupProps{TableName: "users", Column: "id", LabelColumn: "name", Limit: 50}, alterCols)
+		assertErr(t, err, apperr.ErrorLimitTooLarge(10))
+	})
+}
+
+func TestCountLookup(t *testing.T) {
+	builder := NewBuilder(configs.DriverSQLite, 10)
+	query, args, err := builder.CountLookup(models.LookupProps{
+		TableName: "users", Column: "id", LabelColumn: "name", Search: "jo",
+	}, alterCols)
+	assertErr(t, err, nil)
+	assertQuery(t, query, "SELECT COUNT(*) FROM users WHERE (LOWER(id) LIKE LOWER($1) OR LOWER(name) LIKE LOWER($2))")
+	assertArgs(t, args, []any{"%jo%", "%jo%"})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/biisal/rowsql/configs"
 	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
 	"github.com/biisal/rowsql/internal/database/models"
 )
 
@@ -96,21 +97,21 @@ func TestColumnsList(t *testing.T) {
 			name:   "Postgress",
 			driver: configs.DriverPostgres,
 			want:   postgresColumnsListsQuery,
-			args:   []any{"users"},
+			args:   []any{"users", "public", "public"},
 			err:    nil,
 		},
 		{
 			name:   "MySQL",
 			driver: configs.DriverMySQL,
 			want:   mysqlColumnsListsQuery,
-			args:   []any{"users"},
+			args:   []any{"users", "users"},
 			err:    nil,
 		},
 		{
 			name:   "SQLite",
 			driver: configs.DriverSQLite,
 			want:   sqliteColumnsListQuery,
-			args:   []any{"users", "users"},
+			args:   []any{"users", "users", "users", "users", "users"},
 			err:    nil,
 		},
 		{
@@ -388,7 +389,13 @@ func TestListRows(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			builder := NewBuilder(tt.driver, tt.maxLimit)
-			query, args, err := builder.ListRows(tt.tableName, tt.orderCol, tt.orderBy, tt.limit, tt.offset)
+			query, args, err := builder.ListRows(models.ListDataProps{
+				TableName: tt.tableName,
+				Column:    tt.orderCol,
+				Order:     tt.orderBy,
+				Limit:     tt.limit,
+				Offset:    tt.offset,
+			}, nil, locator.Locator{})
 			assertErr(t, err, tt.err)
 			assertQuery(t, query, tt.want)
 			assertArgs(t, args, tt.arg)
@@ -680,296 +687,41 @@ func TestInsertRow(t *testing.T) {
 	}
 }
 
-func TestGetRows(t *testing.T) {
-	tests := []struct {
-		name      string
-		driver    configs.Driver
-		tableName string
-		limit     int
-		offset    int
-		want      string
-		arg       Arg
-		err       error
-	}{
-		{
-			name:      "Psql",
-			driver:    configs.DriverPostgres,
-			tableName: "users",
-			limit:     10,
-			offset:    10,
-			want:      "SELECT * FROM users LIMIT $1 OFFSET $2",
-			arg:       Arg{10, 10},
-		},
-		{
-			name:      "Mysql",
-			driver:    configs.DriverMySQL,
-			tableName: "users",
-			limit:     10,
-			offset:    10,
-			want:      "SELECT * FROM users LIMIT ? OFFSET ?",
-			arg:       Arg{10, 10},
-		},
-		{
-			name:      "Sqlite",
-			driver:    configs.DriverSQLite,
-			tableName: "users",
-			limit:     10,
-			offset:    10,
-			want:      "SELECT * FROM users LIMIT $1 OFFSET $2",
-			arg:       Arg{10, 10},
-		},
-		{
-			name:      "zero offset",
-			driver:    configs.DriverSQLite,
-			tableName: "users",
-			limit:     10,
-			offset:    0,
-			want:      "SELECT * FROM users LIMIT $1",
-			arg:       Arg{10},
-		},
-		{
-			name:      "zero limit",
-			driver:    configs.DriverSQLite,
-			tableName: "users",
-			limit:     0,
-			offset:    10,
-			err:       apperr.ErrorInvalidPagination,
-		},
-		{
-			name:      "Nagative offset",
-			driver:    configs.DriverSQLite,
-			tableName: "users",
-			limit:     0,
-			offset:    -1,
-			err:       apperr.ErrorInvalidPagination,
-		},
-		{
-			name:   "Unknown driver",
-			driver: configs.Driver("unknown"),
-			err:    apperr.ErrorInvalidDriver,
-		},
-		{
-			name:      "whitespace table name",
-			driver:    configs.DriverMySQL,
-			tableName: "   ",
-			limit:     10,
-			offset:    0,
-			err:       apperr.ErrorInvalidTableName,
-		},
-		{
-			name:      "driver validation happens before pagination",
-			driver:    configs.Driver("oracle"),
-			tableName: "users",
-			limit:     -1,
-			offset:    -1,
-			err:       apperr.ErrorInvalidDriver,
-		},
+func assertErrIs(t testing.TB, got, want error) {
+	t.Helper()
+	if want == nil {
+		if got != nil {
+			t.Fatalf("got unexpected error %q", got)
+		}
+		return
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			builder := NewBuilder(tt.driver, 10)
-			query, args, err := builder.GetRows(tt.tableName, tt.limit, tt.offset)
-			assertErr(t, err, tt.err)
-			assertQuery(t, query, tt.want)
-			assertArgs(t, args, tt.arg)
-		})
+	if !errors.Is(got, want) {
+		t.Errorf("got %v , want %v", got, want)
 	}
 }
 
-func TestWhereClause(t *testing.T) {
+func TestReferencesList(t *testing.T) {
 	tests := []struct {
-		name    string
-		driver  configs.Driver
-		cols    []models.ListDataCol
-		rows    []any
-		argsIdx int
-		want    string
-		args    []any
-		err     error
+		name   string
+		driver configs.Driver
+		table  string
+		want   string
+		args   Arg
+		err    error
 	}{
-		{
-			name:   "Postgress",
-			driver: configs.DriverPostgres,
-			cols: []models.ListDataCol{
-				{
-					ColumnName:       "id",
-					DataType:         "int",
-					IsUnique:         false,
-					Value:            1,
-					InputType:        "text",
-					HasAutoIncrement: false,
-				},
-			},
-			rows:    []any{1},
-			want:    "id=$1",
-			argsIdx: 1,
-			args:    []any{1},
-		},
-		{
-			name:   "MySQL",
-			driver: configs.DriverMySQL,
-			cols: []models.ListDataCol{
-				{
-					ColumnName:       "id",
-					DataType:         "int",
-					IsUnique:         false,
-					Value:            1,
-					InputType:        "text",
-					HasAutoIncrement: false,
-				},
-			},
-			rows:    []any{1},
-			want:    "id=?",
-			argsIdx: 1,
-			args:    []any{1},
-		},
-		{
-			name:   "SQLite",
-			driver: configs.DriverSQLite,
-			cols: []models.ListDataCol{
-				{
-					ColumnName:       "id",
-					DataType:         "int",
-					IsUnique:         false,
-					Value:            1,
-					InputType:        "text",
-					HasAutoIncrement: false,
-				},
-			},
-			rows:    []any{1},
-			want:    "id=$1",
-			argsIdx: 1,
-			args:    []any{1},
-		},
-		{
-			name:   "Sqlite zero index",
-			driver: configs.DriverSQLite,
-			cols: []models.ListDataCol{
-				{
-					ColumnName: "id",
-					Value:      1,
-				},
-			},
-			rows:    []any{1},
-			argsIdx: 0,
-			// err: apperr.Err,
-			err: apperr.ErrorInvalidPlaceHolderIndex,
-		},
-		{
-			name:   "Sqlite multiple rows and cols",
-			driver: configs.DriverSQLite,
-			cols: []models.ListDataCol{
-				{
-					ColumnName: "id",
-					Value:      1,
-				},
-				{
-					ColumnName: "name",
-					Value:      "test",
-				},
-				{
-					ColumnName: "email",
-					Value:      "test",
-				},
-			},
-
-			rows:    []any{1, "test", "test"},
-			argsIdx: 1,
-			want:    "id=$1 AND name=$2 AND email=$3",
-			args:    []any{1, "test", "test"},
-		},
-		{
-			name:   "Mysql multiple rows and cols",
-			driver: configs.DriverMySQL,
-			cols: []models.ListDataCol{
-				{
-					ColumnName: "id",
-					Value:      1,
-				},
-				{
-					ColumnName: "name",
-					Value:      "test",
-				},
-				{
-					ColumnName: "email",
-					Value:      "test",
-				},
-			},
-
-			rows:    []any{1, "test", "test"},
-			argsIdx: 1,
-			want:    "id=? AND name=? AND email=?",
-			args:    []any{1, "test", "test"},
-		},
-		{
-			name:   "Sqlite less cols then rows",
-			driver: configs.DriverSQLite,
-			cols: []models.ListDataCol{
-				{
-					ColumnName: "id",
-					Value:      1,
-				},
-				{
-					ColumnName: "name",
-					Value:      "test",
-				},
-			},
-			rows:    []any{1, "test", "test"},
-			argsIdx: 1,
-			err:     apperr.ErrorNotSameRowColsSize,
-		},
-		{
-			name:   "Sqlite unique cols",
-			driver: configs.DriverSQLite,
-			cols: []models.ListDataCol{
-				{
-					ColumnName: "id",
-					Value:      1,
-					IsUnique:   true,
-				},
-				{
-					ColumnName: "name",
-					Value:      "test",
-				},
-			},
-			rows:    []any{1, "test"},
-			args:    []any{1},
-			argsIdx: 1,
-			want:    "id=$1",
-		},
-		{
-			name:   "Sqlite multiple unique cols",
-			driver: configs.DriverSQLite,
-			cols: []models.ListDataCol{
-				{
-					ColumnName: "id",
-					Value:      1,
-					IsUnique:   true,
-				},
-				{
-					ColumnName: "name",
-					Value:      "test",
-				},
-				{
-					ColumnName: "email",
-					Value:      "test",
-					IsUnique:   true,
-				},
-			},
-			rows:    []any{1, "test", "test"},
-			args:    []any{1},
-			argsIdx: 1,
-			want:    "id=$1",
-		},
+		{name: "Postgres", driver: configs.DriverPostgres, table: "users", want: postgresReferencesListQuery, args: Arg{"users", "public", "public"}},
+		{name: "Postgres schema", driver: configs.DriverPostgres, table: "sales.users", want: postgresReferencesListQuery, args: Arg{"users", "sales", "public"}},
+		{name: "MySQL", driver: configs.DriverMySQL, table: "users", want: mysqlReferencesListQuery, args: Arg{"users"}},
+		{name: "SQLite", driver: configs.DriverSQLite, table: "users", want: sqliteReferencesListQuery, args: Arg{"users"}},
+		{name: "Empty driver", driver: "", table: "users", err: ErrUnknownDriver},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			builder := NewBuilder(tt.driver, 10)
-			query, args, err := builder.WhereCluse(tt.cols, tt.rows, tt.argsIdx)
-			assertArgs(t, args, tt.args)
-			assertErr(t, err, tt.err)
+			query, args, err := builder.ReferencesList(tt.table)
+			assertErrIs(t, err, tt.err)
 			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
 		})
 	}
 }
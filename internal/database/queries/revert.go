@@ -0,0 +1,67 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/locator"
+)
+
+// UpdateValues sets columns to values on the row at key. Unlike UpdateRow the
+// values are passed as they are, so NULL can be written back.
+func (b *Builder) UpdateValues(tableName string, columns []string, values []any, loc locator.Locator, key []any) (string, []any, error) {
+	if err := b.checkValidDriver(); err != nil {
+		return "", nil, err
+	}
+	if len(columns) == 0 {
+		return "", nil, apperr.ErrorNoValuesProvided
+	}
+	if len(columns) != len(values) {
+		return "", nil, apperr.ErrorNotSameRowColsSize
+	}
+	table, err := b.getQuotedTableName(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	parts := make([]string, 0, len(columns))
+	for i, name := range columns {
+		col, err := b.quoteIdentifier(name)
+		if err != nil {
+			return "", nil, err
+		}
+		ph, err := b.placeHolder(i + 1)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", col, ph))
+	}
+	clause, keyArgs, err := b.KeyWhere(loc, key, len(values)+1)
+	if err != nil {
+		return "", nil, err
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(parts, ","), clause)
+	return query, append(append([]any{}, values...), keyArgs...), nil
+}
+
+// RestoreRow inserts a deleted row again with the values it had, generated
+// columns must be left out. With identity the values of GENERATED ALWAYS
+// identity columns are kept.
+func (b *Builder) RestoreRow(tableName string, columns []string, values []any, identity bool) (string, []any, error) {
+	return b.insertRows(tableName, columns, [][]any{values}, identity)
+}
+
+// LockRowByKey reads the row at key and locks it until the transaction ends.
+// SQLite has no row locks, its transactions already lock the whole database
+// on the first write.
+func (b *Builder) LockRowByKey(tableName string, loc locator.Locator, key []any) (string, []any, error) {
+	query, args, err := b.GetRowByKey(tableName, loc, key)
+	if err != nil {
+		return "", nil, err
+	}
+	if b.driver != configs.DriverSQLite {
+		query += " FOR UPDATE"
+	}
+	return query, args, nil
+}
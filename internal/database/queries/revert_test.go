@@ -0,0 +1,118 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/apperr"
+)
+
+func TestUpdateValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  configs.Driver
+		columns []string
+		values  []any
+		key     []any
+		want    string
+		args    Arg
+		err     error
+	}{
+		{
+			name:    "Postgres writes NULL back",
+			driver:  configs.DriverPostgres,
+			columns: []string{"name", "email"},
+			values:  []any{"ann", nil},
+			key:     []any{int64(1)},
+			want:    "UPDATE users SET name=$1,email=$2 WHERE id=$3",
+			args:    Arg{"ann", nil, int64(1)},
+		},
+		{
+			name:    "MySQL",
+			driver:  configs.DriverMySQL,
+			columns: []string{"name"},
+			values:  []any{"ann"},
+			key:     []any{int64(1)},
+			want:    "UPDATE users SET name=? WHERE id=?",
+			args:    Arg{"ann", int64(1)},
+		},
+		{
+			name:    "Column with space is quoted",
+			driver:  configs.DriverSQLite,
+			columns: []string{"full name"},
+			values:  []any{"ann"},
+			key:     []any{int64(1)},
+			want:    `UPDATE users SET "full name"=$1 WHERE id=$2`,
+			args:    Arg{"ann", int64(1)},
+		},
+		{
+			name:   "No columns",
+			driver: configs.DriverPostgres,
+			key:    []any{int64(1)},
+			err:    apperr.ErrorNoValuesProvided,
+		},
+		{
+			name:    "Values don't match columns",
+			driver:  configs.DriverPostgres,
+			columns: []string{"name", "email"},
+			values:  []any{"ann"},
+			key:     []any{int64(1)},
+			err:     apperr.ErrorNotSameRowColsSize,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.UpdateValues("users", tt.columns, tt.values, pkLocator, tt.key)
+			assertErrIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, tt.args)
+		})
+	}
+}
+
+func TestLockRowByKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver configs.Driver
+		want   string
+	}{
+		{"Postgres", configs.DriverPostgres, "SELECT * FROM users WHERE id=$1 LIMIT 1 FOR UPDATE"},
+		{"MySQL", configs.DriverMySQL, "SELECT * FROM users WHERE id=? LIMIT 1 FOR UPDATE"},
+		{"SQLite", configs.DriverSQLite, "SELECT * FROM users WHERE id=$1 LIMIT 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.LockRowByKey("users", pkLocator, []any{int64(1)})
+			assertErr(t, err, nil)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, Arg{int64(1)})
+		})
+	}
+}
+
+func TestRestoreRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   configs.Driver
+		identity bool
+		want     string
+	}{
+		{"Postgres", configs.DriverPostgres, false, "INSERT INTO users (id, name) VALUES ($1, $2)"},
+		{"Postgres identity", configs.DriverPostgres, true, "INSERT INTO users (id, name) OVERRIDING SYSTEM VALUE VALUES ($1, $2)"},
+		{"MySQL has no identity columns", configs.DriverMySQL, true, "INSERT INTO users (id, name) VALUES (?, ?)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			query, args, err := builder.RestoreRow("users", []string{"id", "name"}, []any{int64(1), "ann"}, tt.identity)
+			assertErr(t, err, nil)
+			assertQuery(t, query, tt.want)
+			assertArgs(t, args, Arg{int64(1), "ann"})
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package queries
+
+import (
+	"testing"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+func TestSplitTableName(t *testing.T) {
+	tests := []struct {
+		name       string
+		driver     configs.Driver
+		table      string
+		wantSchema string
+		wantTable  string
+	}{
+		{name: "Postgres default schema", driver: configs.DriverPostgres, table: "users", wantSchema: "public", wantTable: "users"},
+		{name: "Postgres schema", driver: configs.DriverPostgres, table: "sales.orders", wantSchema: "sales", wantTable: "orders"},
+		{name: "MySQL has no schemas", driver: configs.DriverMySQL, table: "sales.orders", wantTable: "sales.orders"},
+		{name: "SQLite has no schemas", driver: configs.DriverSQLite, table: "users", wantTable: "users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.driver, 10)
+			schema, table := builder.SplitTableName(tt.table)
+			if schema != tt.wantSchema || table != tt.wantTable {
+				t.Errorf("got %q, %q want %q, %q", schema, table, tt.wantSchema, tt.wantTable)
+			}
+			if got := builder.QualifiedTableName(schema, table); got != tt.table {
+				t.Errorf("got %q want %q", got, tt.table)
+			}
+		})
+	}
+}
+
+func TestSchemaQualifiedQueries(t *testing.T) {
+	builder := NewBuilder(configs.DriverPostgres, 10)
+
+	t.Run("table exists", func(t *testing.T) {
+		query, args, err := builder.CheckTableExitsQuery("sales.orders")
+		assertErr(t, err, nil)
+		assertQuery(t, query, "SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2")
+		assertArgs(t, args, Arg{"sales", "orders"})
+	})
+
+	t.Run("columns", func(t *testing.T) {
+		_, args, err := builder.ColumnsList("sales.orders")
+		assertErr(t, err, nil)
+		assertArgs(t, args, Arg{"orders", "sales", "public"})
+	})
+
+	t.Run("indexes", func(t *testing.T) {
+		_, args, err := builder.ListIndexes("sales.orders")
+		assertErr(t, err, nil)
+		assertArgs(t, args, Arg{"orders", "sales"})
+	})
+
+	t.Run("rows", func(t *testing.T) {
+		query, args, err := builder.GetRowByKey("sales.orders", pkLocator, []any{1})
+		assertErr(t, err, nil)
+		assertQuery(t, query, "SELECT * FROM sales.orders WHERE id=$1 LIMIT 1")
+		assertArgs(t, args, Arg{1})
+	})
+
+	t.Run("quoted parts", func(t *testing.T) {
+		query, _, err := builder.DeleteRow("my sales.order items", pkLocator, []any{1})
+		assertErr(t, err, nil)
+		assertQuery(t, query, `DELETE FROM "my sales"."order items" WHERE id=$1`)
+	})
+
+	t.Run("ctid rows", func(t *testing.T) {
+		query, _, err := builder.ListRows(models.ListDataProps{TableName: "sales.orders", Limit: 10}, nil, ctidLocator)
+		assertErr(t, err, nil)
+		assertQuery(t, query, "SELECT ctid::text, sales.orders.* FROM sales.orders LIMIT $1")
+	})
+
+	t.Run("create table", func(t *testing.T) {
+		_, err := builder.CreateTable("sales.orders", nil)
+		assertErr(t, err, nil)
+		_, err = builder.CreateTable("sales.orders;drop", nil)
+		if err == nil {
+			t.Error("expected an invalid table name error")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package queries
+
+import "strings"
+
+// Token is a keyword, an identifier or a ";" separating SQL statements. Text
+// is lower-cased unless the identifier is Quoted, Depth counts the parentheses
+// around it.
+type Token struct {
+	Text   string
+	Quoted bool
+	Depth  int
+}
+
+// Tokenize returns the keywords, identifiers and statement separators of
+// query, skipping comments, string literals and everything else.
+func Tokenize(query string) []Token {
+	var tokens []Token
+	depth := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			i = skipPast(query, i+2, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipPast(query, i+2, "*/")
+		case c == '\'':
+			i = skipQuoted(query, i+1, '\'')
+		case c == '"' || c == '`' || c == '[':
+			quote := c
+			if c == '[' {
+				quote = ']'
+			}
+			end := skipQuoted(query, i+1, quote)
+			text := query[i+1 : max(i+1, end-1)]
+			text = strings.ReplaceAll(text, string([]byte{quote, quote}), string(quote))
+			tokens = append(tokens, Token{Text: text, Quoted: true, Depth: depth})
+			i = end
+		case c == '$':
+			tag, ok := dollarTag(query[i:])
+			if !ok {
+				i++
+				continue
+			}
+			i = skipPast(query, i+len(tag), tag)
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case c == ';':
+			tokens = append(tokens, Token{Text: ";", Depth: depth})
+			i++
+		case isWordStart(c):
+			j := i + 1
+			for j < len(query) && (isWordStart(query[j]) || query[j] >= '0' && query[j] <= '9' || query[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, Token{Text: strings.ToLower(query[i:j]), Depth: depth})
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// skipPast returns the index after the next end at or after i.
+func skipPast(query string, i int, end string) int {
+	if idx := strings.Index(query[i:], end); idx >= 0 {
+		return i + idx + len(end)
+	}
+	return len(query)
+}
+
+// skipQuoted returns the index after the quote closing the literal that starts
+// at i, a doubled quote is part of the literal.
+func skipQuoted(query string, i int, quote byte) int {
+	for i < len(query) {
+		if query[i] != quote {
+			i++
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i += 2
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+// dollarTag returns the opening tag of a Postgres dollar-quoted string, like
+// $$ or $body$, at the start of s.
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '$' {
+			return s[:i+1], true
+		}
+		if !isWordStart(c) && (i == 1 || c < '0' || c > '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
@@ -17,7 +17,44 @@ func (b *Builder) checkValidDriver() error {
 	return nil
 }
 
+// DefaultSchema is the Postgres schema of tables named without one.
+const DefaultSchema = "public"
+
+// SplitTableName splits a Postgres table name qualified as "schema.table".
+// The other drivers have no schemas and get the name back unchanged.
+func (b *Builder) SplitTableName(tableName string) (schema, table string) {
+	if b.driver != configs.DriverPostgres {
+		return "", tableName
+	}
+	if schema, table, ok := strings.Cut(tableName, "."); ok {
+		return schema, table
+	}
+	return DefaultSchema, tableName
+}
+
+// QualifiedTableName is the name a table is addressed by, tables of the
+// default schema keep their plain name.
+func (b *Builder) QualifiedTableName(schema, table string) string {
+	if b.driver != configs.DriverPostgres || schema == "" || schema == DefaultSchema {
+		return table
+	}
+	return schema + "." + table
+}
+
 func (b *Builder) getQuotedTableName(tableName string) (string, error) {
+	schema, table := b.SplitTableName(tableName)
+	table, err := b.quoteIdentifier(table)
+	if err != nil || schema == "" || schema == DefaultSchema {
+		return table, err
+	}
+	schema, err = b.quoteIdentifier(schema)
+	if err != nil {
+		return "", err
+	}
+	return schema + "." + table, nil
+}
+
+func (b *Builder) quoteIdentifier(tableName string) (string, error) {
 	if !strings.Contains(tableName, " ") {
 		switch b.driver {
 		case configs.DriverPostgres, configs.DriverMySQL, configs.DriverSQLite:
@@ -0,0 +1,337 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/queries"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrorForeignKeyCheck = errors.New("table rebuild would break foreign key constraints")
+
+// AlterTable applies ops to a table one after another and records every op in
+// history, it stops at the first failing op. On Postgres and for SQLite rebuilds each op runs in
+// its own transaction; MySQL commits DDL implicitly.
+func (q *Queries) AlterTable(ctx context.Context, tableName string, ops []database.AlterOp) error {
+	if len(ops) == 0 {
+		return errors.New("no alter operations provided")
+	}
+	for _, op := range ops {
+		cols, err := q.ListCols(ctx, tableName)
+		if err != nil {
+			return err
+		}
+		entry := &models.History{
+			Operation: models.OperationAlterTable,
+			TableName: tableName,
+			Message:   fmt.Sprintf("Altered table '%s': %s", tableName, describeAlter(op)),
+		}
+		err = q.audit(ctx, entry, func() error {
+			var statements []string
+			var err error
+			if q.queryBuilder.NeedsRebuild(op) {
+				statements, err = q.rebuildSQLiteTable(ctx, tableName, op, cols)
+			} else {
+				statements, err = q.alterTable(ctx, tableName, op, cols)
+			}
+			entry.Query = strings.Join(statements, ";\n")
+			return err
+		})
+		// also after a failure, a rolled back transaction may have read the altered table
+		q.schema.clear()
+		q.cache.DeleteTable(tableName)
+		q.counts.invalidate(tableName)
+		if err != nil {
+			return err
+		}
+		if op.Action == database.AlterRenameTable {
+			// the table stays in its schema
+			schema, _ := q.queryBuilder.SplitTableName(tableName)
+			tableName = q.queryBuilder.QualifiedTableName(schema, op.NewName)
+		}
+	}
+	return nil
+}
+
+func describeAlter(op database.AlterOp) string {
+	switch op.Action {
+	case database.AlterAddColumn:
+		return fmt.Sprintf("added column '%s'", op.Input.ColName)
+	case database.AlterDropColumn:
+		return fmt.Sprintf("dropped column '%s'", op.Column)
+	case database.AlterRenameColumn:
+		return fmt.Sprintf("renamed column '%s' to '%s'", op.Column, op.NewName)
+	case database.AlterModifyColumn:
+		return fmt.Sprintf("modified column '%s'", op.Column)
+	case database.AlterRenameTable:
+		return fmt.Sprintf("renamed table to '%s'", op.NewName)
+	}
+	return string(op.Action)
+}
+
+// alterTable returns the statements it ran or tried to run.
+func (q *Queries) alterTable(ctx context.Context, tableName string, op database.AlterOp, cols []models.ListDataCol) ([]string, error) {
+	var statements []string
+	var err error
+	if q.driver == configs.DriverMySQL && op.Action == database.AlterModifyColumn {
+		statements, err = q.mysqlModifyColumn(ctx, tableName, op, cols)
+	} else {
+		statements, err = q.queryBuilder.AlterTable(tableName, op, cols)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if q.driver != configs.DriverPostgres {
+		return statements, execStatements(ctx, q.db, statements)
+	}
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return statements, execStatements(ctx, q.db, statements)
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorln(err)
+		return statements, err
+	}
+	if err := execStatements(ctx, tx, statements); err != nil {
+		rollback(tx)
+		return statements, err
+	}
+	return statements, tx.Commit()
+}
+
+func (q *Queries) mysqlModifyColumn(ctx context.Context, tableName string, op database.AlterOp, cols []models.ListDataCol) ([]string, error) {
+	query, args, err := q.queryBuilder.MySQLColumn(tableName, op.Column)
+	if err != nil {
+		return nil, err
+	}
+	var current models.TableColumn
+	var dflt sql.NullString
+	if err := q.db.QueryRowxContext(ctx, query, args...).Scan(&current.Name, &current.Type, &current.NotNull, &dflt, &current.Extra, &current.Comment); err != nil {
+		logger.Errorln(err)
+		return nil, err
+	}
+	if dflt.Valid {
+		current.Default = &dflt.String
+	}
+	return q.queryBuilder.MySQLModifyColumn(tableName, op, cols, current)
+}
+
+func execStatements(ctx context.Context, db sqlx.ExecerContext, statements []string) error {
+	for _, statement := range statements {
+		logger.Info("ALTER Query: %s", statement)
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			logger.Errorln(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func rollback(tx *sqlx.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logger.Errorln(err)
+	}
+}
+
+// SQLiteTableDefinition reads everything needed to recreate a SQLite table.
+func (q *Queries) SQLiteTableDefinition(ctx context.Context, tableName string) (models.TableDefinition, error) {
+	def := models.TableDefinition{Name: tableName}
+	columnsQuery, fkQuery, uniquesQuery, extrasQuery, err := q.queryBuilder.SQLiteTableDefinition()
+	if err != nil {
+		return def, err
+	}
+
+	rows, err := q.db.QueryxContext(ctx, extrasQuery, tableName)
+	if err != nil {
+		logger.Errorln(err)
+		return def, err
+	}
+	for rows.Next() {
+		var kind, statement string
+		if err := rows.Scan(&kind, &statement); err != nil {
+			logger.Errorln(err)
+			closeRows(rows)
+			return def, err
+		}
+		if kind == "table" {
+			if def.AutoIncrement, def.Options, err = queries.SQLiteTableOptions(statement); err != nil {
+				closeRows(rows)
+				return def, err
+			}
+			continue
+		}
+		def.Extras = append(def.Extras, statement)
+	}
+	closeRows(rows)
+	if err := rows.Err(); err != nil {
+		return def, err
+	}
+
+	unique := make(map[string]bool)
+	rows, err = q.db.QueryxContext(ctx, uniquesQuery, tableName)
+	if err != nil {
+		logger.Errorln(err)
+		return def, err
+	}
+	var constraints []string
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var name, column string
+		if err := rows.Scan(&name, &column); err != nil {
+			logger.Errorln(err)
+			closeRows(rows)
+			return def, err
+		}
+		if _, ok := columns[name]; !ok {
+			constraints = append(constraints, name)
+		}
+		columns[name] = append(columns[name], column)
+	}
+	closeRows(rows)
+	if err := rows.Err(); err != nil {
+		return def, err
+	}
+	for _, name := range constraints {
+		if len(columns[name]) == 1 {
+			unique[columns[name][0]] = true
+		} else {
+			def.Uniques = append(def.Uniques, columns[name])
+		}
+	}
+
+	rows, err = q.db.QueryxContext(ctx, columnsQuery, tableName)
+	if err != nil {
+		logger.Errorln(err)
+		return def, err
+	}
+	for rows.Next() {
+		var c models.TableColumn
+		var dflt sql.NullString
+		if err := rows.Scan(&c.Name, &c.Type, &c.NotNull, &dflt, &c.PK); err != nil {
+			logger.Errorln(err)
+			closeRows(rows)
+			return def, err
+		}
+		if dflt.Valid {
+			c.Default = &dflt.String
+		}
+		c.Unique = unique[c.Name]
+		def.Columns = append(def.Columns, c)
+	}
+	closeRows(rows)
+	if err := rows.Err(); err != nil {
+		return def, err
+	}
+
+	rows, err = q.db.QueryxContext(ctx, fkQuery, tableName)
+	if err != nil {
+		logger.Errorln(err)
+		return def, err
+	}
+	defer closeRows(rows)
+	lastID := -1
+	for rows.Next() {
+		var id int
+		var column, refTable, onUpdate, onDelete string
+		var refColumn sql.NullString
+		if err := rows.Scan(&id, &column, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			logger.Errorln(err)
+			return def, err
+		}
+		if id != lastID {
+			def.ForeignKeys = append(def.ForeignKeys, models.ForeignKey{RefTable: refTable, OnUpdate: onUpdate, OnDelete: onDelete})
+			lastID = id
+		}
+		fk := &def.ForeignKeys[len(def.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		if refColumn.Valid {
+			fk.RefColumns = append(fk.RefColumns, refColumn.String)
+		}
+	}
+	return def, rows.Err()
+}
+
+func closeRows(rows *sqlx.Rows) {
+	if err := rows.Close(); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+// rebuildSQLiteTable follows the procedure from https://www.sqlite.org/lang_altertable.html:
+// foreign keys are switched off on a dedicated connection, the table is
+// recreated in a transaction and the foreign keys are checked before commit.
+func (q *Queries) rebuildSQLiteTable(ctx context.Context, tableName string, op database.AlterOp, cols []models.ListDataCol) ([]string, error) {
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return nil, errors.New("table rebuild can't run inside another transaction")
+	}
+	def, err := q.SQLiteTableDefinition(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := q.queryBuilder.RebuildTable(def, op, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		logger.Errorln(err)
+		return statements, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Errorln(err)
+		}
+	}()
+
+	var foreignKeys bool
+	if err := conn.QueryRowxContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		logger.Errorln(err)
+		return statements, err
+	}
+	if foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			logger.Errorln(err)
+			return statements, err
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA foreign_keys = ON"); err != nil {
+				logger.Errorln(err)
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorln(err)
+		return statements, err
+	}
+	if err := execStatements(ctx, tx, statements); err != nil {
+		rollback(tx)
+		return statements, err
+	}
+	if foreignKeys {
+		rows, err := tx.QueryxContext(ctx, "PRAGMA foreign_key_check")
+		if err != nil {
+			rollback(tx)
+			return statements, err
+		}
+		broken := rows.Next()
+		closeRows(rows)
+		if broken {
+			rollback(tx)
+			return statements, ErrorForeignKeyCheck
+		}
+	}
+	return statements, tx.Commit()
+}
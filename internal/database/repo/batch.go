@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxBatchOperations keeps a batch from holding its locks for too long.
+const maxBatchOperations = 1000
+
+var ErrorInvalidBatch = errors.New("invalid batch")
+
+// RunBatch runs row inserts, updates and deletes in one transaction. It stops
+// at the first failing operation and rolls everything back, the failure is
+// reported in the result rather than as error.
+func (q *Queries) RunBatch(ctx context.Context, ops []models.BatchOperation) (models.BatchResult, error) {
+	result := models.BatchResult{Results: make([]models.BatchOperationResult, 0, len(ops))}
+	if len(ops) == 0 {
+		return result, fmt.Errorf("%w: no operations", ErrorInvalidBatch)
+	}
+	if len(ops) > maxBatchOperations {
+		return result, fmt.Errorf("%w: more than %d operations", ErrorInvalidBatch, maxBatchOperations)
+	}
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return result, errors.New("batch can't run inside another transaction")
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	defer rollback(tx)
+
+	txq := q.WithTx(tx)
+	// the history only learns about the changes once they're committed
+	pending := &pendingHistory{HistoryStore: txq.history}
+	txq.history = pending
+	for i, op := range ops {
+		affected, err := txq.runBatchOperation(ctx, op)
+		opResult := models.BatchOperationResult{Index: i, Operation: op.Operation, TableName: op.TableName, Success: err == nil, RowsAffected: affected}
+		if err != nil {
+			opResult.Error = err.Error()
+		}
+		result.Results = append(result.Results, opResult)
+		if err != nil {
+			logger.Error("Batch operation %d failed, rolling back: %s", i, err)
+			// rows read inside the transaction may have been cached
+			for _, op := range ops[:i+1] {
+				q.cache.Delete(op.TableName, op.Key)
+			}
+			q.InsertHistory(ctx, failedBatchEntry(pending.entries, i, len(ops), op, err))
+			return result, nil
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	result.Committed = true
+	// counts read while the transaction was open are stale now
+	for _, op := range ops {
+		q.counts.invalidate(op.TableName)
+	}
+	for _, entry := range pending.entries {
+		if err := q.history.InsertHistory(context.WithoutCancel(ctx), entry); err != nil {
+			logger.Error("failed to insert history: %s", err)
+		}
+	}
+	return result, nil
+}
+
+func (q *Queries) runBatchOperation(ctx context.Context, op models.BatchOperation) (int64, error) {
+	if err := q.CheckTableExitsInDB(ctx, op.TableName); err != nil {
+		return 0, err
+	}
+	switch op.Operation {
+	case models.OperationInsert:
+		if err := q.InsertRow(ctx, models.InsertDataProps{TableName: op.TableName, Values: op.Data}); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case models.OperationUpdate:
+		return q.UpdateRow(ctx, UpdateOrDeleteRowProps{TableName: op.TableName, Key: op.Key, Values: op.Data, Version: op.Version})
+	case models.OperationDelete:
+		return q.DeleteRow(ctx, UpdateOrDeleteRowProps{TableName: op.TableName, Key: op.Key, Version: op.Version})
+	}
+	return 0, fmt.Errorf("%w: unknown operation '%s'", ErrorInvalidBatch, op.Operation)
+}
+
+// failedBatchEntry records the operation a batch failed at, the entries of
+// the operations before it are dropped with the rollback.
+func failedBatchEntry(entries []models.History, index, total int, op models.BatchOperation, err error) models.History {
+	entry := models.History{Operation: op.Operation, TableName: op.TableName, Message: op.Operation, Error: err.Error()}
+	// the operation may have failed before it got to record itself
+	if n := len(entries); n > 0 && !entries[n-1].Success {
+		entry = entries[n-1]
+	}
+	entry.Message = fmt.Sprintf("Batch rolled back at operation %d of %d: %s", index+1, total, entry.Message)
+	return entry
+}
+
+// pendingHistory holds the entries of a transaction until it's committed.
+type pendingHistory struct {
+	HistoryStore
+	entries []models.History
+}
+
+func (h *pendingHistory) InsertHistory(_ context.Context, entry models.History) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
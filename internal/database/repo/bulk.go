@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/queries"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrorBulkConfirmation = errors.New("confirmation doesn't match the selected rows anymore, preview the operation again")
+	ErrorInvalidBulk      = errors.New("bulk operation must be delete or update")
+)
+
+// Bulk deletes or updates the selected rows with a single statement. Without
+// a confirmation only the number of rows and the statement are returned,
+// together with the confirmation to run it. The rows are counted again before
+// running and the confirmation only matches while the count and statement
+// are the same.
+func (q *Queries) Bulk(ctx context.Context, props models.BulkProps) (models.BulkResult, error) {
+	result := models.BulkResult{TableName: props.TableName, Operation: props.Operation}
+	cols, loc, err := q.Locate(ctx, props.TableName)
+	if err != nil {
+		return result, err
+	}
+	sel := queries.Selection{Loc: loc, Filter: props.Filter}
+	for _, rowKey := range props.Keys {
+		key, err := loc.Decode(props.TableName, rowKey)
+		if err != nil {
+			return result, err
+		}
+		sel.Keys = append(sel.Keys, key)
+	}
+
+	switch props.Operation {
+	case models.OperationDelete:
+		result.Query, result.Args, err = q.queryBuilder.BulkDelete(props.TableName, sel, cols)
+	case models.OperationUpdate:
+		result.Query, result.Args, err = q.queryBuilder.BulkUpdate(props.TableName, props.Values, sel, cols)
+	default:
+		err = ErrorInvalidBulk
+	}
+	if err != nil {
+		return result, err
+	}
+	countQuery, countArgs, err := q.queryBuilder.BulkCount(props.TableName, sel, cols)
+	if err != nil {
+		return result, err
+	}
+
+	if props.Confirmation == "" {
+		if err := sqlx.GetContext(ctx, q.db, &result.Count, countQuery, countArgs...); err != nil {
+			logger.Errorln(err)
+			return result, err
+		}
+		result.Confirmation, err = bulkConfirmation(result)
+		return result, err
+	}
+
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return result, errors.New("bulk operation can't run inside another transaction")
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	defer rollback(tx)
+	if err := tx.GetContext(ctx, &result.Count, countQuery, countArgs...); err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	if confirmation, err := bulkConfirmation(result); err != nil || confirmation != props.Confirmation {
+		return result, errors.Join(ErrorBulkConfirmation, err)
+	}
+
+	entry := &models.History{
+		Operation: props.Operation,
+		TableName: props.TableName,
+		Query:     result.Query,
+		Args:      result.Args,
+	}
+	if props.Operation == models.OperationDelete {
+		entry.Message = fmt.Sprintf("Deleted %d rows from table '%s'", result.Count, props.TableName)
+	} else {
+		entry.Message = fmt.Sprintf("Updated %d rows in table '%s'", result.Count, props.TableName)
+		entry.NewValues = itemValues(props.Values)
+	}
+	logger.Info("Bulk Query: %s", result.Query)
+	err = q.audit(ctx, entry, func() error {
+		res, err := tx.ExecContext(ctx, result.Query, result.Args...)
+		if err != nil {
+			logger.Errorln(err)
+			return err
+		}
+		if result.RowsAffected, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return result, err
+	}
+	q.cache.DeleteTable(props.TableName)
+	q.counts.invalidate(props.TableName)
+	result.Executed = true
+	result.Confirmation = ""
+	return result, nil
+}
+
+// bulkConfirmation fingerprints the statement and the number of rows it
+// affects, like the verification query of DeleteTable it only guards against
+// running something else than what was previewed.
+func bulkConfirmation(result models.BulkResult) (string, error) {
+	data, err := json.Marshal([]any{result.Query, result.Args, result.Count})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
@@ -1,54 +1,156 @@
 package repo
 
-import "sync"
+import (
+	"container/list"
+	"sync"
+	"time"
 
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+// rowOverhead roughly accounts for the entry, the list element and the map
+// slots of every cached row.
+const rowOverhead = 128
+
+type cachedRow struct {
+	table   string
+	key     string
+	row     []any
+	size    int64
+	expires time.Time
+}
+
+// RowCache keeps recently listed rows by table and row key so forms and
+// relations don't read them again. The least recently used rows are evicted
+// once MaxEntries or MaxBytes is reached, a zero limit means no limit.
 type RowCache struct {
-	mu   sync.RWMutex
-	Max  int
-	Keys []string
-	Rows map[string][]any
+	mu         sync.Mutex
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration
+	lru        *list.List
+	tables     map[string]map[string]*list.Element
+	bytes      int64
+	hits       int64
+	misses     int64
+	evictions  int64
 }
 
-func NewRowCache(max int) *RowCache {
+func NewRowCache(maxEntries int, maxBytes int64, ttl time.Duration) *RowCache {
 	return &RowCache{
-		Max:  max,
-		Keys: make([]string, 0, max),
-		Rows: make(map[string][]any),
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+		TTL:        ttl,
+		lru:        list.New(),
+		tables:     make(map[string]map[string]*list.Element),
 	}
 }
 
-func (c *RowCache) Set(key string, row []any) {
+func (c *RowCache) Set(table, key string, row []any) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if _, ok := c.Rows[key]; ok {
-		c.deleteUnlocked(key)
+	if el, ok := c.tables[table][key]; ok {
+		c.removeElement(el)
 	}
-	c.Rows[key] = row
-	c.Keys = append(c.Keys, key)
-	if len(c.Keys) > c.Max {
-		delete(c.Rows, c.Keys[0])
-		c.Keys = c.Keys[1:]
+	entry := &cachedRow{table: table, key: key, row: row, size: rowSize(row)}
+	if c.TTL > 0 {
+		entry.expires = time.Now().Add(c.TTL)
+	}
+	if c.MaxBytes > 0 && entry.size > c.MaxBytes {
+		return
+	}
+	keys, ok := c.tables[table]
+	if !ok {
+		keys = make(map[string]*list.Element)
+		c.tables[table] = keys
+	}
+	keys[key] = c.lru.PushFront(entry)
+	c.bytes += entry.size
+	for (c.MaxEntries > 0 && c.lru.Len() > c.MaxEntries) || (c.MaxBytes > 0 && c.bytes > c.MaxBytes) {
+		c.removeElement(c.lru.Back())
+		c.evictions++
 	}
 }
 
-func (c *RowCache) Get(key string) []any {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.Rows[key]
+func (c *RowCache) Get(table, key string) []any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.tables[table][key]
+	if !ok {
+		c.misses++
+		return nil
+	}
+	entry := el.Value.(*cachedRow)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.removeElement(el)
+		c.misses++
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	c.hits++
+	return entry.row
 }
 
-func (c *RowCache) Delete(key string) {
+func (c *RowCache) Delete(table, key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.deleteUnlocked(key)
+	if el, ok := c.tables[table][key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// DeleteTable drops every cached row of table.
+func (c *RowCache) DeleteTable(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, el := range c.tables[table] {
+		c.removeElement(el)
+	}
+}
+
+func (c *RowCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.tables = make(map[string]map[string]*list.Element)
+	c.bytes = 0
+}
+
+func (c *RowCache) Stats() models.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return models.CacheStats{
+		Entries:    c.lru.Len(),
+		Bytes:      c.bytes,
+		Tables:     len(c.tables),
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+		MaxEntries: c.MaxEntries,
+		MaxBytes:   c.MaxBytes,
+	}
+}
+
+func (c *RowCache) removeElement(el *list.Element) {
+	entry := c.lru.Remove(el).(*cachedRow)
+	c.bytes -= entry.size
+	keys := c.tables[entry.table]
+	delete(keys, entry.key)
+	if len(keys) == 0 {
+		delete(c.tables, entry.table)
+	}
 }
 
-func (c *RowCache) deleteUnlocked(key string) {
-	for i, k := range c.Keys {
-		if k == key {
-			c.Keys = append(c.Keys[:i], c.Keys[i+1:]...)
-			delete(c.Rows, key)
-			return
+// rowSize estimates the memory a row holds on to.
+func rowSize(row []any) int64 {
+	size := int64(rowOverhead + 16*len(row))
+	for _, v := range row {
+		switch v := v.(type) {
+		case string:
+			size += int64(len(v))
+		case []byte:
+			size += int64(len(v))
 		}
 	}
+	return size
 }
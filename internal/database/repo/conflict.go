@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrorRowConflict = errors.New("row was changed since it was loaded")
+
+// RowConflictError is returned when an update or delete was made against an
+// older version of the row. Current is nil when the row can't be found anymore.
+type RowConflictError struct {
+	Current map[string]any
+	Version string
+}
+
+func (e *RowConflictError) Error() string {
+	if e.Current == nil {
+		return fmt.Sprintf("%s, it doesn't exist anymore", ErrorRowConflict)
+	}
+	return fmt.Sprintf("%s, reload it and apply the change again", ErrorRowConflict)
+}
+
+func (e *RowConflictError) Unwrap() error {
+	return ErrorRowConflict
+}
+
+// RowVersion identifies the values of a row as they were loaded, the row has to
+// be normalized like the rows returned by the repository.
+func RowVersion(row []any) string {
+	data, err := json.Marshal(row)
+	if err != nil {
+		logger.Error("failed to compute row version: %v", err)
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// lockRow reads the row at key before it's changed and locks it until the
+// transaction ends. A non empty props.Version has to match the current row.
+func (q *Queries) lockRow(ctx context.Context, props UpdateOrDeleteRowProps, cols []models.ListDataCol, loc locator.Locator, key []any) ([]any, error) {
+	query, args, err := q.queryBuilder.LockRowByKey(props.TableName, loc, key)
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.db.QueryRowxContext(ctx, query, args...).SliceScan()
+	if errors.Is(err, sql.ErrNoRows) {
+		// these keys are made of the row itself, a changed row isn't found again
+		if loc.Kind == locator.KindRow || loc.Kind == locator.KindCtid {
+			return nil, &RowConflictError{}
+		}
+		return nil, ErrorNotFound
+	}
+	if err != nil {
+		logger.Errorln(err)
+		return nil, err
+	}
+	normalizeRow(row)
+	if current := RowVersion(row); props.Version != "" && current != props.Version {
+		q.cache.Set(props.TableName, props.Key, row)
+		return nil, &RowConflictError{Current: rowValues(cols, row), Version: current}
+	}
+	return row, nil
+}
+
+// inTx runs fn in a new transaction, or in the one q already runs in.
+func (q *Queries) inTx(ctx context.Context, fn func(txq *Queries) error) error {
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	defer rollback(tx)
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	return nil
+}
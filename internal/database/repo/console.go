@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/queries"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// MaxConsoleRows caps the rows a console query returns so a careless
+// `SELECT *` can't exhaust memory.
+const MaxConsoleRows = 1000
+
+// readKeywords lead statements that return rows without changing anything.
+var readKeywords = map[string]bool{
+	"select":   true,
+	"show":     true,
+	"explain":  true,
+	"describe": true,
+	"desc":     true,
+	"pragma":   true,
+	"values":   true,
+	"table":    true,
+}
+
+// modifyingKeywords start data-modifying statements, also inside a WITH.
+var modifyingKeywords = map[string]bool{
+	"insert": true,
+	"update": true,
+	"delete": true,
+	"merge":  true,
+}
+
+// schemaKeywords change the schema or a whole table wherever they appear,
+// unless they're a function like REPLACE(s, a, b).
+var schemaKeywords = map[string]bool{
+	"create":   true,
+	"drop":     true,
+	"alter":    true,
+	"truncate": true,
+	"replace":  true,
+	"rename":   true,
+}
+
+// statement tells how a console statement has to run.
+type statement struct {
+	// rows is set when it produces a result set
+	rows bool
+	// writes is set when it may change data or the schema
+	writes bool
+}
+
+// classify tells from its keywords whether a statement returns rows and
+// whether it may write. Anything that doesn't start like a query is taken as
+// a write, as are queries with a data-modifying statement inside and scripts
+// of more than one statement.
+func classify(query string) statement {
+	var words []queries.Token
+	for _, t := range queries.Tokenize(query) {
+		if !t.Quoted {
+			words = append(words, t)
+		}
+	}
+	for len(words) > 0 && words[len(words)-1].Text == ";" {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return statement{}
+	}
+	first := words[0]
+	main := first.Text
+	if main == "with" {
+		main = ""
+		for _, w := range words[1:] {
+			if w.Depth == first.Depth && (readKeywords[w.Text] || modifyingKeywords[w.Text]) {
+				main = w.Text
+				break
+			}
+		}
+	}
+
+	var st statement
+	for i, w := range words {
+		switch {
+		case modifyingKeywords[w.Text]:
+			// SELECT ... FOR UPDATE or FOR NO KEY UPDATE only locks rows
+			if w.Text == "update" && i > 0 && (words[i-1].Text == "for" || words[i-1].Text == "key") {
+				continue
+			}
+			st.writes = true
+		case schemaKeywords[w.Text]:
+			if i+1 < len(words) && words[i+1].Depth > w.Depth {
+				continue
+			}
+			return statement{writes: true}
+		case w.Text == ";":
+			return statement{writes: true}
+		case w.Text == "returning" && w.Depth == first.Depth:
+			st.rows = true
+		case w.Text == "into" && main == "select" && w.Depth == first.Depth:
+			// SELECT INTO creates a table or fills variables
+			return statement{writes: true}
+		}
+	}
+	switch {
+	case readKeywords[main]:
+		st.rows = true
+	case !modifyingKeywords[main]:
+		return statement{writes: true}
+	}
+	return st
+}
+
+// IsStatementError reports whether the database rejected a statement itself,
+// like a syntax error or a violated constraint, as opposed to failing to run
+// it because of the connection, a timeout or a cancellation.
+func IsStatementError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08 connection exception, 53 insufficient resources, 57 operator
+		// intervention like query_canceled, 58 system error, XX internal error
+		if len(pgErr.Code) < 2 {
+			return true
+		}
+		switch pgErr.Code[:2] {
+		case "08", "53", "57", "58", "XX":
+			return false
+		}
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// 1317 query interrupted, 3024 max_execution_time exceeded
+		return mysqlErr.Number != 1317 && mysqlErr.Number != 3024
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_ERROR, sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_MISMATCH,
+			sqlite3.SQLITE_RANGE, sqlite3.SQLITE_READONLY, sqlite3.SQLITE_AUTH, sqlite3.SQLITE_TOOBIG:
+			return true
+		}
+	}
+	return false
+}
+
+// RunQuery executes an arbitrary SQL statement typed into the console.
+func (q *Queries) RunQuery(ctx context.Context, query string) (models.QueryResult, error) {
+	start := time.Now()
+	result, err := q.runQuery(ctx, query)
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000
+	return result, err
+}
+
+func (q *Queries) runQuery(ctx context.Context, query string) (models.QueryResult, error) {
+	logger.Info("Console Query: %s", query)
+	st := classify(query)
+	if !st.writes {
+		return q.consoleRows(ctx, query)
+	}
+
+	result := models.QueryResult{Columns: []models.QueryColumn{}, Rows: []models.ListDataRow{}}
+	err := q.audit(ctx, &models.History{
+		Operation: models.OperationQuery,
+		Message:   fmt.Sprintf("Executed query: %s", shorten(query, 200)),
+		Query:     query,
+	}, func() error {
+		if st.rows {
+			var err error
+			if result, err = q.consoleRows(ctx, query); err != nil {
+				return err
+			}
+		} else {
+			res, err := q.db.ExecContext(ctx, query)
+			if err != nil {
+				logger.Errorln(err)
+				return err
+			}
+			if affected, err := res.RowsAffected(); err == nil {
+				result.RowsAffected = affected
+			}
+		}
+		// any table may have changed, or its schema
+		q.cache.Clear()
+		q.counts.invalidate("")
+		q.schema.clear()
+		return nil
+	})
+	return result, err
+}
+
+func (q *Queries) consoleRows(ctx context.Context, query string) (models.QueryResult, error) {
+	result := models.QueryResult{Columns: []models.QueryColumn{}, Rows: []models.ListDataRow{}}
+	rows, err := q.db.QueryxContext(ctx, query)
+	if err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.Errorln(err)
+		}
+	}()
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	for _, ct := range colTypes {
+		result.Columns = append(result.Columns, models.QueryColumn{
+			Name: ct.Name(),
+			Type: ct.DatabaseTypeName(),
+		})
+	}
+
+	for rows.Next() {
+		if len(result.Rows) == MaxConsoleRows {
+			result.Truncated = true
+			break
+		}
+		row, err := rows.SliceScan()
+		if err != nil {
+			logger.Errorln(err)
+			return result, err
+		}
+		normalizeRow(row)
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	result.RowsAffected = int64(len(result.Rows))
+	return result, nil
+}
+
+func shorten(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
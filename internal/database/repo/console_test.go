@@ -0,0 +1,51 @@
+package repo
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  statement
+	}{
+		{name: "select", query: "SELECT * FROM users", want: statement{rows: true}},
+		{name: "parenthesized select", query: "(SELECT 1) UNION (SELECT 2)", want: statement{rows: true}},
+		{name: "read only cte", query: "WITH t AS (SELECT 1 AS n) SELECT n FROM t", want: statement{rows: true}},
+		{name: "recursive cte", query: "WITH RECURSIVE t(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t) SELECT n FROM t", want: statement{rows: true}},
+		{name: "explain", query: "EXPLAIN SELECT 1", want: statement{rows: true}},
+		{name: "pragma", query: "pragma table_info(users)", want: statement{rows: true}},
+		{name: "select for update locks only", query: "SELECT * FROM users FOR UPDATE", want: statement{rows: true}},
+		{name: "select for no key update", query: "SELECT * FROM users FOR NO KEY UPDATE", want: statement{rows: true}},
+		{name: "keywords in literals", query: "SELECT 'delete', \"update\", `insert` FROM t -- delete\n/* update */", want: statement{rows: true}},
+		{name: "keywords in dollar quotes", query: "SELECT $body$ DELETE FROM t $body$, $$update$$", want: statement{rows: true}},
+		{name: "insert", query: "INSERT INTO users (name) VALUES ('returning')", want: statement{writes: true}},
+		{name: "insert returning", query: "INSERT INTO users (name) VALUES ('bob') RETURNING id", want: statement{rows: true, writes: true}},
+		{name: "update with literal", query: "UPDATE t SET note = 'returning'", want: statement{writes: true}},
+		{name: "delete returning", query: "delete from t where id = $1 returning *", want: statement{rows: true, writes: true}},
+		{name: "data-modifying cte", query: "WITH d AS (DELETE FROM t RETURNING *) SELECT count(*) FROM d", want: statement{rows: true, writes: true}},
+		{name: "cte feeding an update", query: "WITH x AS (SELECT 1 AS id) UPDATE t SET n = 1 FROM x WHERE t.id = x.id", want: statement{writes: true}},
+		{name: "cte feeding an update returning", query: "WITH x AS (SELECT 1 AS id) UPDATE t SET n = 1 FROM x WHERE t.id = x.id RETURNING t.id", want: statement{rows: true, writes: true}},
+		{name: "returning of a cte only", query: "WITH d AS (DELETE FROM t RETURNING id) DELETE FROM u WHERE id IN (SELECT id FROM d)", want: statement{writes: true}},
+		{name: "select into", query: "SELECT * INTO backup FROM users", want: statement{writes: true}},
+		{name: "explain analyze runs the statement", query: "EXPLAIN ANALYZE DELETE FROM t", want: statement{rows: true, writes: true}},
+		{name: "ddl", query: "CREATE TABLE t (id int)", want: statement{writes: true}},
+		{name: "leading comment", query: "-- cleanup\nDROP TABLE t", want: statement{writes: true}},
+		{name: "trailing semicolon", query: "SELECT 1;", want: statement{rows: true}},
+		{name: "semicolon in a literal", query: "SELECT ';DROP TABLE t'", want: statement{rows: true}},
+		{name: "replace function", query: "SELECT replace(name, 'a', 'b') FROM t", want: statement{rows: true}},
+		{name: "select then drop", query: "SELECT 1; DROP TABLE t", want: statement{writes: true}},
+		{name: "select then create", query: "SELECT 1; CREATE TABLE x(a)", want: statement{writes: true}},
+		{name: "two selects", query: "SELECT 1; SELECT 2", want: statement{writes: true}},
+		{name: "ddl in a cte", query: "WITH t AS (SELECT 1) CREATE TABLE x AS SELECT * FROM t", want: statement{writes: true}},
+		{name: "mysql replace", query: "REPLACE INTO t (id) VALUES (1)", want: statement{writes: true}},
+		{name: "empty", query: "  ", want: statement{}},
+		{name: "only semicolons", query: ";;", want: statement{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.query); got != tt.want {
+				t.Errorf("classify(%q) = %+v want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
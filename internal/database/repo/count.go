@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+)
+
+// CountRows counts the rows of tableName matching filter. Unless exact is set
+// an exact count younger than the TTL is reused, and tables whose catalog
+// estimate is above the threshold only get the estimate when unfiltered.
+func (q *Queries) CountRows(ctx context.Context, tableName string, filter *models.Filter, exact bool) (models.RowCount, error) {
+	key := countKey(tableName, filter)
+	if !exact {
+		if count, ok := q.counts.get(key); ok {
+			return models.RowCount{Count: count}, nil
+		}
+		if filter == nil && q.counts.threshold > 0 {
+			if estimate, ok := q.estimateRows(ctx, tableName); ok && estimate >= q.counts.threshold {
+				return models.RowCount{Count: int(estimate), Estimated: true}, nil
+			}
+		}
+	}
+	count, err := q.GetRowCount(ctx, tableName, filter)
+	if err != nil {
+		return models.RowCount{}, err
+	}
+	q.counts.set(key, count)
+	return models.RowCount{Count: count}, nil
+}
+
+// estimateRows reads the planner's row count, which isn't there for tables
+// that were never analyzed.
+func (q *Queries) estimateRows(ctx context.Context, tableName string) (int64, bool) {
+	query, args, err := q.queryBuilder.EstimateRows(tableName)
+	if err != nil {
+		return 0, false
+	}
+	var stat sql.NullString
+	if err := q.db.QueryRowxContext(ctx, query, args...).Scan(&stat); err != nil {
+		// also the missing sqlite_stat1 of a database never analyzed
+		logger.Debug("No row estimate for table '%s': %s", tableName, err)
+		return 0, false
+	}
+	fields := strings.Fields(stat.String)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	// Postgres reports -1 until the table is analyzed
+	estimate, err := strconv.ParseInt(fields[0], 10, 64)
+	return estimate, err == nil && estimate >= 0
+}
+
+func countKey(tableName string, filter *models.Filter) string {
+	if filter == nil {
+		return tableName + "\x00"
+	}
+	data, _ := json.Marshal(filter)
+	return tableName + "\x00" + string(data)
+}
+
+type countEntry struct {
+	count   int
+	expires time.Time
+}
+
+// maxCountEntries bounds the counts kept, every filter that was tried is a key.
+const maxCountEntries = 1000
+
+// countCache keeps exact row counts by table and filter for ttl.
+type countCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	threshold int64
+	entries   map[string]countEntry
+}
+
+func newCountCache(ttl time.Duration, threshold int64) *countCache {
+	return &countCache{ttl: ttl, threshold: threshold, entries: make(map[string]countEntry)}
+}
+
+func (c *countCache) get(key string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return 0, false
+	}
+	return entry.count, true
+}
+
+func (c *countCache) set(key string, count int) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCountEntries {
+		c.evict(now)
+	}
+	c.entries[key] = countEntry{count: count, expires: now.Add(c.ttl)}
+}
+
+// evict drops the expired counts, or the one expiring first when none has.
+func (c *countCache) evict(now time.Time) {
+	var oldest string
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= maxCountEntries {
+		delete(c.entries, oldest)
+	}
+}
+
+// invalidate drops the counts of tableName, or of every table when it's empty.
+func (c *countCache) invalidate(tableName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if tableName == "" || strings.HasPrefix(key, tableName+"\x00") {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCountCacheBounded(t *testing.T) {
+	c := newCountCache(time.Minute, 0)
+	for i := range maxCountEntries + 10 {
+		c.set(countKey("users", nil)+strconv.Itoa(i), i)
+	}
+	if len(c.entries) != maxCountEntries {
+		t.Fatalf("got %d entries want %d", len(c.entries), maxCountEntries)
+	}
+	if _, ok := c.get(countKey("users", nil) + strconv.Itoa(maxCountEntries+9)); !ok {
+		t.Error("latest count was evicted")
+	}
+}
+
+func TestCountCacheSweepsExpired(t *testing.T) {
+	c := newCountCache(time.Minute, 0)
+	for i := range maxCountEntries {
+		c.entries[strconv.Itoa(i)] = countEntry{count: i, expires: time.Now().Add(-time.Second)}
+	}
+	c.set("fresh", 1)
+	if len(c.entries) != 1 {
+		t.Fatalf("got %d entries want only the fresh one", len(c.entries))
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/biisal/rowsql/configs"
 	"github.com/biisal/rowsql/internal/database/queries"
+	"github.com/biisal/rowsql/internal/logger"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -13,25 +14,55 @@ type Queries struct {
 	driver          configs.Driver
 	queryBuilder    *queries.Builder
 	cache           *RowCache
+	counts          *countCache
+	schema          *schemaCache
 	maxItemsPerPage int
+	// readOnly skips every write rowsql does on its own, like the history
+	readOnly bool
+	history  HistoryStore
 }
 
-func New(db *sqlx.DB, driver configs.Driver, queryBuilder *queries.Builder, maxItemsPerPage int) *Queries {
-	return &Queries{
+// New creates the repository of db. Without a history store the legacy
+// rowsql_history table inside db is used.
+func New(db *sqlx.DB, driver configs.Driver, queryBuilder *queries.Builder, maxItemsPerPage int, counts configs.CountConfig, cache configs.CacheConfig, readOnly bool, history HistoryStore) *Queries {
+	q := &Queries{
 		db:              db,
 		driver:          driver,
 		queryBuilder:    queryBuilder,
-		cache:           NewRowCache(100),
+		cache:           NewRowCache(cache.RowEntries, cache.RowBytes, cache.RowTTL),
+		counts:          newCountCache(counts.CacheTTL, counts.EstimateThreshold),
+		schema:          newSchemaCache(cache.SchemaTTL),
 		maxItemsPerPage: maxItemsPerPage,
+		readOnly:        readOnly,
+		history:         history,
 	}
+	if q.history == nil {
+		q.history = tableHistory{q}
+	}
+	return q
 }
 
+// WithTx returns a copy of q running on tx, it shares the caches and the
+// history store of q. The legacy history table is written inside tx.
 func (q *Queries) WithTx(tx *sqlx.Tx) *Queries {
-	return &Queries{
-		db: tx,
+	txq := *q
+	txq.db = tx
+	if _, ok := q.history.(tableHistory); ok {
+		txq.history = tableHistory{&txq}
 	}
+	return &txq
 }
 
 func (q *Queries) Init(ctx context.Context) (err error) {
+	if _, ok := q.history.(tableHistory); !ok {
+		if err := q.migrateHistory(ctx); err != nil {
+			// the migration is retried on the next start
+			logger.Error("Failed to migrate the history table: %s", err)
+		}
+		return nil
+	}
+	if q.readOnly {
+		return nil
+	}
 	return q.CreateHistoryTable(ctx)
 }
@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrorNoResultSet = errors.New("statement doesn't return rows")
+	ErrorNotReadOnly = errors.New("only statements that don't change data can be exported")
+)
+
+// RowWriter receives streamed rows, see the export package.
+type RowWriter interface {
+	WriteHeader(cols []string) error
+	WriteRow(row []any) error
+}
+
+// ExportRows streams the rows of a table into w one by one.
+func (q *Queries) ExportRows(ctx context.Context, props models.ExportProps, w RowWriter) error {
+	cols, err := q.ListCols(ctx, props.TableName)
+	if err != nil {
+		return err
+	}
+	query, args, err := q.queryBuilder.ExportRows(props, cols)
+	if err != nil {
+		return err
+	}
+	return q.inReadOnly(ctx, func(db sqlx.QueryerContext) error {
+		return streamRows(ctx, db, w, query, args...)
+	})
+}
+
+// ExportQuery streams the result of a console query into w. It runs read-only
+// so an export can't be used to change data.
+func (q *Queries) ExportQuery(ctx context.Context, query string, w RowWriter) error {
+	st := classify(query)
+	if !st.rows {
+		return ErrorNoResultSet
+	}
+	if st.writes {
+		return ErrorNotReadOnly
+	}
+	return q.inReadOnly(ctx, func(db sqlx.QueryerContext) error {
+		return streamRows(ctx, db, w, query)
+	})
+}
+
+// inReadOnly runs fn in a read-only transaction without a statement timeout,
+// exports run as long as they need. SQLite has neither, fn gets a connection
+// with query_only set instead.
+func (q *Queries) inReadOnly(ctx context.Context, fn func(db sqlx.QueryerContext) error) error {
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return errors.New("read-only query can't run inside another transaction")
+	}
+	if q.driver != configs.DriverSQLite {
+		tx, err := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			logger.Errorln(err)
+			return err
+		}
+		defer rollback(tx)
+		restore, err := q.liftStatementTimeout(ctx, tx)
+		if err != nil {
+			return err
+		}
+		defer restore()
+		return fn(tx)
+	}
+
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Errorln(err)
+		}
+	}()
+	var queryOnly bool
+	if err := conn.QueryRowxContext(ctx, "PRAGMA query_only").Scan(&queryOnly); err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	if !queryOnly {
+		if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+			logger.Errorln(err)
+			return err
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA query_only = OFF"); err != nil {
+				logger.Errorln(err)
+				// don't hand a read-only connection back to the pool
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+	}
+	return fn(conn)
+}
+
+func streamRows(ctx context.Context, db sqlx.QueryerContext, w RowWriter, query string, args ...any) error {
+	logger.Info("Export Query: %s", query)
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	defer closeRows(rows)
+
+	cols, err := rows.Columns()
+	if err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	if err := w.WriteHeader(cols); err != nil {
+		return err
+	}
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			logger.Errorln(err)
+			return err
+		}
+		normalizeRow(row)
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// liftStatementTimeout turns off the statement timeout the session was opened
+// with for the rest of tx. Postgres resets it when tx ends, MySQL only when
+// restore runs, which has to happen before tx ends. restore may run twice.
+func (q *Queries) liftStatementTimeout(ctx context.Context, tx *sqlx.Tx) (restore func(), err error) {
+	restore = func() {}
+	switch q.driver {
+	case configs.DriverPostgres:
+		if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = 0"); err != nil {
+			logger.Errorln(err)
+			return nil, err
+		}
+	case configs.DriverMySQL:
+		var timeout int64
+		if err := tx.QueryRowxContext(ctx, "SELECT @@SESSION.max_execution_time").Scan(&timeout); err != nil {
+			logger.Errorln(err)
+			return nil, err
+		}
+		if timeout == 0 {
+			return restore, nil
+		}
+		if _, err := tx.ExecContext(ctx, "SET SESSION max_execution_time = 0"); err != nil {
+			logger.Errorln(err)
+			return nil, err
+		}
+		restored := false
+		restore = func() {
+			if restored {
+				return
+			}
+			restored = true
+			if _, err := tx.ExecContext(context.WithoutCancel(ctx), "SET SESSION max_execution_time = ?", timeout); err != nil {
+				logger.Errorln(err)
+			}
+		}
+	}
+	return restore, nil
+}
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/biisal/rowsql/configs"
 	"github.com/biisal/rowsql/internal/database/models"
 	"github.com/biisal/rowsql/internal/logger"
 	"github.com/go-sql-driver/mysql"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
 	"modernc.org/sqlite"
 	sqlite3 "modernc.org/sqlite/lib"
 )
@@ -41,7 +43,127 @@ func IsTableNotExistError(err error) bool {
 	return false
 }
 
-func (q *Queries) InsertHistory(ctx context.Context, message string) {
+var (
+	ErrorLegacyHistory   = errors.New("the legacy history table only keeps messages, filtering and reverting need the metadata store")
+	ErrorHistoryNotFound = errors.New("history entry not found")
+)
+
+// HistoryStore keeps the audit log of the changes made through rowsql.
+type HistoryStore interface {
+	InsertHistory(ctx context.Context, entry models.History) error
+	ListHistory(ctx context.Context, filter models.HistoryFilter, limit, offset int) ([]models.History, error)
+	// GetHistory returns sql.ErrNoRows for an unknown id
+	GetHistory(ctx context.Context, id int) (models.History, error)
+}
+
+// historyMigrator is a HistoryStore that takes over the rows of the legacy table once.
+type historyMigrator interface {
+	Migrated(ctx context.Context) (bool, error)
+	ImportHistory(ctx context.Context, entries []models.History) error
+}
+
+type actorCtxKey struct{}
+
+// Actor is who made a request, it is stored with every history entry.
+type Actor struct {
+	User     string
+	ClientIP string
+}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// InsertHistory records entry with the actor of ctx, failures are only logged.
+func (q *Queries) InsertHistory(ctx context.Context, entry models.History) {
+	actor, _ := ctx.Value(actorCtxKey{}).(Actor)
+	entry.User, entry.ClientIP = actor.User, actor.ClientIP
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	// the request may be canceled already, the entry should still be written
+	if err := q.history.InsertHistory(context.WithoutCancel(ctx), entry); err != nil {
+		logger.Error("failed to insert history: %s", err)
+	}
+}
+
+// audit runs exec and records entry with its duration and outcome, exec may
+// still fill in the entry.
+func (q *Queries) audit(ctx context.Context, entry *models.History, exec func() error) error {
+	start := time.Now()
+	err := exec()
+	entry.Duration = float64(time.Since(start).Microseconds()) / 1000
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	q.InsertHistory(ctx, *entry)
+	return err
+}
+
+// rowValues maps a row aligned with cols to its column names.
+func rowValues(cols []models.ListDataCol, row []any) map[string]any {
+	if len(row) != len(cols) {
+		return nil
+	}
+	values := make(map[string]any, len(cols))
+	for i, col := range cols {
+		values[col.ColumnName] = row[i]
+	}
+	return values
+}
+
+func itemValues(items []models.RowItem) map[string]any {
+	values := make(map[string]any, len(items))
+	for _, item := range items {
+		values[item.ColumnName] = item.Value
+	}
+	return values
+}
+
+func (q *Queries) ListHistory(ctx context.Context, filter models.HistoryFilter, limit, offset int) ([]models.History, error) {
+	return q.history.ListHistory(ctx, filter, limit, offset)
+}
+
+// migrateHistory moves the rows of the legacy rowsql_history table into the
+// history store, the table itself is left alone.
+func (q *Queries) migrateHistory(ctx context.Context) error {
+	migrator, ok := q.history.(historyMigrator)
+	if !ok {
+		return nil
+	}
+	if migrated, err := migrator.Migrated(ctx); err != nil || migrated {
+		return err
+	}
+	var entries []models.History
+	err := sqlx.SelectContext(ctx, q.db, &entries, fmt.Sprintf("SELECT id, message, time FROM %s ORDER BY id", historyTableName))
+	if err != nil && !IsTableNotExistError(err) {
+		return err
+	}
+	for i := range entries {
+		// only successful changes were recorded in the legacy table
+		entries[i].Success = true
+	}
+	if err := migrator.ImportHistory(ctx, entries); err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		logger.Info("Moved %d history entries out of '%s', the table can be dropped now", len(entries), historyTableName)
+	}
+	return nil
+}
+
+// tableHistory is the legacy history kept in a rowsql_history table inside the database.
+type tableHistory struct {
+	q *Queries
+}
+
+// InsertHistory only keeps the message of entry.
+func (h tableHistory) InsertHistory(ctx context.Context, entry models.History) error {
+	q := h.q
+	if q.readOnly {
+		return nil
+	}
 	var query string
 
 	switch q.driver {
@@ -54,23 +176,18 @@ func (q *Queries) InsertHistory(ctx context.Context, message string) {
 
 	}
 
+	message := entry.Message
+	if !entry.Success {
+		message = fmt.Sprintf("Failed: %s (%s)", message, entry.Error)
+	}
 	_, err := q.db.ExecContext(ctx, query, message)
-	if err != nil {
-		if IsTableNotExistError(err) {
-			if err = q.CreateHistoryTable(ctx); err != nil {
-				logger.Errorln(err)
-				return
-			}
-			_, err = q.db.ExecContext(ctx, query, message)
-			if err != nil {
-				logger.Errorln(err)
-				return
-			}
-		} else {
-			logger.Errorln(err)
-			return
+	if err != nil && IsTableNotExistError(err) {
+		if err = q.CreateHistoryTable(ctx); err != nil {
+			return err
 		}
+		_, err = q.db.ExecContext(ctx, query, message)
 	}
+	return err
 }
 
 func (q *Queries) DeleteHistory(ctx context.Context, id int) error {
@@ -102,7 +219,11 @@ func (q *Queries) CreateHistoryTable(ctx context.Context) error {
 	return nil
 }
 
-func (q *Queries) ListHistory(ctx context.Context, limit, offset int) ([]models.History, error) {
+func (h tableHistory) ListHistory(ctx context.Context, filter models.HistoryFilter, limit, offset int) ([]models.History, error) {
+	if !filter.IsZero() {
+		return nil, ErrorLegacyHistory
+	}
+	q := h.q
 	var query string
 
 	switch q.driver {
@@ -116,6 +237,10 @@ func (q *Queries) ListHistory(ctx context.Context, limit, offset int) ([]models.
 	}
 	rows, err := q.db.QueryxContext(ctx, query, limit, offset)
 	if err != nil {
+		// read-only databases never get a history table
+		if q.readOnly && IsTableNotExistError(err) {
+			return nil, nil
+		}
 		logger.Errorln(err)
 		return nil, err
 	}
@@ -139,3 +264,7 @@ func (q *Queries) ListHistory(ctx context.Context, limit, offset int) ([]models.
 	}
 	return items, nil
 }
+
+func (h tableHistory) GetHistory(ctx context.Context, id int) (models.History, error) {
+	return models.History{}, ErrorLegacyHistory
+}
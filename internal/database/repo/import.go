@@ -0,0 +1,278 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/queries"
+	"github.com/biisal/rowsql/internal/importer"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/biisal/rowsql/internal/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxImportErrors caps the row errors kept in an ImportResult, the rest is only counted.
+const maxImportErrors = 100
+
+type ImportProps struct {
+	TableName string
+	// Mapping maps source fields to columns. When it's empty, fields named
+	// like a column (ignoring case) are imported into that column.
+	Mapping map[string]string
+	DryRun  bool
+}
+
+// ImportRows inserts every record of src in batches inside one transaction.
+// Nothing is committed when a record fails or for a dry run, the result then
+// tells which records would fail.
+func (q *Queries) ImportRows(ctx context.Context, props ImportProps, src importer.Source) (models.ImportResult, error) {
+	start := time.Now()
+	result := models.ImportResult{Errors: []models.ImportRowError{}, DryRun: props.DryRun}
+	cols, err := q.ListCols(ctx, props.TableName)
+	if err != nil {
+		return result, err
+	}
+	resolve, err := importMapping(props.Mapping, cols)
+	if err != nil {
+		return result, err
+	}
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return result, errors.New("import can't run inside another transaction")
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			rollback(tx)
+		}
+	}()
+	restore, err := q.liftStatementTimeout(ctx, tx)
+	if err != nil {
+		return result, err
+	}
+	defer restore()
+
+	batch := &importBatch{q: q, tx: tx, tableName: props.TableName, result: &result}
+	for row := 1; ; row++ {
+		record, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		result.Total++
+		if err != nil {
+			if !errors.Is(err, importer.ErrorInvalidRecord) {
+				return result, err
+			}
+			batch.fail(row, "", err.Error())
+			continue
+		}
+
+		columns := make([]string, 0, len(record))
+		values := make([]any, 0, len(record))
+		failed := false
+		// keep the table's column order so equal records share a batch
+		for _, col := range cols {
+			v, ok := resolve(record, col.ColumnName)
+			if !ok {
+				continue
+			}
+			inputType := col.InputType
+			if inputType == utils.ReferenceInput {
+				inputType = utils.GetInputType(col.DataType)
+			}
+			value, err := importer.Coerce(v, inputType)
+			if err != nil {
+				batch.fail(row, col.ColumnName, err.Error())
+				failed = true
+				break
+			}
+			columns = append(columns, col.ColumnName)
+			values = append(values, value)
+		}
+		if failed {
+			continue
+		}
+		if len(columns) == 0 {
+			batch.fail(row, "", "no field maps to a column")
+			continue
+		}
+		if err := batch.add(ctx, row, columns, values); err != nil {
+			return result, err
+		}
+	}
+	if err := batch.flush(ctx); err != nil {
+		return result, err
+	}
+
+	if result.ErrorCount > 0 || props.DryRun {
+		return result, nil
+	}
+	restore()
+	if err := tx.Commit(); err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	committed = true
+	result.Committed = true
+	q.cache.DeleteTable(props.TableName)
+	q.counts.invalidate(props.TableName)
+	q.InsertHistory(ctx, models.History{
+		Operation: models.OperationImport,
+		TableName: props.TableName,
+		Message:   fmt.Sprintf("Imported %d rows into table '%s'", result.Inserted, props.TableName),
+		Duration:  float64(time.Since(start).Microseconds()) / 1000,
+		Success:   true,
+	})
+	return result, nil
+}
+
+// importMapping validates mapping against cols and returns a lookup of the
+// source value imported into a column.
+func importMapping(mapping map[string]string, cols []models.ListDataCol) (func(map[string]any, string) (any, bool), error) {
+	if len(mapping) == 0 {
+		return func(record map[string]any, column string) (any, bool) {
+			if v, ok := record[column]; ok {
+				return v, true
+			}
+			for field, v := range record {
+				if strings.EqualFold(field, column) {
+					return v, true
+				}
+			}
+			return nil, false
+		}, nil
+	}
+
+	fields := make(map[string]string, len(mapping))
+	for field, column := range mapping {
+		if column == "" {
+			continue
+		}
+		found := false
+		for _, col := range cols {
+			if col.ColumnName == column {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidColumn, column)
+		}
+		if _, ok := fields[column]; ok {
+			return nil, fmt.Errorf("%w: %s", apperr.ErrorDuplicateColumn, column)
+		}
+		fields[column] = field
+	}
+	return func(record map[string]any, column string) (any, bool) {
+		field, ok := fields[column]
+		if !ok {
+			return nil, false
+		}
+		v, ok := record[field]
+		return v, ok
+	}, nil
+}
+
+// importBatch collects records with the same columns into one INSERT.
+type importBatch struct {
+	q         *Queries
+	tx        *sqlx.Tx
+	tableName string
+	result    *models.ImportResult
+
+	columns []string
+	rows    [][]any
+	rowNums []int
+}
+
+func (b *importBatch) fail(row int, column, message string) {
+	b.result.ErrorCount++
+	if len(b.result.Errors) < maxImportErrors {
+		b.result.Errors = append(b.result.Errors, models.ImportRowError{Row: row, Column: column, Message: message})
+	}
+}
+
+func (b *importBatch) add(ctx context.Context, row int, columns []string, values []any) error {
+	if len(b.rows) > 0 && (strings.Join(columns, "\x00") != strings.Join(b.columns, "\x00") ||
+		len(b.rows) >= queries.ImportBatchSize(len(columns))) {
+		if err := b.flush(ctx); err != nil {
+			return err
+		}
+	}
+	b.columns = columns
+	b.rows = append(b.rows, values)
+	b.rowNums = append(b.rowNums, row)
+	return nil
+}
+
+// flush inserts the collected rows with one statement. When it fails the
+// rows are retried one by one to find the failing records.
+func (b *importBatch) flush(ctx context.Context) error {
+	if len(b.rows) == 0 {
+		return nil
+	}
+	defer func() {
+		b.rows, b.rowNums = nil, nil
+	}()
+	execErr, err := b.exec(ctx, b.rows)
+	if err != nil {
+		return err
+	}
+	if execErr == nil {
+		b.result.Inserted += len(b.rows)
+		return nil
+	}
+	if len(b.rows) == 1 {
+		b.fail(b.rowNums[0], "", execErr.Error())
+		return nil
+	}
+	for i, row := range b.rows {
+		execErr, err := b.exec(ctx, [][]any{row})
+		if err != nil {
+			return err
+		}
+		if execErr != nil {
+			b.fail(b.rowNums[i], "", execErr.Error())
+			continue
+		}
+		b.result.Inserted++
+	}
+	return nil
+}
+
+// exec runs the insert inside a savepoint so a failing statement doesn't
+// abort the whole transaction. execErr is the error of the insert itself,
+// err a failure that makes the transaction unusable.
+func (b *importBatch) exec(ctx context.Context, rows [][]any) (execErr, err error) {
+	query, args, err := b.q.queryBuilder.InsertRows(b.tableName, b.columns, rows)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.tx.ExecContext(ctx, "SAVEPOINT rowsql_import"); err != nil {
+		logger.Errorln(err)
+		return nil, err
+	}
+	if _, execErr = b.tx.ExecContext(ctx, query, args...); execErr != nil {
+		if _, err := b.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT rowsql_import"); err != nil {
+			logger.Errorln(err)
+			return nil, err
+		}
+		return execErr, nil
+	}
+	if _, err := b.tx.ExecContext(ctx, "RELEASE SAVEPOINT rowsql_import"); err != nil {
+		logger.Errorln(err)
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+)
+
+var (
+	ErrorIndexNotFound  = errors.New("index not found")
+	ErrorDropPrimaryKey = errors.New("primary key index can't be dropped")
+)
+
+func (q *Queries) ListIndexes(ctx context.Context, tableName string) ([]models.Index, error) {
+	if indexes, ok := getSchema[models.Index](q.schema, schemaIndexesKey(tableName)); ok {
+		return indexes, nil
+	}
+	query, args, err := q.queryBuilder.ListIndexes(tableName)
+	if err != nil {
+		logger.Error("failed to build query : %v", err)
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("failed to query: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.Errorln(err)
+		}
+	}()
+	items := []models.Index{}
+	for rows.Next() {
+		var i models.Index
+		var column string
+		if err := rows.Scan(&i.Name, &column, &i.IsUnique, &i.IsPrimary, &i.Method, &i.Predicate, &i.Definition); err != nil {
+			logger.Error("failed to scan rows in list indexes: %v", err)
+			return nil, err
+		}
+		// rows come ordered by index, one per column
+		if n := len(items); n > 0 && items[n-1].Name == i.Name {
+			items[n-1].Columns = append(items[n-1].Columns, column)
+			continue
+		}
+		i.Columns = []string{column}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("failed to scan rows: %v", err)
+		return nil, err
+	}
+	setSchema(q.schema, schemaIndexesKey(tableName), items)
+	return items, nil
+}
+
+// CreateIndexQuery returns the statement CreateIndex would run without running it.
+func (q *Queries) CreateIndexQuery(ctx context.Context, tableName string, input database.IndexInput) (string, error) {
+	cols, err := q.ListCols(ctx, tableName)
+	if err != nil {
+		return "", err
+	}
+	return q.queryBuilder.CreateIndex(tableName, input, cols)
+}
+
+func (q *Queries) CreateIndex(ctx context.Context, tableName string, input database.IndexInput) (string, error) {
+	query, err := q.CreateIndexQuery(ctx, tableName, input)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("CREATE INDEX Query: %s", query)
+	// a unique index changes the columns too
+	defer q.schema.clear()
+	err = q.audit(ctx, &models.History{
+		Operation: models.OperationCreateIndex,
+		TableName: tableName,
+		Message:   fmt.Sprintf("Created index on table '%s': %s", tableName, query),
+		Query:     query,
+	}, func() error {
+		_, err := q.db.ExecContext(ctx, query)
+		if err != nil {
+			logger.Errorln(err)
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return query, nil
+}
+
+// DropIndexQuery returns the statement DropIndex would run without running it.
+func (q *Queries) DropIndexQuery(ctx context.Context, tableName, indexName string) (string, error) {
+	indexes, err := q.ListIndexes(ctx, tableName)
+	if err != nil {
+		return "", err
+	}
+	for _, index := range indexes {
+		if index.Name != indexName {
+			continue
+		}
+		if index.IsPrimary {
+			return "", ErrorDropPrimaryKey
+		}
+		return q.queryBuilder.DropIndex(tableName, indexName)
+	}
+	return "", ErrorIndexNotFound
+}
+
+func (q *Queries) DropIndex(ctx context.Context, tableName, indexName string) (string, error) {
+	query, err := q.DropIndexQuery(ctx, tableName, indexName)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("DROP INDEX Query: %s", query)
+	defer q.schema.clear()
+	err = q.audit(ctx, &models.History{
+		Operation: models.OperationDropIndex,
+		TableName: tableName,
+		Message:   fmt.Sprintf("Dropped index '%s' on table '%s'", indexName, tableName),
+		Query:     query,
+	}, func() error {
+		_, err := q.db.ExecContext(ctx, query)
+		if err != nil {
+			logger.Errorln(err)
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return query, nil
+}
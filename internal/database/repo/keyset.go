@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/queries"
+)
+
+// KeysetRows reads a page of rows after or before cursor, props.Column and
+// props.Order only apply to the first page, which cursor is empty for. Tables
+// without a primary key or rowid, and SQLite tables sorted by a date, fall
+// back to offset paging with props.Offset.
+func (q *Queries) KeysetRows(ctx context.Context, props models.ListDataProps, cursor string) (models.RowsPage, error) {
+	cols, loc, err := q.Locate(ctx, props.TableName)
+	if err != nil {
+		return models.RowsPage{}, err
+	}
+	if !loc.Ordered() {
+		rows, err := q.ListRows(ctx, props)
+		return models.RowsPage{Rows: rows}, err
+	}
+	position := models.Cursor{Column: props.Column, Desc: strings.EqualFold(props.Order, "desc")}
+	if cursor != "" {
+		if position, err = loc.DecodeCursor(props.TableName, cursor); err != nil {
+			return models.RowsPage{}, err
+		}
+	}
+	if !q.queryBuilder.KeysetSortable(position.Column, cols, loc) {
+		rows, err := q.ListRows(ctx, props)
+		return models.RowsPage{Rows: rows}, err
+	}
+	query, args, err := q.queryBuilder.KeysetRows(props, cols, loc, position)
+	if err != nil {
+		return models.RowsPage{}, err
+	}
+	rows, keys, err := q.queryRows(ctx, props.TableName, query, args, cols, loc)
+	if err != nil {
+		return models.RowsPage{}, err
+	}
+
+	more := len(rows) > props.Limit
+	if more {
+		rows, keys = rows[:props.Limit], keys[:props.Limit]
+	}
+	if position.Before {
+		slices.Reverse(rows)
+		slices.Reverse(keys)
+	}
+	page := models.RowsPage{Rows: rows, Keyset: true}
+	if len(rows) == 0 {
+		return page, nil
+	}
+	// a page read after a cursor has one before it and the other way around
+	hasNext, hasPrev := more, len(position.Values) > 0
+	if position.Before {
+		hasNext, hasPrev = true, more
+	}
+
+	sortIdx := slices.IndexFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == position.Column })
+	boundary := func(i int, before bool) (string, error) {
+		values := keys[i]
+		if len(queries.KeysetColumns(position.Column, loc)) > len(loc.Columns) {
+			// the row key is the first value of a listed row
+			values = append([]any{rows[i].([]any)[sortIdx+1]}, values...)
+		}
+		return loc.EncodeCursor(props.TableName, models.Cursor{
+			Column: position.Column,
+			Desc:   position.Desc,
+			Values: values,
+			Before: before,
+		})
+	}
+	if hasNext {
+		if page.NextCursor, err = boundary(len(rows)-1, false); err != nil {
+			return page, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = boundary(0, true); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
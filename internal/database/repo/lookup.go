@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+)
+
+var ErrorNoReference = errors.New("column doesn't reference another table")
+
+var labelColumnNames = []string{"name", "title", "label", "username", "email", "slug", "code"}
+
+// labelColumn picks the column of the referenced table that best describes a
+// row to a human: a well known name first, then the first text column.
+func labelColumn(cols []models.ListDataCol, valueColumn string) string {
+	for _, name := range labelColumnNames {
+		idx := slices.IndexFunc(cols, func(c models.ListDataCol) bool {
+			return strings.EqualFold(c.ColumnName, name)
+		})
+		if idx >= 0 {
+			return cols[idx].ColumnName
+		}
+	}
+	for _, col := range cols {
+		if col.ColumnName != valueColumn && (col.InputType == "text" || col.InputType == "textarea") {
+			return col.ColumnName
+		}
+	}
+	return valueColumn
+}
+
+// Lookup lists candidate values for a foreign key column of props.TableName
+// from the table it references. props.LabelColumn is optional.
+func (q *Queries) Lookup(ctx context.Context, props models.LookupProps) (models.LookupResult, error) {
+	var result models.LookupResult
+	cols, err := q.ListCols(ctx, props.TableName)
+	if err != nil {
+		return result, err
+	}
+	idx := slices.IndexFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == props.Column })
+	if idx < 0 {
+		return result, apperr.ErrorInvalidColumn
+	}
+	col := cols[idx]
+	if col.RefTable == "" {
+		return result, ErrorNoReference
+	}
+	refCols, err := q.ListCols(ctx, col.RefTable)
+	if err != nil {
+		return result, err
+	}
+
+	props.TableName, props.Column = col.RefTable, col.RefColumn
+	if props.LabelColumn == "" {
+		props.LabelColumn = labelColumn(refCols, col.RefColumn)
+	}
+	result = models.LookupResult{
+		Table:       props.TableName,
+		Column:      props.Column,
+		LabelColumn: props.LabelColumn,
+		Options:     []models.LookupOption{},
+	}
+
+	query, args, err := q.queryBuilder.Lookup(props, refCols)
+	if err != nil {
+		return result, err
+	}
+	logger.Info("Query : %s", query)
+	rows, err := q.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			logger.Errorln(err)
+			return result, err
+		}
+		normalizeRow(row)
+		result.Options = append(result.Options, models.LookupOption{Value: row[0], Label: row[1]})
+	}
+	if err := rows.Err(); err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+
+	countQuery, args, err := q.queryBuilder.CountLookup(props, refCols)
+	if err != nil {
+		return result, err
+	}
+	if err := q.db.QueryRowxContext(ctx, countQuery, args...).Scan(&result.RowCount); err != nil {
+		logger.Errorln(err)
+		return result, err
+	}
+	result.HasNextPage = props.Offset+len(result.Options) < result.RowCount
+	return result, nil
+}
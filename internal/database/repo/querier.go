@@ -12,7 +12,7 @@ type Querier interface {
 	ListCols(ctx context.Context, tableName string) ([]models.ListDataCol, error)
 	ListRows(ctx context.Context, props models.ListDataProps) (models.ListDataRow, error)
 	InsertRow(ctx context.Context, props models.InsertDataProps) error
-	GetRow(ctx context.Context, tableName, hash string, offset, limit int) ([]any, error)
+	GetRow(ctx context.Context, tableName, rowKey string) ([]any, error)
 	GetDriver() configs.Driver
 }
 
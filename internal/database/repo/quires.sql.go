@@ -5,9 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/biisal/rowsql/configs"
 	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/locator"
 	"github.com/biisal/rowsql/internal/database/models"
 	"github.com/biisal/rowsql/internal/logger"
 	"github.com/biisal/rowsql/internal/utils"
@@ -19,19 +21,13 @@ func ErrorInvalidTable(tableName string) error {
 
 var ErrorNotFound = errors.New("not found")
 
-func (q *Queries) GetQuotedTableName(tableName string) string {
-	switch q.GetDriver() {
-	case configs.DriverMySQL:
-		tableName = fmt.Sprintf("`%s`", tableName)
-	case configs.DriverPostgres:
-		tableName = fmt.Sprintf("\"%s\"", tableName)
-	case configs.DriverSQLite:
-		tableName = fmt.Sprintf("\"%s\"", tableName)
-	}
-	return tableName
-}
-
 func (q *Queries) CheckTableExitsInDB(ctx context.Context, tableName string) error {
+	if q.schema.enabled() {
+		tables, err := q.ListTables(ctx)
+		if err == nil && slices.ContainsFunc(tables, func(t models.ListTablesRow) bool { return t.Name == tableName }) {
+			return nil
+		}
+	}
 	query, args, err := q.queryBuilder.CheckTableExitsQuery(tableName)
 	if err != nil {
 		return err
@@ -50,10 +46,15 @@ func (q *Queries) CheckTableExitsInDB(ctx context.Context, tableName string) err
 		logger.Info("Query : %s", query)
 		return ErrorInvalidTable(tableName)
 	}
+	// created outside rowsql since the tables were listed
+	q.schema.delete(schemaTablesKey)
 	return nil
 }
 
 func (q *Queries) ListCols(ctx context.Context, tableName string) ([]models.ListDataCol, error) {
+	if cols, ok := getSchema[models.ListDataCol](q.schema, schemaColsKey(tableName)); ok {
+		return cols, nil
+	}
 	query, args, err := q.queryBuilder.ColumnsList(tableName)
 	if err != nil {
 		logger.Error("failed to build query : %v", err)
@@ -72,21 +73,28 @@ func (q *Queries) ListCols(ctx context.Context, tableName string) ([]models.List
 	var items []models.ListDataCol
 	for rows.Next() {
 		var i models.ListDataCol
-		if err := rows.Scan(&i.ColumnName, &i.DataType, &i.HasDefault, &i.IsUnique, &i.HasAutoIncrement); err != nil {
+		if err := rows.Scan(&i.ColumnName, &i.DataType, &i.HasDefault, &i.IsUnique, &i.IsUniqueKey, &i.IsNullable, &i.HasAutoIncrement, &i.IsGenerated, &i.IsIdentity, &i.IsPrimaryKey, &i.RefTable, &i.RefColumn); err != nil {
 			logger.Error("failed to scan rows in list cols: %v", err)
 			return nil, err
 		}
 		i.InputType = utils.GetInputType(i.DataType)
+		if i.RefTable != "" {
+			i.InputType = utils.ReferenceInput
+		}
 		items = append(items, i)
 	}
 	if err := rows.Err(); err != nil {
 		logger.Error("failed to scan rows: %v", err)
 		return nil, err
 	}
+	setSchema(q.schema, schemaColsKey(tableName), items)
 	return items, nil
 }
 
 func (q *Queries) ListTables(ctx context.Context) ([]models.ListTablesRow, error) {
+	if tables, ok := getSchema[models.ListTablesRow](q.schema, schemaTablesKey); ok {
+		return tables, nil
+	}
 	query, err := q.queryBuilder.ListTables()
 	if err != nil {
 		logger.Error("failed to build query : %v", err)
@@ -108,7 +116,8 @@ func (q *Queries) ListTables(ctx context.Context) ([]models.ListTablesRow, error
 			logger.Error("failed to scan rows: %v", err)
 			return nil, err
 		}
-		if i.TableName == historyTableName {
+		i.Name = q.queryBuilder.QualifiedTableName(i.TableSchema, i.TableName)
+		if i.Name == historyTableName {
 			continue
 		}
 		items = append([]models.ListTablesRow{i}, items...)
@@ -117,20 +126,40 @@ func (q *Queries) ListTables(ctx context.Context) ([]models.ListTablesRow, error
 		logger.Error("failed to scan rows: %v", err)
 		return nil, err
 	}
+	setSchema(q.schema, schemaTablesKey, items)
 	return items, nil
 }
 
+// Locate returns the columns of a table together with the locator used to address its rows.
+func (q *Queries) Locate(ctx context.Context, tableName string) ([]models.ListDataCol, locator.Locator, error) {
+	cols, err := q.ListCols(ctx, tableName)
+	if err != nil {
+		return nil, locator.Locator{}, err
+	}
+	return cols, locator.Discover(q.driver, cols), nil
+}
+
 func (q *Queries) ListRows(ctx context.Context, props models.ListDataProps) (models.ListDataRow, error) {
-	query, args, err := q.queryBuilder.ListRows(props.TableName, props.Column, props.Order, props.Limit, props.Offset)
+	cols, loc, err := q.Locate(ctx, props.TableName)
 	if err != nil {
 		return nil, err
 	}
+	query, args, err := q.queryBuilder.ListRows(props, cols, loc)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := q.queryRows(ctx, props.TableName, query, args, cols, loc)
+	return data, err
+}
 
+// queryRows runs a row listing and caches the rows. Every row starts with its
+// row key, the key values of the rows are returned as well.
+func (q *Queries) queryRows(ctx context.Context, tableName, query string, args []any, cols []models.ListDataCol, loc locator.Locator) (models.ListDataRow, [][]any, error) {
 	logger.Info("Query : %s", query)
 	rows, err := q.db.QueryxContext(ctx, query, args...)
 	if err != nil {
 		logger.Errorln(err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
@@ -138,40 +167,64 @@ func (q *Queries) ListRows(ctx context.Context, props models.ListDataProps) (mod
 		}
 	}()
 	data := make(models.ListDataRow, 0)
+	var keys [][]any
 	for rows.Next() {
 		row, err := rows.SliceScan()
 		if err != nil {
 			logger.Errorln(err.Error())
-			return nil, err
+			return nil, nil, err
 		}
+		normalizeRow(row)
 
-		for i, v := range row {
-			if b, ok := v.([]byte); ok {
-				row[i] = string(b)
-			}
+		keyValues, err := loc.Values(cols, row)
+		if err != nil {
+			logger.Error("failed to get row key: %v", err)
+			return nil, nil, err
 		}
-		rowHash, err := utils.MakeRowHash(row)
+		if loc.PseudoColumn() != "" {
+			row = row[1:]
+		}
+		rowKey, err := loc.Encode(tableName, keyValues)
 		if err != nil {
-			logger.Error("failed to hash row: %v", err)
-			continue
+			logger.Error("failed to encode row key: %v", err)
+			return nil, nil, err
 		}
-		q.cache.Set(rowHash, row)
-		row = append([]any{rowHash}, row...)
+		q.cache.Set(tableName, rowKey, row)
+		row = append([]any{rowKey}, row...)
 		data = append(data, row)
+		keys = append(keys, keyValues)
 	}
 
 	if err := rows.Err(); err != nil {
 		logger.Errorln(err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 
-	return data, nil
+	return data, keys, nil
+}
+
+func normalizeRow(row []any) {
+	for i, v := range row {
+		if b, ok := v.([]byte); ok {
+			row[i] = string(b)
+		}
+	}
 }
 
-func (q *Queries) GetRowCount(ctx context.Context, tableName string) (int, error) {
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", q.GetQuotedTableName(tableName))
+func (q *Queries) GetRowCount(ctx context.Context, tableName string, filter *models.Filter) (int, error) {
+	var cols []models.ListDataCol
+	if filter != nil {
+		var err error
+		if cols, err = q.ListCols(ctx, tableName); err != nil {
+			return 0, err
+		}
+	}
+	countQuery, args, err := q.queryBuilder.CountRows(tableName, filter, cols)
+	if err != nil {
+		return 0, err
+	}
 	var count int
-	err := q.db.QueryRowxContext(ctx, countQuery).Scan(&count)
+	err = q.db.QueryRowxContext(ctx, countQuery, args...).Scan(&count)
 	if err != nil {
 		logger.Errorln(err.Error())
 		return 0, err
@@ -187,125 +240,224 @@ func (q *Queries) InsertRow(ctx context.Context, props models.InsertDataProps) e
 	}
 
 	logger.Info("Query: %s", query)
-	_, err = q.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		logger.Errorln(err)
-		return err
-	}
-
-	historyMsg := fmt.Sprintf("Inserted row into table '%s'", props.TableName)
-	q.InsertHistory(ctx, historyMsg)
-
-	return nil
+	entry := &models.History{
+		Operation: models.OperationInsert,
+		TableName: props.TableName,
+		Message:   fmt.Sprintf("Inserted row into table '%s'", props.TableName),
+		Query:     query,
+		Args:      args,
+		NewValues: itemValues(props.Values),
+	}
+	return q.audit(ctx, entry, func() error {
+		row, err := q.insertRow(ctx, props, query, args)
+		if err != nil {
+			logger.Errorln(err)
+			return err
+		}
+		if row != nil {
+			entry.NewValues = row
+		}
+		// a new row may take over the rowid or ctid of a deleted one
+		q.cache.DeleteTable(props.TableName)
+		q.counts.invalidate(props.TableName)
+		return nil
+	})
 }
 
-func (q *Queries) GetRow(ctx context.Context, tableName, hash string, offest, limit int) ([]any, error) {
-	if row := q.cache.Get(hash); row != nil {
-		logger.Info("found data in cache: %v", row)
-		return row, nil
+// insertRow runs the insert and reads the inserted row back, so values the
+// database generated like serial keys are recorded too. The row is nil when
+// it can't be found again.
+func (q *Queries) insertRow(ctx context.Context, props models.InsertDataProps, query string, args []any) (map[string]any, error) {
+	cols, loc, err := q.Locate(ctx, props.TableName)
+	if err != nil {
+		return nil, err
 	}
-	logger.Info("not found in cache! Fetching from db limit=%d offset=%d", limit, offest)
-	for offest <= limit {
-		query, args, err := q.queryBuilder.GetRows(tableName, offest+1, offest)
+	if q.driver != configs.DriverMySQL {
+		row, err := q.db.QueryRowxContext(ctx, query+" RETURNING *", args...).SliceScan()
 		if err != nil {
 			return nil, err
 		}
-		logger.Info("Query: %s offset=%d tableName=%s", query, offest, tableName)
-		data, err := q.db.QueryRowxContext(ctx, query, args...).SliceScan()
-		if err != nil {
-			logger.Error("failed to query: %v", err)
-			if !errors.Is(err, sql.ErrNoRows) {
-				return nil, err
-			}
-		}
-		for i, v := range data {
-			if b, ok := v.([]byte); ok {
-				data[i] = string(b)
-			}
-		}
-		rowHash, err := utils.MakeRowHash(data)
-		if err != nil {
-			logger.Error("failed to hash row: %v", err)
+		normalizeRow(row)
+		return rowValues(cols, row), nil
+	}
+
+	res, err := q.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if loc.Kind != locator.KindPrimaryKey && loc.Kind != locator.KindUnique {
+		return nil, nil
+	}
+	values := itemValues(props.Values)
+	key := make([]any, 0, len(loc.Columns))
+	for _, name := range loc.Columns {
+		if v, ok := values[name]; ok {
+			key = append(key, v)
 			continue
 		}
-		if rowHash == hash {
-			q.cache.Set(rowHash, data)
-			logger.Info("found data in db: %v", data)
-			return data, nil
+		idx := slices.IndexFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == name })
+		if idx < 0 || !cols[idx].HasAutoIncrement {
+			return nil, nil
 		}
-		offest++
+		id, err := res.LastInsertId()
+		if err != nil || id == 0 {
+			return nil, nil
+		}
+		key = append(key, id)
 	}
-	return nil, ErrorNotFound
+	getQuery, getArgs, err := q.queryBuilder.GetRowByKey(props.TableName, loc, key)
+	if err != nil {
+		return nil, nil
+	}
+	row, err := q.db.QueryRowxContext(ctx, getQuery, getArgs...).SliceScan()
+	if err != nil {
+		logger.Error("failed to read the inserted row: %s", err)
+		return nil, nil
+	}
+	normalizeRow(row)
+	return rowValues(cols, row), nil
 }
 
-func (q *Queries) DeleteRow(ctx context.Context, props UpdateOrDeleteRowProps) error {
-	row := q.cache.Get(props.Hash)
-	if row == nil {
-		var err error
-		row, err = q.GetRow(ctx, props.TableName, props.Hash, props.Offset, props.Limit)
-		if err != nil {
-			return err
-		}
+func (q *Queries) GetRow(ctx context.Context, tableName, rowKey string) ([]any, error) {
+	if row := q.cache.Get(tableName, rowKey); row != nil {
+		logger.Info("found data in cache: %v", row)
+		return row, nil
 	}
-	cols, err := q.ListCols(ctx, props.TableName)
+	_, loc, err := q.Locate(ctx, tableName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	query, args, err := q.queryBuilder.DeleteRow(props.TableName, cols, row, 1)
+	key, err := loc.Decode(tableName, rowKey)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	query, args, err := q.queryBuilder.GetRowByKey(tableName, loc, key)
+	if err != nil {
+		return nil, err
 	}
 	logger.Info("Query: %s", query)
-	_, err = q.db.ExecContext(ctx, query, args...)
+	data, err := q.db.QueryRowxContext(ctx, query, args...).SliceScan()
 	if err != nil {
-		logger.Errorln(err)
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotFound
+		}
+		logger.Error("failed to query: %v", err)
+		return nil, err
 	}
+	normalizeRow(data)
+	q.cache.Set(tableName, rowKey, data)
+	return data, nil
+}
 
-	historyMsg := fmt.Sprintf("Deleted row from table '%s'", props.TableName)
-	q.InsertHistory(ctx, historyMsg)
+func (q *Queries) DeleteRow(ctx context.Context, props UpdateOrDeleteRowProps) (int64, error) {
+	cols, loc, err := q.Locate(ctx, props.TableName)
+	if err != nil {
+		return 0, err
+	}
+	key, err := loc.Decode(props.TableName, props.Key)
+	if err != nil {
+		return 0, err
+	}
+	query, args, err := q.queryBuilder.DeleteRow(props.TableName, loc, key)
+	if err != nil {
+		return 0, err
+	}
+	entry := &models.History{
+		Operation: models.OperationDelete,
+		TableName: props.TableName,
+		Message:   fmt.Sprintf("Deleted row from table '%s'", props.TableName),
+		Query:     query,
+		Args:      args,
+	}
+	logger.Info("Query: %s", query)
+	var affected int64
+	err = q.audit(ctx, entry, func() error {
+		return q.inTx(ctx, func(txq *Queries) error {
+			row, err := txq.lockRow(ctx, props, cols, loc, key)
+			if err != nil {
+				return err
+			}
+			entry.OldRow = rowValues(cols, row)
+			affected, err = txq.execRowChange(ctx, query, args)
+			return err
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	q.cache.Delete(props.TableName, props.Key)
+	q.counts.invalidate(props.TableName)
+	return affected, nil
+}
 
-	return nil
+// execRowChange runs the update or delete of a single row.
+func (q *Queries) execRowChange(ctx context.Context, query string, args []any) (int64, error) {
+	result, err := q.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.Errorln(err)
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logger.Errorln(err)
+		return 0, err
+	}
+	return affected, nil
 }
 
 type UpdateOrDeleteRowProps struct {
 	TableName string
 	Values    []models.RowItem
-	Hash      string
-	Limit     int
-	Offset    int
+	Key       string
+	// Version is the RowVersion of the row as it was loaded, the change is
+	// refused with a RowConflictError when the row changed since. Empty
+	// skips the check.
+	Version string
 }
 
-func (q *Queries) UpdateRow(ctx context.Context, props UpdateOrDeleteRowProps) error {
-	row := q.cache.Get(props.Hash)
-	if row == nil {
-		var err error
-		row, err = q.GetRow(ctx, props.TableName, props.Hash, props.Offset, props.Limit)
-		if err != nil {
-			return err
-		}
+func (q *Queries) UpdateRow(ctx context.Context, props UpdateOrDeleteRowProps) (int64, error) {
+	cols, loc, err := q.Locate(ctx, props.TableName)
+	if err != nil {
+		return 0, err
 	}
-
-	cols, err := q.ListCols(ctx, props.TableName)
+	key, err := loc.Decode(props.TableName, props.Key)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	query, args, err := q.queryBuilder.UpdateRow(props.TableName, props.Values, cols, row)
-	logger.Info("Query to Update : %s", query)
+	query, args, err := q.queryBuilder.UpdateRow(props.TableName, props.Values, loc, key)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	entry := &models.History{
+		Operation: models.OperationUpdate,
+		TableName: props.TableName,
+		Message:   fmt.Sprintf("Updated row in table '%s'", props.TableName),
+		Query:     query,
+		Args:      args,
+		NewValues: itemValues(props.Values),
 	}
-	_, err = q.db.ExecContext(ctx, query, args...)
+	logger.Info("Query to Update : %s", query)
+	var affected int64
+	err = q.audit(ctx, entry, func() error {
+		return q.inTx(ctx, func(txq *Queries) error {
+			row, err := txq.lockRow(ctx, props, cols, loc, key)
+			if err != nil {
+				return err
+			}
+			entry.OldRow = rowValues(cols, row)
+			// MySQL doesn't count rows that already had the new values
+			affected, err = txq.execRowChange(ctx, query, args)
+			return err
+		})
+	})
 	if err != nil {
-		logger.Errorln(err)
-		return err
+		return 0, err
 	}
-
-	historyMsg := fmt.Sprintf("Updated row in table '%s'", props.TableName)
-	q.InsertHistory(ctx, historyMsg)
-
-	return nil
+	q.cache.Delete(props.TableName, props.Key)
+	// filtered counts may change with the row
+	q.counts.invalidate(props.TableName)
+	return affected, nil
 }
 
 type CreateTableProps struct {
@@ -319,43 +471,46 @@ func (q *Queries) CreateTable(ctx context.Context, props CreateTableProps) error
 		return err
 	}
 	logger.Info("CREATE Query: %s", query)
-	result, err := q.db.ExecContext(ctx, query)
-	if err != nil {
-		logger.Errorln(err)
-		return err
-	}
-
-	_, err = result.RowsAffected()
-	if err != nil {
-		logger.Errorln(err)
+	defer q.schema.clear()
+	return q.audit(ctx, &models.History{
+		Operation: models.OperationCreateTable,
+		TableName: props.TableName,
+		Message:   fmt.Sprintf("Created table '%s'", props.TableName),
+		Query:     query,
+	}, func() error {
+		result, err := q.db.ExecContext(ctx, query)
+		if err != nil {
+			logger.Errorln(err)
+			return err
+		}
+		_, err = result.RowsAffected()
+		if err != nil {
+			logger.Errorln(err)
+		}
 		return err
-	}
-
-	historyMsg := fmt.Sprintf("Created table '%s'", props.TableName)
-	q.InsertHistory(ctx, historyMsg)
-
-	// TODO: get table info and add to q.Tables
-	// temp refresh
-	if _, err := q.ListTables(ctx); err != nil {
-		logger.Errorln(err)
-	}
-
-	return nil
+	})
 }
 
 func (q *Queries) DeleteTable(ctx context.Context, tableName string) error {
 	query := q.queryBuilder.DeleteTable(tableName)
 	logger.Info("Query: %s", query)
-	_, err := q.db.ExecContext(ctx, query)
-	if err != nil {
-		logger.Errorln(err)
-		return err
-	}
-
-	historyMsg := fmt.Sprintf("Dropped table '%s'", tableName)
-	q.InsertHistory(ctx, historyMsg)
-
-	return nil
+	// other tables may have referenced it
+	defer q.schema.clear()
+	return q.audit(ctx, &models.History{
+		Operation: models.OperationDropTable,
+		TableName: tableName,
+		Message:   fmt.Sprintf("Dropped table '%s'", tableName),
+		Query:     query,
+	}, func() error {
+		_, err := q.db.ExecContext(ctx, query)
+		if err != nil {
+			logger.Errorln(err)
+			return err
+		}
+		q.cache.DeleteTable(tableName)
+		q.counts.invalidate(tableName)
+		return nil
+	})
 }
 
 func (q *Queries) GetDriver() configs.Driver {
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"slices"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+)
+
+func (q *Queries) ListReferences(ctx context.Context, tableName string) ([]models.Reference, error) {
+	query, args, err := q.queryBuilder.ReferencesList(tableName)
+	if err != nil {
+		logger.Error("failed to build query : %v", err)
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("failed to query: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.Errorln(err)
+		}
+	}()
+	var items []models.Reference
+	for rows.Next() {
+		var i models.Reference
+		if err := rows.Scan(&i.Table, &i.Column, &i.RefColumn); err != nil {
+			logger.Error("failed to scan rows in list references: %v", err)
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("failed to scan rows: %v", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// RowRelations returns the parent rows the given row references through its
+// foreign keys and the first page of child rows referencing it.
+func (q *Queries) RowRelations(ctx context.Context, tableName, rowKey string) (models.RowRelations, error) {
+	relations := models.RowRelations{
+		Parents:  []models.RelatedRows{},
+		Children: []models.RelatedRows{},
+	}
+	cols, err := q.ListCols(ctx, tableName)
+	if err != nil {
+		return relations, err
+	}
+	row, err := q.GetRow(ctx, tableName, rowKey)
+	if err != nil {
+		return relations, err
+	}
+	valueOf := func(column string) any {
+		idx := slices.IndexFunc(cols, func(c models.ListDataCol) bool { return c.ColumnName == column })
+		if idx < 0 || idx >= len(row) {
+			return nil
+		}
+		return row[idx]
+	}
+
+	for _, col := range cols {
+		if col.RefTable == "" {
+			continue
+		}
+		value := valueOf(col.ColumnName)
+		if value == nil {
+			continue
+		}
+		parent, err := q.relatedRows(ctx, col.RefTable, col.RefColumn, col.ColumnName, value, 1)
+		if err != nil {
+			return relations, err
+		}
+		relations.Parents = append(relations.Parents, parent)
+	}
+
+	refs, err := q.ListReferences(ctx, tableName)
+	if err != nil {
+		return relations, err
+	}
+	for _, ref := range refs {
+		value := valueOf(ref.RefColumn)
+		if value == nil {
+			continue
+		}
+		children, err := q.relatedRows(ctx, ref.Table, ref.Column, ref.RefColumn, value, q.maxItemsPerPage)
+		if err != nil {
+			return relations, err
+		}
+		relations.Children = append(relations.Children, children)
+	}
+	return relations, nil
+}
+
+func (q *Queries) relatedRows(ctx context.Context, tableName, column, localColumn string, value any, limit int) (models.RelatedRows, error) {
+	related := models.RelatedRows{
+		Table:       tableName,
+		Column:      column,
+		LocalColumn: localColumn,
+		Filter:      &models.Filter{Column: column, Op: models.FilterEq, Value: value},
+	}
+	var err error
+	if related.Cols, err = q.ListCols(ctx, tableName); err != nil {
+		return related, err
+	}
+	related.Rows, err = q.ListRows(ctx, models.ListDataProps{
+		TableName: tableName,
+		Limit:     limit,
+		Filter:    related.Filter,
+	})
+	if err != nil {
+		return related, err
+	}
+	if related.RowCount, err = q.GetRowCount(ctx, tableName, related.Filter); err != nil {
+		return related, err
+	}
+	return related, nil
+}
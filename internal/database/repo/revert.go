@@ -0,0 +1,293 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/biisal/rowsql/internal/database/locator"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/biisal/rowsql/internal/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrorNotRevertible   = errors.New("history entry can't be reverted")
+	ErrorAlreadyReverted = errors.New("history entry was already reverted")
+	ErrorRevertConflict  = errors.New("row changed since, reverting would overwrite newer data")
+)
+
+// revertPlan is the compensating statement of a history entry.
+type revertPlan struct {
+	loc  locator.Locator
+	key  []any
+	cols []models.ListDataCol
+	// expected is the row as the change left it, nil when the change deleted it
+	expected map[string]any
+	undo     models.History
+}
+
+// RevertHistory undoes a row insert, update or delete. The row must still be
+// in the state the change left it in, otherwise ErrorRevertConflict is
+// returned. With preview the statement is only built.
+func (q *Queries) RevertHistory(ctx context.Context, id int, preview bool) (models.RevertResult, error) {
+	result := models.RevertResult{EntryID: id}
+	plan, err := q.planRevert(ctx, id)
+	if err != nil {
+		return result, err
+	}
+	result.TableName, result.Operation = plan.undo.TableName, plan.undo.Operation
+	result.Query, result.Args = plan.undo.Query, plan.undo.Args
+	if preview {
+		return result, nil
+	}
+	db, ok := q.db.(*sqlx.DB)
+	if !ok {
+		return result, errors.New("revert can't run inside another transaction")
+	}
+	logger.Info("Revert Query: %s", plan.undo.Query)
+	if err := q.audit(ctx, &plan.undo, func() error { return q.execRevert(ctx, db, plan) }); err != nil {
+		return result, err
+	}
+	result.Executed = true
+	return result, nil
+}
+
+func (q *Queries) planRevert(ctx context.Context, id int) (revertPlan, error) {
+	entry, err := q.history.GetHistory(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return revertPlan{}, ErrorHistoryNotFound
+	}
+	if err != nil {
+		return revertPlan{}, err
+	}
+	if !entry.Success {
+		return revertPlan{}, fmt.Errorf("%w: the change failed", ErrorNotRevertible)
+	}
+	reverts, err := q.history.ListHistory(ctx, models.HistoryFilter{RevertOf: id}, 100, 0)
+	if err != nil {
+		return revertPlan{}, err
+	}
+	if slices.ContainsFunc(reverts, func(h models.History) bool { return h.Success }) {
+		return revertPlan{}, ErrorAlreadyReverted
+	}
+
+	cols, loc, err := q.Locate(ctx, entry.TableName)
+	if err != nil {
+		return revertPlan{}, err
+	}
+	if loc.Kind != locator.KindPrimaryKey && loc.Kind != locator.KindUnique {
+		return revertPlan{}, fmt.Errorf("%w: '%s' has no primary key or unique column to find the row again", ErrorNotRevertible, entry.TableName)
+	}
+	plan := revertPlan{
+		loc:  loc,
+		cols: cols,
+		undo: models.History{
+			TableName: entry.TableName,
+			Message:   fmt.Sprintf("Reverted #%d: %s", id, entry.Message),
+			RevertOf:  id,
+		},
+	}
+
+	switch entry.Operation {
+	case models.OperationInsert:
+		plan.expected = entry.NewValues
+		if plan.key, err = keyOf(loc, plan.expected); err != nil {
+			return plan, err
+		}
+		plan.undo.Operation = models.OperationDelete
+		plan.undo.OldRow = entry.NewValues
+		plan.undo.Query, plan.undo.Args, err = q.queryBuilder.DeleteRow(entry.TableName, loc, plan.key)
+
+	case models.OperationUpdate:
+		if entry.OldRow == nil {
+			return plan, fmt.Errorf("%w: the row before the update wasn't recorded", ErrorNotRevertible)
+		}
+		plan.expected = make(map[string]any, len(entry.OldRow))
+		for name, v := range entry.OldRow {
+			plan.expected[name] = v
+		}
+		var columns []string
+		var values []any
+		for _, col := range cols {
+			newValue, ok := entry.NewValues[col.ColumnName]
+			if !ok {
+				continue
+			}
+			plan.expected[col.ColumnName] = newValue
+			columns = append(columns, col.ColumnName)
+			values = append(values, utils.NormalizeNumber(entry.OldRow[col.ColumnName]))
+		}
+		if plan.key, err = keyOf(loc, plan.expected); err != nil {
+			return plan, err
+		}
+		plan.undo.Operation = models.OperationUpdate
+		plan.undo.OldRow = plan.expected
+		plan.undo.NewValues = make(map[string]any, len(columns))
+		for i, name := range columns {
+			plan.undo.NewValues[name] = values[i]
+		}
+		plan.undo.Query, plan.undo.Args, err = q.queryBuilder.UpdateValues(entry.TableName, columns, values, loc, plan.key)
+
+	case models.OperationDelete:
+		if entry.OldRow == nil {
+			return plan, fmt.Errorf("%w: the deleted row wasn't recorded", ErrorNotRevertible)
+		}
+		if plan.key, err = keyOf(loc, entry.OldRow); err != nil {
+			return plan, err
+		}
+		var columns []string
+		var values []any
+		identity := false
+		for _, col := range cols {
+			// the database computes generated columns again
+			if v, ok := entry.OldRow[col.ColumnName]; ok && !col.IsGenerated {
+				columns = append(columns, col.ColumnName)
+				values = append(values, utils.NormalizeNumber(v))
+				identity = identity || col.IsIdentity
+			}
+		}
+		plan.undo.Operation = models.OperationInsert
+		plan.undo.NewValues = entry.OldRow
+		plan.undo.Query, plan.undo.Args, err = q.queryBuilder.RestoreRow(entry.TableName, columns, values, identity)
+
+	default:
+		return plan, fmt.Errorf("%w: only row inserts, updates and deletes can be reverted", ErrorNotRevertible)
+	}
+	return plan, err
+}
+
+// execRevert checks and runs the plan in one transaction, the row is locked
+// while it's compared.
+func (q *Queries) execRevert(ctx context.Context, db *sqlx.DB, plan revertPlan) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	defer rollback(tx)
+
+	lockQuery, lockArgs, err := q.queryBuilder.LockRowByKey(plan.undo.TableName, plan.loc, plan.key)
+	if err != nil {
+		return err
+	}
+	// the cache is keyed by the values as the database returns them
+	cacheKey := plan.key
+	row, err := tx.QueryRowxContext(ctx, lockQuery, lockArgs...).SliceScan()
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if plan.expected != nil {
+			return fmt.Errorf("%w: the row doesn't exist anymore", ErrorRevertConflict)
+		}
+	case err != nil:
+		logger.Errorln(err)
+		return err
+	case plan.expected == nil:
+		return fmt.Errorf("%w: a row with the same key exists again", ErrorRevertConflict)
+	default:
+		normalizeRow(row)
+		if column, ok := rowMatches(plan.cols, row, plan.expected); !ok {
+			return fmt.Errorf("%w: column '%s' was changed", ErrorRevertConflict, column)
+		}
+		if key, err := plan.loc.Values(plan.cols, row); err == nil {
+			cacheKey = key
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, plan.undo.Query, plan.undo.Args...); err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Errorln(err)
+		return err
+	}
+	if rowKey, err := plan.loc.Encode(plan.undo.TableName, cacheKey); err == nil {
+		q.cache.Delete(plan.undo.TableName, rowKey)
+	}
+	q.counts.invalidate(plan.undo.TableName)
+	return nil
+}
+
+// keyOf reads the locator columns from a recorded row.
+func keyOf(loc locator.Locator, values map[string]any) ([]any, error) {
+	key := make([]any, 0, len(loc.Columns))
+	for _, name := range loc.Columns {
+		v, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: the value of key column '%s' wasn't recorded", ErrorNotRevertible, name)
+		}
+		key = append(key, utils.NormalizeNumber(v))
+	}
+	return key, nil
+}
+
+// rowMatches compares the columns of row present in expected and returns the
+// first one that differs.
+func rowMatches(cols []models.ListDataCol, row []any, expected map[string]any) (string, bool) {
+	for i, col := range cols {
+		want, ok := expected[col.ColumnName]
+		if !ok || i >= len(row) {
+			continue
+		}
+		same := sameValue(row[i], utils.NormalizeNumber(want))
+		if !same && col.InputType == "json" {
+			same = sameJSON(row[i], want)
+		}
+		if !same {
+			return col.ColumnName, false
+		}
+	}
+	return "", true
+}
+
+// sameValue compares a value read from the database with one recorded in the
+// history, which may be a form string or went through JSON.
+func sameValue(current, recorded any) bool {
+	if current == nil || recorded == nil {
+		return current == nil && recorded == nil
+	}
+	c, r := valueString(current), valueString(recorded)
+	if c == r {
+		return true
+	}
+	if cf, err := strconv.ParseFloat(c, 64); err == nil {
+		if rf, err := strconv.ParseFloat(r, 64); err == nil {
+			return cf == rf
+		}
+	}
+	if ct, ok := current.(time.Time); ok {
+		for _, layout := range []string{time.RFC3339Nano, time.DateTime, "2006-01-02T15:04", time.DateOnly} {
+			if rt, err := time.Parse(layout, r); err == nil {
+				return ct.Equal(rt)
+			}
+		}
+	}
+	return false
+}
+
+func valueString(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// sameJSON ignores the formatting of json columns.
+func sameJSON(current, recorded any) bool {
+	var c, r any
+	if json.Unmarshal([]byte(valueString(current)), &c) != nil || json.Unmarshal([]byte(valueString(recorded)), &r) != nil {
+		return false
+	}
+	return reflect.DeepEqual(c, r)
+}
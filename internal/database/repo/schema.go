@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+const schemaTablesKey = "tables"
+
+func schemaColsKey(tableName string) string {
+	return "cols\x00" + tableName
+}
+
+func schemaIndexesKey(tableName string) string {
+	return "indexes\x00" + tableName
+}
+
+type schemaEntry struct {
+	value   any
+	expires time.Time
+}
+
+// schemaCache keeps the tables, the columns with their keys and the indexes
+// read from the catalog, so browsing only runs the data queries. rowsql's own
+// DDL clears it, changes made elsewhere show up after ttl or a refresh.
+type schemaCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]schemaEntry
+}
+
+func newSchemaCache(ttl time.Duration) *schemaCache {
+	return &schemaCache{ttl: ttl, entries: make(map[string]schemaEntry)}
+}
+
+func (c *schemaCache) enabled() bool {
+	return c.ttl > 0
+}
+
+func (c *schemaCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *schemaCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]schemaEntry)
+}
+
+// getSchema returns a copy of the cached list, callers are free to change it.
+func getSchema[T any](c *schemaCache, key string) ([]T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return slices.Clone(entry.value.([]T)), true
+}
+
+func setSchema[T any](c *schemaCache, key string, value []T) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = schemaEntry{value: slices.Clone(value), expires: time.Now().Add(c.ttl)}
+}
+
+// CacheStats reports how the row cache is doing.
+func (q *Queries) CacheStats() models.CacheStats {
+	return q.cache.Stats()
+}
+
+// RefreshSchema forgets the cached tables, columns and indexes, for changes
+// made to the database outside rowsql.
+func (q *Queries) RefreshSchema() {
+	q.schema.clear()
+}
@@ -0,0 +1,176 @@
+// Package export writes rows as CSV, JSON or NDJSON one row at a time so
+// whole tables can be streamed without holding them in memory.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+)
+
+var ErrorUnknownFormat = errors.New("unknown export format, use csv, json or ndjson")
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/json"
+}
+
+// Writer receives the column names once and then every row in order. Close
+// has to be called to flush buffered output.
+type Writer interface {
+	WriteHeader(cols []string) error
+	WriteRow(row []any) error
+	Close() error
+}
+
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: bufio.NewWriter(w), array: true}, nil
+	case FormatNDJSON:
+		return &jsonWriter{w: bufio.NewWriter(w)}, nil
+	}
+	return nil, ErrorUnknownFormat
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvWriter) WriteHeader(cols []string) error {
+	c.record = make([]string, len(cols))
+	return c.w.Write(cols)
+}
+
+func (c *csvWriter) WriteRow(row []any) error {
+	for i, v := range row {
+		c.record[i] = csvValue(v)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonWriter writes every row as an object keeping the column order, either
+// inside one array or one object per line.
+type jsonWriter struct {
+	w     *bufio.Writer
+	array bool
+	keys  [][]byte
+	rows  int
+}
+
+func (j *jsonWriter) WriteHeader(cols []string) error {
+	j.keys = make([][]byte, len(cols))
+	for i, col := range cols {
+		key, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		j.keys[i] = key
+	}
+	if j.array {
+		_, err := j.w.WriteString("[")
+		return err
+	}
+	return nil
+}
+
+func (j *jsonWriter) WriteRow(row []any) error {
+	if j.array && j.rows > 0 {
+		if err := j.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	if j.array {
+		if err := j.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	if err := j.w.WriteByte('{'); err != nil {
+		return err
+	}
+	for i, v := range row {
+		if i > 0 {
+			if err := j.w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := j.w.Write(j.keys[i]); err != nil {
+			return err
+		}
+		if err := j.w.WriteByte(':'); err != nil {
+			return err
+		}
+		if _, err := j.w.Write(value); err != nil {
+			return err
+		}
+	}
+	if err := j.w.WriteByte('}'); err != nil {
+		return err
+	}
+	if !j.array {
+		if err := j.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	j.rows++
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	if j.array {
+		closing := "]\n"
+		if j.rows > 0 {
+			closing = "\n]\n"
+		}
+		if _, err := j.w.WriteString(closing); err != nil {
+			return err
+		}
+	}
+	return j.w.Flush()
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriters(t *testing.T) {
+	cols := []string{"id", "name", "created"}
+	rows := [][]any{
+		{int64(1), "a, \"b\"", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{int64(2), nil, []byte("raw")},
+	}
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: FormatCSV,
+			want:   "id,name,created\n1,\"a, \"\"b\"\"\",2024-01-02T03:04:05Z\n2,,raw\n",
+		},
+		{
+			format: FormatJSON,
+			want: "[\n{\"id\":1,\"name\":\"a, \\\"b\\\"\",\"created\":\"2024-01-02T03:04:05Z\"},\n" +
+				"{\"id\":2,\"name\":null,\"created\":\"raw\"}\n]\n",
+		},
+		{
+			format: FormatNDJSON,
+			want: "{\"id\":1,\"name\":\"a, \\\"b\\\"\",\"created\":\"2024-01-02T03:04:05Z\"}\n" +
+				"{\"id\":2,\"name\":null,\"created\":\"raw\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var sb strings.Builder
+			w, err := NewWriter(tt.format, &sb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteHeader(cols); err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range rows {
+				if err := w.WriteRow(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if sb.String() != tt.want {
+				t.Errorf("got %q want %q", sb.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("empty json", func(t *testing.T) {
+		var sb strings.Builder
+		w, _ := NewWriter(FormatJSON, &sb)
+		if err := w.WriteHeader(cols); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != "[]\n" {
+			t.Errorf("got %q want %q", sb.String(), "[]\n")
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := NewWriter("xml", &strings.Builder{}); !errors.Is(err, ErrorUnknownFormat) {
+			t.Errorf("got %v want %v", err, ErrorUnknownFormat)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+// Package importer reads records from uploaded CSV or JSON files and coerces
+// their values to what the target columns expect.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+var (
+	ErrorUnknownFormat = errors.New("unknown import format, use csv or json")
+	ErrorInvalidFile   = errors.New("invalid import file")
+	// ErrorInvalidRecord is returned by Source.Next for a single broken
+	// record, reading can go on with the next one.
+	ErrorInvalidRecord = errors.New("invalid record")
+)
+
+// FormatFromFilename guesses the format from the file extension.
+func FormatFromFilename(name string) Format {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".csv"):
+		return FormatCSV
+	case strings.HasSuffix(name, ".json"):
+		return FormatJSON
+	}
+	return ""
+}
+
+// Source yields records keyed by their source field names until io.EOF.
+type Source interface {
+	Next() (map[string]any, error)
+}
+
+func NewSource(format Format, r io.Reader) (Source, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVSource(r)
+	case FormatJSON:
+		return newJSONSource(r)
+	}
+	return nil, ErrorUnknownFormat
+}
+
+// csvSource uses the first line as field names.
+type csvSource struct {
+	r      *csv.Reader
+	header []string
+}
+
+func newCSVSource(r io.Reader) (*csvSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: the file is empty", ErrorInvalidFile)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrorInvalidFile, err)
+	}
+	for i, field := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(field, "\ufeff"))
+	}
+	return &csvSource{r: reader, header: header}, nil
+}
+
+func (c *csvSource) Next() (map[string]any, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("%w: %s", ErrorInvalidRecord, err)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrorInvalidFile, err)
+	}
+	if len(record) != len(c.header) {
+		return nil, fmt.Errorf("%w: %d fields, expected %d", ErrorInvalidRecord, len(record), len(c.header))
+	}
+	values := make(map[string]any, len(record))
+	for i, v := range record {
+		values[c.header[i]] = v
+	}
+	return values, nil
+}
+
+// jsonSource reads an array of objects without decoding the whole file.
+type jsonSource struct {
+	dec *json.Decoder
+}
+
+func newJSONSource(r io.Reader) (*jsonSource, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorInvalidFile, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%w: expected an array of objects", ErrorInvalidFile)
+	}
+	return &jsonSource{dec: dec}, nil
+}
+
+func (j *jsonSource) Next() (map[string]any, error) {
+	if !j.dec.More() {
+		return nil, io.EOF
+	}
+	var values map[string]any
+	if err := j.dec.Decode(&values); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("%w: expected an object, got %s", ErrorInvalidRecord, typeErr.Value)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrorInvalidFile, err)
+	}
+	return values, nil
+}
+
+// Coerce converts a source value to the Go value bound for a column with the
+// given input type. Empty strings become NULL except for long text columns.
+func Coerce(v any, inputType string) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" && inputType != "textarea" {
+			return nil, nil
+		}
+		return coerceString(v, inputType)
+	case json.Number:
+		switch inputType {
+		case "number":
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+			return v.Float64()
+		case "checkbox":
+			return coerceString(v.String(), inputType)
+		}
+		return v.String(), nil
+	case bool:
+		switch inputType {
+		case "checkbox":
+			return v, nil
+		case "number":
+			return nil, fmt.Errorf("expected a number, got %t", v)
+		}
+		return strconv.FormatBool(v), nil
+	}
+	if inputType != "json" {
+		return nil, fmt.Errorf("unexpected %T value", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func coerceString(s, inputType string) (any, error) {
+	switch inputType {
+	case "number":
+		s = strings.TrimSpace(s)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return f, nil
+	case "checkbox":
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "t", "1", "yes", "y", "on":
+			return true, nil
+		case "false", "f", "0", "no", "n", "off":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", s)
+	case "json":
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("%q is not valid JSON", s)
+		}
+	}
+	return s, nil
+}
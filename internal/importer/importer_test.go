@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, src Source) ([]map[string]any, []error) {
+	t.Helper()
+	var records []map[string]any
+	var errs []error
+	for {
+		record, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return records, errs
+		}
+		if err != nil {
+			if !errors.Is(err, ErrorInvalidRecord) {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, record)
+	}
+}
+
+func TestCSVSource(t *testing.T) {
+	src, err := NewSource(FormatCSV, strings.NewReader("\ufeffid, name\n1,a\n2\n3,\"c, d\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, errs := readAll(t, src)
+	want := []map[string]any{{"id": "1", "name": "a"}, {"id": "3", "name": "c, d"}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %v, want %v", records, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got %d record errors, want 1", len(errs))
+	}
+
+	if _, err := NewSource(FormatCSV, strings.NewReader("")); !errors.Is(err, ErrorInvalidFile) {
+		t.Errorf("empty file: got %v, want %v", err, ErrorInvalidFile)
+	}
+}
+
+func TestJSONSource(t *testing.T) {
+	src, err := NewSource(FormatJSON, strings.NewReader(`[{"id": 1, "tags": ["a"]}, 5, {"id": 2.5}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, errs := readAll(t, src)
+	want := []map[string]any{
+		{"id": json.Number("1"), "tags": []any{"a"}},
+		{"id": json.Number("2.5")},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %v, want %v", records, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got %d record errors, want 1", len(errs))
+	}
+
+	if _, err := NewSource(FormatJSON, strings.NewReader(`{"id": 1}`)); !errors.Is(err, ErrorInvalidFile) {
+		t.Errorf("object: got %v, want %v", err, ErrorInvalidFile)
+	}
+	if _, err := NewSource("xml", strings.NewReader("")); !errors.Is(err, ErrorUnknownFormat) {
+		t.Errorf("xml: got %v, want %v", err, ErrorUnknownFormat)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     any
+		inputType string
+		want      any
+		wantErr   bool
+	}{
+		{name: "empty string is null", value: "", inputType: "text", want: nil},
+		{name: "empty textarea stays", value: "", inputType: "textarea", want: ""},
+		{name: "int", value: " 42 ", inputType: "number", want: int64(42)},
+		{name: "float", value: "4.5", inputType: "number", want: 4.5},
+		{name: "json number", value: json.Number("7"), inputType: "number", want: int64(7)},
+		{name: "not a number", value: "abc", inputType: "number", wantErr: true},
+		{name: "bool string", value: "Yes", inputType: "checkbox", want: true},
+		{name: "bool number", value: json.Number("0"), inputType: "checkbox", want: false},
+		{name: "not a bool", value: "maybe", inputType: "checkbox", wantErr: true},
+		{name: "bool as number", value: true, inputType: "number", wantErr: true},
+		{name: "bool as text", value: false, inputType: "text", want: "false"},
+		{name: "json string", value: `{"a":1}`, inputType: "json", want: `{"a":1}`},
+		{name: "invalid json", value: `{"a"`, inputType: "json", wantErr: true},
+		{name: "json object", value: map[string]any{"a": json.Number("1")}, inputType: "json", want: `{"a":1}`},
+		{name: "object as text", value: []any{"a"}, inputType: "text", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Coerce(tt.value, tt.inputType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@ package response
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/biisal/rowsql/internal/apperr"
+	dberrors "github.com/biisal/rowsql/internal/database/db-errors"
 	"github.com/biisal/rowsql/internal/logger"
 )
 
@@ -28,10 +31,23 @@ func Success(w http.ResponseWriter, status int, data any) {
 	}
 }
 
+// Error writes errMsg as JSON, permission errors are always sent as 403 and
+// database timeouts as 504.
 func Error(w http.ResponseWriter, status int, errMsg error) {
+	ErrorData(w, status, errMsg, nil)
+}
+
+// ErrorData is Error with data the client needs to recover from it.
+func ErrorData(w http.ResponseWriter, status int, errMsg error, data any) {
+	if errors.Is(errMsg, apperr.ErrorPermissionDenied) {
+		status = http.StatusForbidden
+	}
+	if dberrors.IsTimeout(errMsg) {
+		status, errMsg = dberrors.UserFriendlyError(errMsg)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	jsonData, err := json.Marshal(Response{Error: errMsg.Error()})
+	jsonData, err := json.Marshal(Response{Error: errMsg.Error(), Data: data})
 	if err != nil {
 		logger.Error("failed to marshal response: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
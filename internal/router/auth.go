@@ -0,0 +1,169 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+)
+
+const sessionCookie = "rowsql_session"
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type MeResponse struct {
+	AuthEnabled bool       `json:"authEnabled"`
+	User        *auth.User `json:"user,omitempty"`
+}
+
+// authenticate accepts a bearer API token or session, or the session cookie.
+func (h *DBHandler) authenticate(r *http.Request) (auth.User, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = strings.TrimSpace(token)
+		if user, ok := h.auth.Token(token); ok {
+			return user, true
+		}
+		return h.auth.Session(token)
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return h.auth.Session(cookie.Value)
+	}
+	return auth.User{}, false
+}
+
+// requireAuth rejects unauthenticated API requests, the user is stored in the
+// request context for the handlers.
+func (h *DBHandler) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.auth.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, ok := h.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rowsql"`)
+			resopnse.Error(w, http.StatusUnauthorized, auth.ErrorUnauthenticated)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+	})
+}
+
+// requireLogin redirects unauthenticated page loads to the login page.
+func (h *DBHandler) requireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.auth.Enabled() {
+			if _, ok := h.authenticate(r); !ok {
+				http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loginPage serves the login form, signed in users go straight to the app.
+func (h *DBHandler) loginPage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := h.authenticate(r); ok || !h.auth.Enabled() {
+			http.Redirect(w, r, redirectTarget(r.URL.Query().Get("next")), http.StatusFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// redirectTarget only allows local paths so `next` can't send users elsewhere.
+func redirectTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func loginErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrorInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrorLoginDisabled):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// Login starts a session from a JSON body or the login form. The session id is
+// set as cookie and can also be sent as bearer token.
+func (h *DBHandler) Login(w http.ResponseWriter, r *http.Request) {
+	isForm := strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	var req LoginRequest
+	if isForm {
+		req.Username, req.Password = r.FormValue("username"), r.FormValue("password")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	session, err := h.auth.Login(r.Context(), req.Username, req.Password)
+	if err != nil {
+		logger.Error("Failed login for '%s' from %s: %s", req.Username, r.RemoteAddr, err)
+		if isForm {
+			http.Redirect(w, r, "/login?error=1&next="+url.QueryEscape(r.FormValue("next")), http.StatusSeeOther)
+			return
+		}
+		resopnse.Error(w, loginErrorStatus(err), err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.ID,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	logger.Info("User '%s' logged in", session.User.Name)
+	if isForm {
+		http.Redirect(w, r, redirectTarget(r.FormValue("next")), http.StatusSeeOther)
+		return
+	}
+	resopnse.Success(w, http.StatusOK, struct {
+		auth.Session
+		Token string `json:"token"`
+	}{session, session.ID})
+}
+
+func (h *DBHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		h.auth.Logout(cookie.Value)
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		h.auth.Logout(strings.TrimSpace(token))
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	resopnse.Success(w, http.StatusOK, nil)
+}
+
+func (h *DBHandler) Me(w http.ResponseWriter, r *http.Request) {
+	res := MeResponse{AuthEnabled: h.auth.Enabled()}
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		res.User = &user
+	}
+	resopnse.Success(w, http.StatusOK, res)
+}
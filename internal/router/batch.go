@@ -0,0 +1,42 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+)
+
+type BatchRequest struct {
+	Operations []models.BatchOperation `json:"operations"`
+}
+
+// RunBatch applies row changes across tables in one transaction. A failing
+// operation rolls back the batch, which is still answered with 200 and
+// committed false so the results show where it stopped.
+func (h *DBHandler) RunBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.db(r).RunBatch(r.Context(), req.Operations)
+	if err != nil {
+		logger.Error("Failed to run batch: %s", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, repo.ErrorInvalidBatch) {
+			status = http.StatusBadRequest
+		}
+		resopnse.Error(w, status, err)
+		return
+	}
+	if result.Committed {
+		logger.Success("Batch of %d operations committed", len(result.Results))
+	}
+	resopnse.Success(w, http.StatusOK, result)
+}
@@ -0,0 +1,67 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+)
+
+// BulkRequest selects rows by their keys or a filter. It's sent once without
+// confirmation for the preview and again with the confirmation of the preview.
+type BulkRequest struct {
+	Operation    string           `json:"operation"`
+	Keys         []string         `json:"keys"`
+	Filter       *models.Filter   `json:"filter"`
+	Data         []models.RowItem `json:"data"`
+	Confirmation string           `json:"confirmation"`
+}
+
+func (h *DBHandler) BulkRows(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	var req BulkRequest
+	dec := json.NewDecoder(r.Body)
+	// filter values are numbers or strings like in parseFilter
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.db(r).BulkRows(r.Context(), models.BulkProps{
+		TableName:    tableName,
+		Operation:    req.Operation,
+		Keys:         req.Keys,
+		Filter:       req.Filter,
+		Values:       req.Data,
+		Confirmation: req.Confirmation,
+	})
+	if err != nil {
+		logger.Error("Failed to bulk %s rows of '%s': %s", req.Operation, tableName, err)
+		resopnse.Error(w, bulkErrorStatus(err), err)
+		return
+	}
+	if result.Executed {
+		logger.Success("Bulk %s changed %d rows of '%s'", req.Operation, result.RowsAffected, tableName)
+	}
+	resopnse.Success(w, http.StatusOK, result)
+}
+
+func bulkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repo.ErrorBulkConfirmation):
+		return http.StatusConflict
+	case errors.Is(err, repo.ErrorInvalidBulk), errors.Is(err, apperr.ErrorInvalidSelection),
+		errors.Is(err, apperr.ErrorNoValuesProvided), errors.Is(err, apperr.ErrorDuplicateColumn):
+		return http.StatusBadRequest
+	}
+	if status := filterErrorStatus(err); status != http.StatusInternalServerError {
+		return status
+	}
+	return rowErrorStatus(err)
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/connection"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+	"github.com/biisal/rowsql/internal/service"
+)
+
+type serviceCtxKey struct{}
+
+// withConnection resolves the `connID` path value, routes without it use the
+// default connection.
+func (h *DBHandler) withConnection(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := connectionID(r)
+		svc, err := h.connections.Service(id)
+		if err != nil {
+			logger.Error("%s", err)
+			resopnse.Error(w, http.StatusNotFound, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), serviceCtxKey{}, svc)))
+	})
+}
+
+func connectionID(r *http.Request) string {
+	if id := r.PathValue("connID"); id != "" {
+		return id
+	}
+	return connection.DefaultID
+}
+
+// db returns the service of the connection resolved by withConnection.
+func (h *DBHandler) db(r *http.Request) service.DBService {
+	return r.Context().Value(serviceCtxKey{}).(service.DBService)
+}
+
+type ConnectionRequest struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	DBString string `json:"dbString"`
+}
+
+func connectionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, connection.ErrorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, connection.ErrorExists):
+		return http.StatusConflict
+	case errors.Is(err, connection.ErrorInvalidID), errors.Is(err, connection.ErrorDeleteDefault):
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
+
+// ListConnections only lists the connections the user has any grant on.
+func (h *DBHandler) ListConnections(w http.ResponseWriter, r *http.Request) {
+	items := slices.DeleteFunc(h.connections.List(), func(info connection.Info) bool {
+		return !h.auth.Policy().CanAccess(r.Context(), info.ID, "")
+	})
+	resopnse.Success(w, http.StatusOK, items)
+}
+
+func (h *DBHandler) CreateConnection(w http.ResponseWriter, r *http.Request) {
+	var req ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.auth.Policy().Authorize(r.Context(), req.ID, "", auth.OpManage); err != nil {
+		resopnse.Error(w, http.StatusForbidden, err)
+		return
+	}
+	info, err := h.connections.Add(r.Context(), req.ID, req.Name, req.DBString)
+	if err != nil {
+		logger.Error("Failed to add connection '%s': %s", req.ID, err)
+		resopnse.Error(w, connectionErrorStatus(err), err)
+		return
+	}
+	logger.Success("Connection '%s' added", info.ID)
+	resopnse.Success(w, http.StatusCreated, info)
+}
+
+func (h *DBHandler) UpdateConnection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("connID")
+	var req ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.auth.Policy().Authorize(r.Context(), id, "", auth.OpManage); err != nil {
+		resopnse.Error(w, http.StatusForbidden, err)
+		return
+	}
+	info, err := h.connections.Update(r.Context(), id, req.Name, req.DBString)
+	if err != nil {
+		logger.Error("Failed to update connection '%s': %s", id, err)
+		resopnse.Error(w, connectionErrorStatus(err), err)
+		return
+	}
+	logger.Success("Connection '%s' updated", id)
+	resopnse.Success(w, http.StatusOK, info)
+}
+
+func (h *DBHandler) DeleteConnection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("connID")
+	if err := h.auth.Policy().Authorize(r.Context(), id, "", auth.OpManage); err != nil {
+		resopnse.Error(w, http.StatusForbidden, err)
+		return
+	}
+	if err := h.connections.Remove(id); err != nil {
+		logger.Error("Failed to remove connection '%s': %s", id, err)
+		resopnse.Error(w, connectionErrorStatus(err), err)
+		return
+	}
+	logger.Success("Connection '%s' removed", id)
+	w.WriteHeader(http.StatusNoContent)
+}
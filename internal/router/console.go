@@ -0,0 +1,57 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+	"github.com/biisal/rowsql/internal/service"
+)
+
+type RunQueryRequest struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+}
+
+func (h *DBHandler) RunQuery(w http.ResponseWriter, r *http.Request) {
+	var req RunQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.db(r).RunQuery(r.Context(), req.ID, req.Query)
+	if err != nil {
+		logger.Error("Failed to run query %s: %s", result.ID, err)
+		resopnse.Error(w, queryErrorStatus(err), err)
+		return
+	}
+	logger.Success("Query %s finished in %.2fms", result.ID, result.Duration)
+	resopnse.Success(w, http.StatusOK, result)
+}
+
+func (h *DBHandler) CancelQuery(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.db(r).CancelQuery(r.Context(), id); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusNotFound, err)
+		return
+	}
+	logger.Info("Query %s canceled", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queryErrorStatus blames the client only for its own statement, connection
+// failures and cancellations are the server's.
+func queryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrorEmptyQuery), repo.IsStatementError(err):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrorQueryIDInUse):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/export"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+	"github.com/biisal/rowsql/internal/service"
+)
+
+// exportResponse delays the download headers until the first byte is
+// written, so errors raised before streaming starts are still sent as JSON.
+type exportResponse struct {
+	w        http.ResponseWriter
+	format   export.Format
+	filename string
+	started  bool
+}
+
+func (e *exportResponse) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", e.format.ContentType())
+		e.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": e.filename + "." + string(e.format),
+		}))
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return e.w.Write(p)
+}
+
+func exportFormat(r *http.Request) export.Format {
+	format := export.Format(strings.ToLower(r.URL.Query().Get("format")))
+	if format == "" {
+		return export.FormatCSV
+	}
+	return format
+}
+
+func exportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, apperr.ErrorInvalidFilter), errors.Is(err, apperr.ErrorInvalidColumn),
+		errors.Is(err, apperr.ErrorDuplicateColumn), errors.Is(err, repo.ErrorNoResultSet), errors.Is(err, repo.ErrorNotReadOnly),
+		errors.Is(err, service.ErrorEmptyQuery):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// stream runs fn with a writer for the requested format and finishes the download.
+func stream(w http.ResponseWriter, r *http.Request, filename string, fn func(export.Writer) error) {
+	out := &exportResponse{w: w, format: exportFormat(r), filename: filename}
+	writer, err := export.NewWriter(out.format, out)
+	if err != nil {
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := fn(writer); err != nil {
+		logger.Error("Failed to export '%s': %s", filename, err)
+		// once the download started the status can't change anymore
+		if !out.started {
+			resopnse.Error(w, exportErrorStatus(err), err)
+		}
+		return
+	}
+	if err := writer.Close(); err != nil {
+		logger.Error("Failed to finish export of '%s': %s", filename, err)
+	}
+}
+
+// ExportRows streams a table as csv, json or ndjson. `columns` is a comma
+// separated column selection, `column`, `order` and `filter` work like in ListRows.
+func (h DBHandler) ExportRows(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	query := r.URL.Query()
+	filter, err := parseFilter(query.Get("filter"))
+	if err != nil {
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	props := models.ExportProps{
+		TableName: tableName,
+		Column:    strings.TrimSpace(query.Get("column")),
+		Order:     query.Get("order"),
+		Filter:    filter,
+	}
+	for col := range strings.SplitSeq(query.Get("columns"), ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			props.Columns = append(props.Columns, col)
+		}
+	}
+	stream(w, r, tableName, func(writer export.Writer) error {
+		return h.db(r).ExportRows(r.Context(), props, writer)
+	})
+}
+
+// ExportQuery streams the result of a console query.
+func (h DBHandler) ExportQuery(w http.ResponseWriter, r *http.Request) {
+	var req RunQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	stream(w, r, "query", func(writer export.Writer) error {
+		return h.db(r).ExportQuery(r.Context(), req.Query, writer)
+	})
+}
@@ -1,24 +1,32 @@
 package router
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/connection"
 	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/locator"
 	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/repo"
 	"github.com/biisal/rowsql/internal/logger"
 	resopnse "github.com/biisal/rowsql/internal/response"
-	"github.com/biisal/rowsql/internal/service"
+	"github.com/biisal/rowsql/internal/store"
 )
 
 type DBHandler struct {
-	service    service.DBService
-	itemsLimit int
+	connections *connection.Manager
+	auth        *auth.Manager
+	store       *store.Store
+	itemsLimit  int
 }
 
 type BaseHTMLData struct {
@@ -31,15 +39,18 @@ type ErrorMessage struct {
 	Message string
 }
 
-func NewHandler(service service.DBService, itemsLimit int) DBHandler {
+func NewHandler(connections *connection.Manager, authManager *auth.Manager, metadata *store.Store, itemsLimit int) DBHandler {
 	return DBHandler{
-		service,
+		connections,
+		authManager,
+		metadata,
 		itemsLimit,
 	}
 }
 
-func (h DBHandler) getBaseData(ctx context.Context, tableName ...string) (*BaseHTMLData, error) {
-	tables, err := h.service.ListTables(ctx)
+func (h DBHandler) getBaseData(r *http.Request, tableName ...string) (*BaseHTMLData, error) {
+	ctx := r.Context()
+	tables, err := h.db(r).ListTables(ctx)
 	if err != nil {
 		logger.Error("%s", err)
 		return nil, err
@@ -47,7 +58,7 @@ func (h DBHandler) getBaseData(ctx context.Context, tableName ...string) (*BaseH
 	if len(tableName) == 0 {
 		return &BaseHTMLData{Tables: tables}, nil
 	}
-	cols, err := h.service.ListCols(ctx, tableName[0])
+	cols, err := h.db(r).ListCols(ctx, tableName[0])
 	if err != nil {
 		logger.Error("%s", err)
 		return nil, err
@@ -56,7 +67,7 @@ func (h DBHandler) getBaseData(ctx context.Context, tableName ...string) (*BaseH
 }
 
 func (h DBHandler) ListColumns(w http.ResponseWriter, r *http.Request) {
-	cols, err := h.service.ListCols(r.Context(), r.PathValue("tableName"))
+	cols, err := h.db(r).ListCols(r.Context(), r.PathValue("tableName"))
 	if err != nil {
 		logger.Error("%s", err)
 		resopnse.Error(w, http.StatusInternalServerError, err)
@@ -66,15 +77,29 @@ func (h DBHandler) ListColumns(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h DBHandler) ListTables(w http.ResponseWriter, r *http.Request) {
-	tables, err := h.service.ListTables(r.Context())
+	tables, err := h.db(r).ListTables(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
+	if schema := r.URL.Query().Get("schema"); schema != "" {
+		tables = slices.DeleteFunc(tables, func(t models.ListTablesRow) bool { return t.TableSchema != schema })
+	}
 
 	logger.Info("Fetched %d tables", len(tables))
 	resopnse.Success(w, http.StatusOK, tables)
 }
 
+// RefreshSchema forgets the cached tables, columns and indexes after changes
+// made outside rowsql and lists the tables again.
+func (h DBHandler) RefreshSchema(w http.ResponseWriter, r *http.Request) {
+	h.db(r).RefreshSchema()
+	h.ListTables(w, r)
+}
+
+func (h DBHandler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	resopnse.Success(w, http.StatusOK, h.db(r).CacheStats())
+}
+
 func (h DBHandler) ListRows(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("tableName")
 	page := r.URL.Query().Get("page")
@@ -87,10 +112,17 @@ func (h DBHandler) ListRows(w http.ResponseWriter, r *http.Request) {
 	colParam := strings.TrimSpace(r.URL.Query().Get("column"))
 	order := r.URL.Query().Get("order")
 
+	filter, err := parseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		logger.Error("error: %s, table: %s", err, tableName)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+
 	colFound := false
 	if colParam != "" {
 		var cols []models.ListDataCol
-		cols, err = h.service.ListCols(r.Context(), tableName)
+		cols, err = h.db(r).ListCols(r.Context(), tableName)
 		if err != nil {
 			resopnse.Error(w, http.StatusInternalServerError, err)
 		}
@@ -107,63 +139,160 @@ func (h DBHandler) ListRows(w http.ResponseWriter, r *http.Request) {
 		}
 
 	}
-	rows, err := h.service.ListRows(r.Context(), tableName, pageInt, colParam, order)
-	if err != nil {
-		logger.Error("Failed to fetch rows from table '%s'", tableName)
-		resopnse.Error(w, http.StatusInternalServerError, err)
-		return
+	// a cursor parameter, even an empty one for the first page, asks for keyset paging
+	keyset := r.URL.Query().Has("cursor")
+	var rows models.ListDataRow
+	var nextCursor, prevCursor string
+	if keyset {
+		page, err := h.db(r).KeysetRows(r.Context(), tableName, pageInt, colParam, order, filter, r.URL.Query().Get("cursor"))
+		if err != nil {
+			logger.Error("Failed to fetch rows from table '%s'", tableName)
+			resopnse.Error(w, listErrorStatus(err), err)
+			return
+		}
+		rows, nextCursor, prevCursor, keyset = page.Rows, page.NextCursor, page.PrevCursor, page.Keyset
+	} else {
+		rows, err = h.db(r).ListRows(r.Context(), tableName, pageInt, colParam, order, filter)
+		if err != nil {
+			logger.Error("Failed to fetch rows from table '%s'", tableName)
+			resopnse.Error(w, filterErrorStatus(err), err)
+			return
+		}
 	}
 
-	cols, err := h.service.ListCols(r.Context(), tableName)
+	cols, err := h.db(r).ListCols(r.Context(), tableName)
 	if err != nil {
 		logger.Error("%s", err)
 		resopnse.Error(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	count, err := h.service.GetRowCount(r.Context(), tableName)
+	versions := make([]string, len(rows))
+	for i, row := range rows {
+		// the first value is the row key
+		if values, ok := row.([]any); ok && len(values) > 0 {
+			versions[i] = repo.RowVersion(values[1:])
+		}
+	}
+
+	if keyset {
+		// counting is what keyset paging avoids on large tables
+		logger.Debug("Loaded keyset page for table '%s'", tableName)
+		resopnse.Success(w, http.StatusOK, ListRowsResponse{
+			Rows:        rows,
+			Cols:        cols,
+			ActiveTable: tableName,
+			HasNextPage: nextCursor != "",
+			Versions:    versions,
+			NextCursor:  nextCursor,
+			PrevCursor:  prevCursor,
+		})
+		return
+	}
+
+	exact := r.URL.Query().Get("exactCount") == "true"
+	count, err := h.db(r).CountRows(r.Context(), tableName, filter, exact)
 	if err != nil {
 		logger.Error("%s", err)
-		resopnse.Error(w, http.StatusInternalServerError, err)
+		resopnse.Error(w, filterErrorStatus(err), err)
 		return
 	}
+	hasNextPage := h.db(r).HasNextPage(r.Context(), count.Count, pageInt)
+	if count.Estimated {
+		// an estimate can't tell where the last page ends
+		hasNextPage = len(rows) == h.itemsLimit
+	}
 
 	logger.Debug("Loaded page %d for table '%s'", pageInt, tableName)
 	resopnse.Success(w, http.StatusOK,
 		ListRowsResponse{
-			Page:        pageInt,
-			Rows:        rows,
-			Cols:        cols,
-			RowCount:    count,
-			ActiveTable: tableName,
-			HasNextPage: h.service.HasNextPage(r.Context(), count, pageInt),
-			TotalPages:  count / h.itemsLimit,
+			Page:              pageInt,
+			Rows:              rows,
+			Cols:              cols,
+			RowCount:          count.Count,
+			RowCountEstimated: count.Estimated,
+			ActiveTable:       tableName,
+			HasNextPage:       hasNextPage,
+			TotalPages:        count.Count / h.itemsLimit,
+			Versions:          versions,
 		},
 	)
 }
 
+func listErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, locator.ErrorInvalidCursor):
+		return http.StatusBadRequest
+	case errors.Is(err, locator.ErrorStaleKey):
+		return http.StatusConflict
+	}
+	return filterErrorStatus(err)
+}
+
+// parseFilter decodes the JSON filter query parameter, numbers are kept as
+// json.Number so integers don't lose precision.
+func parseFilter(raw string) (*models.Filter, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var filter models.Filter
+	if err := dec.Decode(&filter); err != nil {
+		return nil, fmt.Errorf("%w: %s", apperr.ErrorInvalidFilter, err)
+	}
+	return &filter, nil
+}
+
+func filterErrorStatus(err error) int {
+	if errors.Is(err, apperr.ErrorInvalidFilter) || errors.Is(err, apperr.ErrorInvalidColumn) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func rowErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, locator.ErrorInvalidKey):
+		return http.StatusBadRequest
+	case errors.Is(err, locator.ErrorStaleKey), errors.Is(err, repo.ErrorRowConflict):
+		return http.StatusConflict
+	case errors.Is(err, repo.ErrorNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// rowError writes err of a row change, conflicts carry the current row.
+func rowError(w http.ResponseWriter, err error) {
+	var conflict *repo.RowConflictError
+	if errors.As(err, &conflict) {
+		resopnse.ErrorData(w, http.StatusConflict, err, RowConflictResponse{Current: conflict.Current, Version: conflict.Version})
+		return
+	}
+	resopnse.Error(w, rowErrorStatus(err), err)
+}
+
 func (h DBHandler) RowInsertForm(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("tableName")
 
 	action := "Insert"
-	hash := strings.TrimSpace(r.URL.Query().Get("hash"))
-	page := r.URL.Query().Get("page")
-	pageInt, err := strconv.Atoi(page)
-	if err != nil {
-		pageInt = 1
-	}
+	// the row key is still sent as `hash` to keep the frontend routes stable
+	rowKey := strings.TrimSpace(r.URL.Query().Get("hash"))
 	var initialRow []any
-	if hash != "" {
+	if rowKey != "" {
 		action = "Update"
-		initialRow, err = h.service.GetRow(r.Context(), tableName, hash, pageInt)
+		var err error
+		initialRow, err = h.db(r).GetRow(r.Context(), tableName, rowKey)
 		if err != nil {
 			logger.Error("%s", err)
-			resopnse.Error(w, http.StatusInternalServerError, err)
+			resopnse.Error(w, rowErrorStatus(err), err)
 			return
 		}
 	}
 
-	basseData, err := h.getBaseData(r.Context(), tableName)
+	basseData, err := h.getBaseData(r, tableName)
 	if err != nil {
 		logger.Error("%s", err)
 		resopnse.Error(w, http.StatusInternalServerError, err)
@@ -174,11 +303,17 @@ func (h DBHandler) RowInsertForm(w http.ResponseWriter, r *http.Request) {
 			basseData.Cols[i].Value = initialRow[i]
 		}
 	}
+	var version string
+	if initialRow != nil {
+		version = repo.RowVersion(initialRow)
+	}
 	data := struct {
-		Action string
+		Action  string
+		Version string
 		BaseHTMLData
 	}{
 		action,
+		version,
 		*basseData,
 	}
 	resopnse.Success(w, http.StatusOK, data)
@@ -196,26 +331,21 @@ func (h DBHandler) InsertOrUpdateRow(w http.ResponseWriter, r *http.Request) {
 	}
 	logger.Info("Request data: %+v", form)
 
-	page := r.URL.Query().Get("page")
-	pageInt, err := strconv.Atoi(page)
-	if err != nil {
-		pageInt = 1
-	}
-
-	hash := strings.TrimSpace(r.URL.Query().Get("hash"))
-	if hash != "" {
-		if err := h.service.UpdateRow(ctx, form.Data, tableName, hash, pageInt); err != nil {
+	rowKey := strings.TrimSpace(r.URL.Query().Get("hash"))
+	if rowKey != "" {
+		affected, err := h.db(r).UpdateRow(ctx, form.Data, tableName, rowKey, form.Version)
+		if err != nil {
 			logger.Error("%s", err)
 			logger.Error("Failed to update row in table '%s'", tableName)
-			resopnse.Error(w, http.StatusInternalServerError, err)
+			rowError(w, err)
 			return
 		}
 		logger.Success("Row updated successfully in table '%s'", tableName)
-		w.WriteHeader(http.StatusOK)
+		resopnse.Success(w, http.StatusOK, RowChangeResponse{RowsAffected: affected})
 		return
 	}
 
-	if err := h.service.InsertRow(ctx, models.InsertDataProps{
+	if err := h.db(r).InsertRow(ctx, models.InsertDataProps{
 		TableName: tableName,
 		Values:    form.Data,
 	}); err != nil {
@@ -230,24 +360,60 @@ func (h DBHandler) InsertOrUpdateRow(w http.ResponseWriter, r *http.Request) {
 
 func (h DBHandler) DeleteRow(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("tableName")
-	hash := r.PathValue("hash")
-	page := r.URL.Query().Get("page")
-	pageInt, err := strconv.Atoi(page)
+	rowKey := r.PathValue("hash")
+	version := strings.TrimSpace(r.URL.Query().Get("version"))
+	affected, err := h.db(r).DeleteRow(r.Context(), tableName, rowKey, version)
 	if err != nil {
-		pageInt = 1
-	}
-	if err := h.service.DeleteRow(r.Context(), tableName, hash, pageInt); err != nil {
 		logger.Error("%s", err)
 		logger.Error("Failed to delete row from table '%s'", tableName)
-		resopnse.Error(w, http.StatusInternalServerError, err)
+		rowError(w, err)
 		return
 	}
 	logger.Success("Row deleted successfully from table '%s'", tableName)
-	resopnse.Success(w, http.StatusOK, nil)
+	resopnse.Success(w, http.StatusOK, RowChangeResponse{RowsAffected: affected})
+}
+
+func (h DBHandler) RowRelations(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	rowKey := r.PathValue("hash")
+	relations, err := h.db(r).GetRowRelations(r.Context(), tableName, rowKey)
+	if err != nil {
+		logger.Error("%s", err)
+		logger.Error("Failed to get relations of row in table '%s'", tableName)
+		resopnse.Error(w, rowErrorStatus(err), err)
+		return
+	}
+	resopnse.Success(w, http.StatusOK, relations)
+}
+
+// LookupReference lists the values a foreign key column can take, searchable
+// with `search` and paged with `page`. `label` overrides the label column.
+func (h DBHandler) LookupReference(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	column := r.PathValue("column")
+	query := r.URL.Query()
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil {
+		page = 1
+	}
+	page = max(page, 1)
+
+	result, err := h.db(r).LookupReference(r.Context(), tableName, column,
+		strings.TrimSpace(query.Get("label")), query.Get("search"), page)
+	if err != nil {
+		logger.Error("Failed to look up values for '%s.%s': %s", tableName, column, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, apperr.ErrorInvalidColumn) || errors.Is(err, repo.ErrorNoReference) {
+			status = http.StatusBadRequest
+		}
+		resopnse.Error(w, status, err)
+		return
+	}
+	resopnse.Success(w, http.StatusOK, result)
 }
 
 func (h DBHandler) NewTableFormFileds(w http.ResponseWriter, r *http.Request) {
-	fields := h.service.GetTableFormDataTypes()
+	fields := h.db(r).GetTableFormDataTypes()
 	if fields == nil {
 		resopnse.Error(w, http.StatusInternalServerError, fmt.Errorf("no data found"))
 		return
@@ -267,7 +433,7 @@ func (h DBHandler) CreeteNewTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	logger.Info("Request data: %+v", req)
-	if err := h.service.CreateTable(r.Context(), req.TableName, req.Inputs); err != nil {
+	if err := h.db(r).CreateTable(r.Context(), req.TableName, req.Inputs); err != nil {
 		logger.Error("%s", err)
 		logger.Error("Failed to create table '%s'", req.TableName)
 		resopnse.Error(w, http.StatusInternalServerError, err)
@@ -277,6 +443,34 @@ func (h DBHandler) CreeteNewTable(w http.ResponseWriter, r *http.Request) {
 	resopnse.Success(w, http.StatusCreated, nil)
 }
 
+type AlterTableRequest struct {
+	Operations []database.AlterOp `json:"operations"`
+}
+
+func (h *DBHandler) AlterTable(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	var req AlterTableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	logger.Info("Request data: %+v", req)
+	if err := h.db(r).AlterTable(r.Context(), tableName, req.Operations); err != nil {
+		logger.Error("%s", err)
+		logger.Error("Failed to alter table '%s'", tableName)
+		status := http.StatusInternalServerError
+		if errors.Is(err, apperr.ErrorInvalidAlter) || errors.Is(err, apperr.ErrorInvalidColumn) ||
+			errors.Is(err, apperr.ErrorInvalidDataType) {
+			status = http.StatusBadRequest
+		}
+		resopnse.Error(w, status, err)
+		return
+	}
+	logger.Success("Table '%s' altered with %d operations", tableName, len(req.Operations))
+	resopnse.Success(w, http.StatusOK, nil)
+}
+
 type DeleteTableRequest struct {
 	TableName         string `json:"tableName"`
 	VerificationQuiry string `json:"verificationQuery"`
@@ -289,7 +483,7 @@ func (h *DBHandler) DeleteTable(w http.ResponseWriter, r *http.Request) {
 		resopnse.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	err := h.service.DeleteTable(r.Context(), req.TableName, req.VerificationQuiry)
+	err := h.db(r).DeleteTable(r.Context(), req.TableName, req.VerificationQuiry)
 	if err != nil {
 		logger.Error("%s", err)
 		logger.Error("Failed to delete table '%s'", req.TableName)
@@ -307,12 +501,21 @@ func (h *DBHandler) ListHistory(w http.ResponseWriter, r *http.Request) {
 		pageInt = 1
 	}
 	pageInt = max(pageInt, 1)
+	filter, err := historyFilter(r)
+	if err != nil {
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
 
-	history, err := h.service.ListHistory(r.Context(), pageInt)
+	history, err := h.db(r).ListHistory(r.Context(), filter, pageInt)
 	if err != nil {
 		logger.Error("Failed to list history: %v", err)
 		logger.Error("Failed to fetch query history")
-		resopnse.Error(w, http.StatusInternalServerError, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, repo.ErrorLegacyHistory) {
+			status = http.StatusBadRequest
+		}
+		resopnse.Error(w, status, err)
 		return
 	}
 
@@ -320,8 +523,32 @@ func (h *DBHandler) ListHistory(w http.ResponseWriter, r *http.Request) {
 	resopnse.Success(w, http.StatusOK, history)
 }
 
+var ErrorInvalidHistoryTime = errors.New("from and to must be RFC 3339 times")
+
+// historyFilter reads the table, operation, user, from and to query parameters.
+func historyFilter(r *http.Request) (models.HistoryFilter, error) {
+	query := r.URL.Query()
+	filter := models.HistoryFilter{
+		TableName: query.Get("table"),
+		Operation: query.Get("operation"),
+		User:      query.Get("user"),
+	}
+	var err error
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, fmt.Errorf("%w: %s", ErrorInvalidHistoryTime, err)
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, fmt.Errorf("%w: %s", ErrorInvalidHistoryTime, err)
+		}
+	}
+	return filter, nil
+}
+
 func (h *DBHandler) ListRecentHistory(w http.ResponseWriter, r *http.Request) {
-	history, err := h.service.ListHistory(r.Context(), 1)
+	history, err := h.db(r).ListHistory(r.Context(), models.HistoryFilter{}, 1)
 	if err != nil {
 		logger.Error("Failed to list recent history: %v", err)
 		resopnse.Error(w, http.StatusInternalServerError, err)
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/importer"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+)
+
+// maxImportSize limits the uploaded file, parts above 32MB are buffered on disk.
+const maxImportSize = 256 << 20
+
+func importErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, importer.ErrorUnknownFormat), errors.Is(err, importer.ErrorInvalidFile),
+		errors.Is(err, apperr.ErrorInvalidColumn), errors.Is(err, apperr.ErrorDuplicateColumn):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// ImportRows inserts the records of an uploaded csv or json `file`. `mapping`
+// is a JSON object of source field to column, `format` defaults to the file
+// extension and with `dryRun=true` nothing is committed.
+func (h DBHandler) ImportRows(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		resopnse.Error(w, http.StatusBadRequest, fmt.Errorf("%w: %s", importer.ErrorInvalidFile, err))
+		return
+	}
+	defer file.Close()
+
+	format := importer.Format(strings.ToLower(r.FormValue("format")))
+	if format == "" {
+		format = importer.FormatFromFilename(header.Filename)
+	}
+	props := repo.ImportProps{
+		TableName: tableName,
+		DryRun:    r.FormValue("dryRun") == "true",
+	}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &props.Mapping); err != nil {
+			resopnse.Error(w, http.StatusBadRequest, fmt.Errorf("invalid mapping: %w", err))
+			return
+		}
+	}
+
+	src, err := importer.NewSource(format, file)
+	if err != nil {
+		resopnse.Error(w, importErrorStatus(err), err)
+		return
+	}
+	result, err := h.db(r).ImportRows(r.Context(), props, src)
+	if err != nil {
+		logger.Error("Failed to import into '%s': %s", tableName, err)
+		resopnse.Error(w, importErrorStatus(err), err)
+		return
+	}
+	if result.Committed {
+		logger.Success("Imported %d rows into '%s'", result.Inserted, tableName)
+	}
+	resopnse.Success(w, http.StatusOK, result)
+}
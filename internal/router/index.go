@@ -0,0 +1,83 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/biisal/rowsql/internal/apperr"
+	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+)
+
+type IndexQueryResponse struct {
+	Query   string `json:"query"`
+	Preview bool   `json:"preview"`
+}
+
+func indexErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, apperr.ErrorInvalidIndex), errors.Is(err, apperr.ErrorInvalidColumn),
+		errors.Is(err, apperr.ErrorDuplicateColumn), errors.Is(err, repo.ErrorDropPrimaryKey):
+		return http.StatusBadRequest
+	case errors.Is(err, repo.ErrorIndexNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *DBHandler) ListIndexes(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	indexes, err := h.db(r).ListIndexes(r.Context(), tableName)
+	if err != nil {
+		logger.Error("Failed to list indexes of '%s': %s", tableName, err)
+		resopnse.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+	resopnse.Success(w, http.StatusOK, indexes)
+}
+
+// CreateIndex creates an index, with ?preview=true only the statement is returned.
+func (h *DBHandler) CreateIndex(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	preview := r.URL.Query().Get("preview") == "true"
+	var req database.IndexInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	query, err := h.db(r).CreateIndex(r.Context(), tableName, req, preview)
+	if err != nil {
+		logger.Error("Failed to create index on '%s': %s", tableName, err)
+		resopnse.Error(w, indexErrorStatus(err), err)
+		return
+	}
+	if preview {
+		resopnse.Success(w, http.StatusOK, IndexQueryResponse{Query: query, Preview: true})
+		return
+	}
+	logger.Success("Index created on '%s'", tableName)
+	resopnse.Success(w, http.StatusCreated, IndexQueryResponse{Query: query})
+}
+
+// DropIndex drops an index, with ?preview=true only the statement is returned.
+func (h *DBHandler) DropIndex(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("tableName")
+	indexName := r.PathValue("indexName")
+	preview := r.URL.Query().Get("preview") == "true"
+	query, err := h.db(r).DropIndex(r.Context(), tableName, indexName, preview)
+	if err != nil {
+		logger.Error("Failed to drop index '%s' on '%s': %s", indexName, tableName, err)
+		resopnse.Error(w, indexErrorStatus(err), err)
+		return
+	}
+	if preview {
+		resopnse.Success(w, http.StatusOK, IndexQueryResponse{Query: query, Preview: true})
+		return
+	}
+	logger.Success("Index '%s' dropped on '%s'", indexName, tableName)
+	resopnse.Success(w, http.StatusOK, IndexQueryResponse{Query: query})
+}
@@ -1,8 +1,13 @@
 package router
 
 import (
+	"errors"
+	"net"
 	"net/http"
+	"time"
 
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/database/repo"
 	"github.com/biisal/rowsql/internal/logger"
 	"github.com/biisal/rowsql/internal/response"
 )
@@ -27,6 +32,47 @@ func CORS() func(next http.Handler) http.Handler {
 	}
 }
 
+var ErrorReadOnly = errors.New("rowsql is running in read-only mode")
+
+func rejectReadOnly(w http.ResponseWriter, r *http.Request) {
+	response.Error(w, http.StatusForbidden, ErrorReadOnly)
+}
+
+var ErrorConnectionChanges = errors.New("changing connections needs a login or ALLOW_RUNTIME_CONNECTIONS=true")
+
+func rejectConnectionChanges(w http.ResponseWriter, r *http.Request) {
+	response.Error(w, http.StatusForbidden, ErrorConnectionChanges)
+}
+
+// withoutDeadlines lifts the server's read and write timeouts for uploads and
+// downloads, which take as long as the file is big.
+func withoutDeadlines(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			logger.Debug("Failed to lift the read deadline: %s", err)
+		}
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			logger.Debug("Failed to lift the write deadline: %s", err)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withActor passes the user and address of the request on to the history.
+func withActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := repo.Actor{ClientIP: r.RemoteAddr}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			actor.ClientIP = host
+		}
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			actor.User = user.Name
+		}
+		next.ServeHTTP(w, r.WithContext(repo.WithActor(r.Context(), actor)))
+	})
+}
+
 func (h *DBHandler) withTable(handlerFunc http.HandlerFunc) http.Handler {
 	return h.middlewareCheckTableExists(http.HandlerFunc(handlerFunc))
 }
@@ -34,7 +80,7 @@ func (h *DBHandler) withTable(handlerFunc http.HandlerFunc) http.Handler {
 func (h *DBHandler) middlewareCheckTableExists(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tableName := r.PathValue("tableName")
-		if err := h.service.CheckTableExits(r.Context(), tableName); err != nil {
+		if err := h.db(r).CheckTableExits(r.Context(), tableName); err != nil {
 			logger.Error("%s", err)
 			response.Error(w, http.StatusNotFound, err)
 			return
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+)
+
+// preferencesUser scopes preferences to the signed in user, everyone shares
+// them when authentication is disabled.
+func preferencesUser(r *http.Request) string {
+	user, _ := auth.UserFromContext(r.Context())
+	return user.Name
+}
+
+func (h *DBHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	prefs, err := h.store.Preferences(r.Context(), preferencesUser(r))
+	if err != nil {
+		logger.Error("Failed to load preferences: %s", err)
+		resopnse.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+	resopnse.Success(w, http.StatusOK, prefs)
+}
+
+// UpdatePreferences merges the JSON object into the stored preferences, keys
+// set to null are removed.
+func (h *DBHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	user := preferencesUser(r)
+	if err := h.store.SetPreferences(r.Context(), user, req); err != nil {
+		logger.Error("Failed to save preferences: %s", err)
+		resopnse.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.GetPreferences(w, r)
+}
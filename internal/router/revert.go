@@ -0,0 +1,44 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+)
+
+// RevertHistory undoes a history entry, with ?preview=true only the statement
+// is returned.
+func (h *DBHandler) RevertHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		resopnse.Error(w, http.StatusNotFound, repo.ErrorHistoryNotFound)
+		return
+	}
+	preview := r.URL.Query().Get("preview") == "true"
+	result, err := h.db(r).RevertHistory(r.Context(), id, preview)
+	if err != nil {
+		logger.Error("Failed to revert history entry %d: %s", id, err)
+		resopnse.Error(w, revertErrorStatus(err), err)
+		return
+	}
+	if !preview {
+		logger.Success("Reverted history entry %d", id)
+	}
+	resopnse.Success(w, http.StatusOK, result)
+}
+
+func revertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repo.ErrorHistoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repo.ErrorAlreadyReverted), errors.Is(err, repo.ErrorRevertConflict):
+		return http.StatusConflict
+	case errors.Is(err, repo.ErrorNotRevertible), errors.Is(err, repo.ErrorLegacyHistory):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
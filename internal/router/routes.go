@@ -14,25 +14,86 @@ func route(method methodType, path string) string {
 	return fmt.Sprintf("%s %s%s", method, apiPrefix, path)
 }
 
-func MountRouter(handler DBHandler) (*http.ServeMux, error) {
+// MountRouter registers every route, with readOnly the routes that write
+// answer with ErrorReadOnly. Without manageConnections the routes changing
+// connections answer with ErrorConnectionChanges, a dbString can point the
+// server at any file or host it reaches.
+func MountRouter(handler DBHandler, readOnly, manageConnections bool) (*http.ServeMux, error) {
 	mux := http.NewServeMux()
 
-	mux.Handle("GET /", frontend.ReactHandler("/"))
+	mux.Handle("GET /", handler.requireLogin(frontend.ReactHandler("/")))
+	mux.Handle("GET /login", handler.loginPage(frontend.LoginHandler()))
 
-	mux.HandleFunc(route(GET, "/tables"), handler.ListTables)
-	mux.Handle(route(GET, "/tables/{tableName}"), handler.withTable(handler.ListRows))
-	mux.Handle(route(GET, "/tables/{tableName}/form"), handler.withTable(handler.RowInsertForm))
-	mux.Handle(route(GET, "/tables/{tableName}/columns"), handler.withTable(handler.ListColumns))
-	mux.Handle(route(POST, "/tables/{tableName}/form"), handler.withTable(handler.InsertOrUpdateRow))
-	mux.Handle(route(DELETE, "/tables/{tableName}/row/{hash}"), handler.withTable(handler.DeleteRow))
+	mux.HandleFunc(route(POST, "/auth/login"), handler.Login)
+	mux.HandleFunc(route(POST, "/auth/logout"), handler.Logout)
+	mux.Handle(route(GET, "/auth/me"), handler.requireAuth(http.HandlerFunc(handler.Me)))
 
-	mux.HandleFunc(route(GET, "/tables/form/new"), handler.NewTableFormFileds)
-	mux.HandleFunc(route(POST, "/tables/form/new"), handler.CreeteNewTable)
-	mux.HandleFunc(route(DELETE, "/tables"), handler.DeleteTable)
-	mux.HandleFunc(route(GET, "/history"), handler.ListHistory)
-	mux.HandleFunc(route(GET, "/history/recent"), handler.ListRecentHistory)
+	mux.Handle(route(GET, "/preferences"), handler.requireAuth(http.HandlerFunc(handler.GetPreferences)))
+	mux.Handle(route(PUT, "/preferences"), handler.requireAuth(http.HandlerFunc(handler.UpdatePreferences)))
+
+	mux.Handle(route(GET, "/connections"), handler.requireAuth(http.HandlerFunc(handler.ListConnections)))
+	manage := func(method methodType, path string, h http.HandlerFunc) {
+		if !manageConnections {
+			h = rejectConnectionChanges
+		}
+		mux.Handle(route(method, path), handler.requireAuth(h))
+	}
+	manage(POST, "/connections", handler.CreateConnection)
+	manage(PUT, "/connections/{connID}", handler.UpdateConnection)
+	manage(DELETE, "/connections/{connID}", handler.DeleteConnection)
+
+	// database routes are scoped to a connection, the unscoped ones use the default connection
+	mountDatabaseRoutes(mux, handler, "/connections/{connID}", readOnly)
+	mountDatabaseRoutes(mux, handler, "", readOnly)
 
 	// fs := http.FileServer(http.Dir("frontend/static"))
 	// mux.Handle("GET /static/", http.StripPrefix("/static/", fs))
 	return mux, nil
 }
+
+func mountDatabaseRoutes(mux *http.ServeMux, handler DBHandler, prefix string, readOnly bool) {
+	handle := func(method methodType, path string, h http.Handler) {
+		mux.Handle(route(method, prefix+path), handler.requireAuth(withActor(handler.withConnection(h))))
+	}
+	write := func(method methodType, path string, h http.Handler) {
+		if readOnly {
+			h = http.HandlerFunc(rejectReadOnly)
+		}
+		handle(method, path, h)
+	}
+
+	handle(GET, "/tables", http.HandlerFunc(handler.ListTables))
+	handle(POST, "/schema/refresh", http.HandlerFunc(handler.RefreshSchema))
+	handle(GET, "/cache/stats", http.HandlerFunc(handler.CacheStats))
+	handle(GET, "/tables/{tableName}", handler.withTable(handler.ListRows))
+	handle(GET, "/tables/{tableName}/form", handler.withTable(handler.RowInsertForm))
+	handle(GET, "/tables/{tableName}/columns", handler.withTable(handler.ListColumns))
+	handle(GET, "/tables/{tableName}/export", withoutDeadlines(handler.withTable(handler.ExportRows)))
+	write(POST, "/tables/{tableName}/import", withoutDeadlines(handler.withTable(handler.ImportRows)))
+	handle(GET, "/tables/{tableName}/columns/{column}/lookup", handler.withTable(handler.LookupReference))
+	write(POST, "/tables/{tableName}/form", handler.withTable(handler.InsertOrUpdateRow))
+	write(DELETE, "/tables/{tableName}/row/{hash}", handler.withTable(handler.DeleteRow))
+	write(POST, "/tables/{tableName}/bulk", handler.withTable(handler.BulkRows))
+	handle(GET, "/tables/{tableName}/row/{hash}/relations", handler.withTable(handler.RowRelations))
+	write(POST, "/tables/{tableName}/alter", handler.withTable(handler.AlterTable))
+	handle(GET, "/tables/{tableName}/indexes", handler.withTable(handler.ListIndexes))
+	write(POST, "/tables/{tableName}/indexes", handler.withTable(handler.CreateIndex))
+	write(DELETE, "/tables/{tableName}/indexes/{indexName}", handler.withTable(handler.DropIndex))
+
+	handle(GET, "/tables/form/new", http.HandlerFunc(handler.NewTableFormFileds))
+	write(POST, "/tables/form/new", http.HandlerFunc(handler.CreeteNewTable))
+	write(DELETE, "/tables", http.HandlerFunc(handler.DeleteTable))
+	write(POST, "/batch", http.HandlerFunc(handler.RunBatch))
+	handle(GET, "/history", http.HandlerFunc(handler.ListHistory))
+	handle(GET, "/history/recent", http.HandlerFunc(handler.ListRecentHistory))
+	write(POST, "/history/{id}/revert", http.HandlerFunc(handler.RevertHistory))
+
+	handle(GET, "/saved-queries", handler.withSavedQueries(handler.ListSavedQueries))
+	handle(POST, "/saved-queries", handler.withSavedQueries(handler.CreateSavedQuery))
+	handle(PUT, "/saved-queries/{id}", handler.withSavedQueries(handler.UpdateSavedQuery))
+	handle(DELETE, "/saved-queries/{id}", handler.withSavedQueries(handler.DeleteSavedQuery))
+
+	handle(POST, "/query", http.HandlerFunc(handler.RunQuery))
+	handle(POST, "/query/export", withoutDeadlines(http.HandlerFunc(handler.ExportQuery)))
+	handle(DELETE, "/query/{id}", http.HandlerFunc(handler.CancelQuery))
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+	resopnse "github.com/biisal/rowsql/internal/response"
+	"github.com/biisal/rowsql/internal/store"
+)
+
+func savedQueryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrorSavedQueryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrorSavedQueryExists):
+		return http.StatusConflict
+	case errors.Is(err, store.ErrorInvalidSavedQuery):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// withSavedQueries only lets users who may run raw SQL on the connection
+// manage its saved queries.
+func (h *DBHandler) withSavedQueries(handlerFunc http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.auth.Policy().Authorize(r.Context(), connectionID(r), "", auth.OpSQL); err != nil {
+			resopnse.Error(w, http.StatusForbidden, err)
+			return
+		}
+		handlerFunc(w, r)
+	})
+}
+
+func (h *DBHandler) ListSavedQueries(w http.ResponseWriter, r *http.Request) {
+	items, err := h.store.ListSavedQueries(r.Context(), connectionID(r))
+	if err != nil {
+		logger.Error("Failed to list saved queries: %s", err)
+		resopnse.Error(w, http.StatusInternalServerError, err)
+		return
+	}
+	resopnse.Success(w, http.StatusOK, items)
+}
+
+func (h *DBHandler) CreateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	var req models.SavedQuery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	saved, err := h.store.SaveQuery(r.Context(), connectionID(r), req)
+	if err != nil {
+		logger.Error("Failed to save query '%s': %s", req.Name, err)
+		resopnse.Error(w, savedQueryErrorStatus(err), err)
+		return
+	}
+	resopnse.Success(w, http.StatusCreated, saved)
+}
+
+func (h *DBHandler) UpdateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		resopnse.Error(w, http.StatusNotFound, store.ErrorSavedQueryNotFound)
+		return
+	}
+	var req models.SavedQuery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("%s", err)
+		resopnse.Error(w, http.StatusBadRequest, err)
+		return
+	}
+	req.ID = id
+	saved, err := h.store.UpdateSavedQuery(r.Context(), connectionID(r), req)
+	if err != nil {
+		logger.Error("Failed to update saved query %d: %s", id, err)
+		resopnse.Error(w, savedQueryErrorStatus(err), err)
+		return
+	}
+	resopnse.Success(w, http.StatusOK, saved)
+}
+
+func (h *DBHandler) DeleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		resopnse.Error(w, http.StatusNotFound, store.ErrorSavedQueryNotFound)
+		return
+	}
+	if err := h.store.DeleteSavedQuery(r.Context(), connectionID(r), id); err != nil {
+		logger.Error("Failed to delete saved query %d: %s", id, err)
+		resopnse.Error(w, savedQueryErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -21,4 +21,25 @@ type ListRowsResponse struct {
 	ActiveTable string               `json:"activeTable"`
 	HasNextPage bool                 `json:"hasNextPage"`
 	TotalPages  int                  `json:"totalPages"`
+	// RowCountEstimated is set when RowCount and TotalPages come from the
+	// catalog of a large table, `?exactCount=true` counts it instead
+	RowCountEstimated bool `json:"rowCountEstimated"`
+	// Versions holds the version of every row, send it back with a change to
+	// have it refused when the row changed since
+	Versions []string `json:"versions"`
+	// NextCursor and PrevCursor are set with keyset paging, which leaves out
+	// the page numbers and the row count
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+type RowChangeResponse struct {
+	RowsAffected int64 `json:"rowsAffected"`
+}
+
+// RowConflictResponse is sent with a 409 when a row changed since it was
+// loaded, Current is null when it was deleted.
+type RowConflictResponse struct {
+	Current map[string]any `json:"current"`
+	Version string         `json:"version,omitempty"`
 }
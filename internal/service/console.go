@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/database/models"
+)
+
+var (
+	ErrorEmptyQuery       = errors.New("query cannot be empty")
+	ErrorQueryNotRunning  = errors.New("no running query with this id")
+	ErrorQueryIDInUse     = errors.New("a query with this id is already running")
+	ErrorQueryWasCanceled = errors.New("query was canceled")
+)
+
+// runningQueries keeps the cancel functions of console queries in flight so
+// they can be stopped from another request of the same user.
+type runningQueries struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newRunningQueries() *runningQueries {
+	return &runningQueries{cancels: make(map[string]context.CancelFunc)}
+}
+
+func (r *runningQueries) add(id string, cancel context.CancelFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cancels[id]; ok {
+		return ErrorQueryIDInUse
+	}
+	r.cancels[id] = cancel
+	return nil
+}
+
+func (r *runningQueries) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cancels, id)
+}
+
+func (r *runningQueries) cancel(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.cancels[id]
+	if ok {
+		cancel()
+		delete(r.cancels, id)
+	}
+	return ok
+}
+
+// queryKey scopes a client chosen query id to the user running it, nobody can
+// cancel, or find out about, the queries of someone else.
+func queryKey(ctx context.Context, id string) string {
+	user, _ := auth.UserFromContext(ctx)
+	return user.Name + "\x00" + id
+}
+
+func newQueryID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *svc) RunQuery(ctx context.Context, id, query string) (models.QueryResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return models.QueryResult{}, ErrorEmptyQuery
+	}
+	if id == "" {
+		id = newQueryID()
+	}
+
+	key := queryKey(ctx, id)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := s.running.add(key, cancel); err != nil {
+		return models.QueryResult{ID: id}, err
+	}
+	defer s.running.remove(key)
+
+	result, err := s.repo.RunQuery(ctx, query)
+	result.ID = id
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return result, ErrorQueryWasCanceled
+	}
+	return result, err
+}
+
+func (s *svc) CancelQuery(ctx context.Context, id string) error {
+	if !s.running.cancel(queryKey(ctx, id)) {
+		return ErrorQueryNotRunning
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/biisal/rowsql/internal/auth"
+	"github.com/biisal/rowsql/internal/database"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/queries"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/importer"
+)
+
+// guard checks the grants of the request user before calling the wrapped
+// service. Every method is spelled out so new ones can't skip the check.
+type guard struct {
+	next    DBService
+	connID  string
+	builder *queries.Builder
+	policy  *auth.Policy
+}
+
+// WithPolicy enforces policy on every call to next, a nil policy disables the
+// checks. builder qualifies the names tables are renamed to.
+func WithPolicy(next DBService, connID string, builder *queries.Builder, policy *auth.Policy) DBService {
+	if policy == nil {
+		return next
+	}
+	return &guard{next: next, connID: connID, builder: builder, policy: policy}
+}
+
+func (g *guard) authorize(ctx context.Context, tableName string, op auth.Operation) error {
+	return g.policy.Authorize(ctx, g.connID, tableName, op)
+}
+
+func (g *guard) CheckTableExits(ctx context.Context, tableName string) error {
+	if !g.policy.CanAccess(ctx, g.connID, tableName) {
+		return g.authorize(ctx, tableName, auth.OpRead)
+	}
+	return g.next.CheckTableExits(ctx, tableName)
+}
+
+// ListTables only returns the tables the user has any grant on.
+func (g *guard) ListTables(ctx context.Context) ([]models.ListTablesRow, error) {
+	tables, err := g.next.ListTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tables, func(t models.ListTablesRow) bool {
+		return !g.policy.CanAccess(ctx, g.connID, t.Name)
+	}), nil
+}
+
+func (g *guard) ListCols(ctx context.Context, tableName string) ([]models.ListDataCol, error) {
+	if !g.policy.CanAccess(ctx, g.connID, tableName) {
+		return nil, g.authorize(ctx, tableName, auth.OpRead)
+	}
+	return g.next.ListCols(ctx, tableName)
+}
+
+func (g *guard) ListRows(ctx context.Context, tableName string, page int, column string, order string, filter *models.Filter) (models.ListDataRow, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return models.ListDataRow{}, err
+	}
+	return g.next.ListRows(ctx, tableName, page, column, order, filter)
+}
+
+func (g *guard) KeysetRows(ctx context.Context, tableName string, page int, column, order string, filter *models.Filter, cursor string) (models.RowsPage, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return models.RowsPage{}, err
+	}
+	return g.next.KeysetRows(ctx, tableName, page, column, order, filter, cursor)
+}
+
+func (g *guard) InsertRow(ctx context.Context, props models.InsertDataProps) error {
+	if err := g.authorize(ctx, props.TableName, auth.OpInsert); err != nil {
+		return err
+	}
+	return g.next.InsertRow(ctx, props)
+}
+
+func (g *guard) GetRow(ctx context.Context, tableName string, rowKey string) ([]any, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return nil, err
+	}
+	return g.next.GetRow(ctx, tableName, rowKey)
+}
+
+func (g *guard) UpdateRow(ctx context.Context, values []models.RowItem, tableName, rowKey, version string) (int64, error) {
+	if err := g.authorize(ctx, tableName, auth.OpUpdate); err != nil {
+		return 0, err
+	}
+	return g.next.UpdateRow(ctx, values, tableName, rowKey, version)
+}
+
+func (g *guard) CreateTable(ctx context.Context, tableName string, inputs []database.Input) error {
+	if err := g.authorize(ctx, tableName, auth.OpDDL); err != nil {
+		return err
+	}
+	return g.next.CreateTable(ctx, tableName, inputs)
+}
+
+// AlterTable also needs DDL on every name the table is renamed to.
+func (g *guard) AlterTable(ctx context.Context, tableName string, ops []database.AlterOp) error {
+	if err := g.authorize(ctx, tableName, auth.OpDDL); err != nil {
+		return err
+	}
+	name := tableName
+	for _, op := range ops {
+		if op.Action != database.AlterRenameTable {
+			continue
+		}
+		schema, _ := g.builder.SplitTableName(name)
+		name = g.builder.QualifiedTableName(schema, op.NewName)
+		if err := g.authorize(ctx, name, auth.OpDDL); err != nil {
+			return err
+		}
+	}
+	return g.next.AlterTable(ctx, tableName, ops)
+}
+
+func (g *guard) ListIndexes(ctx context.Context, tableName string) ([]models.Index, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return nil, err
+	}
+	return g.next.ListIndexes(ctx, tableName)
+}
+
+func (g *guard) CreateIndex(ctx context.Context, tableName string, input database.IndexInput, preview bool) (string, error) {
+	if err := g.authorize(ctx, tableName, auth.OpDDL); err != nil {
+		return "", err
+	}
+	return g.next.CreateIndex(ctx, tableName, input, preview)
+}
+
+func (g *guard) DropIndex(ctx context.Context, tableName, indexName string, preview bool) (string, error) {
+	if err := g.authorize(ctx, tableName, auth.OpDDL); err != nil {
+		return "", err
+	}
+	return g.next.DropIndex(ctx, tableName, indexName, preview)
+}
+
+func (g *guard) GetRowCount(ctx context.Context, tableName string, filter *models.Filter) (int, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return 0, err
+	}
+	return g.next.GetRowCount(ctx, tableName, filter)
+}
+
+func (g *guard) CountRows(ctx context.Context, tableName string, filter *models.Filter, exact bool) (models.RowCount, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return models.RowCount{}, err
+	}
+	return g.next.CountRows(ctx, tableName, filter, exact)
+}
+
+func (g *guard) DeleteRow(ctx context.Context, tableName, rowKey, version string) (int64, error) {
+	if err := g.authorize(ctx, tableName, auth.OpDelete); err != nil {
+		return 0, err
+	}
+	return g.next.DeleteRow(ctx, tableName, rowKey, version)
+}
+
+// RunBatch checks every operation before any of them runs.
+func (g *guard) RunBatch(ctx context.Context, ops []models.BatchOperation) (models.BatchResult, error) {
+	for _, op := range ops {
+		required, ok := batchOperations[op.Operation]
+		if !ok {
+			// unknown operations fail the batch anyway
+			continue
+		}
+		if err := g.authorize(ctx, op.TableName, required); err != nil {
+			return models.BatchResult{}, err
+		}
+	}
+	return g.next.RunBatch(ctx, ops)
+}
+
+func (g *guard) BulkRows(ctx context.Context, props models.BulkProps) (models.BulkResult, error) {
+	if required, ok := batchOperations[props.Operation]; ok {
+		if err := g.authorize(ctx, props.TableName, required); err != nil {
+			return models.BulkResult{}, err
+		}
+	}
+	return g.next.BulkRows(ctx, props)
+}
+
+var batchOperations = map[string]auth.Operation{
+	models.OperationInsert: auth.OpInsert,
+	models.OperationUpdate: auth.OpUpdate,
+	models.OperationDelete: auth.OpDelete,
+}
+
+// GetRowRelations leaves out the related tables the user can't read.
+func (g *guard) GetRowRelations(ctx context.Context, tableName, rowKey string) (models.RowRelations, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return models.RowRelations{}, err
+	}
+	relations, err := g.next.GetRowRelations(ctx, tableName, rowKey)
+	if err != nil {
+		return relations, err
+	}
+	hidden := func(r models.RelatedRows) bool {
+		return g.authorize(ctx, r.Table, auth.OpRead) != nil
+	}
+	relations.Parents = slices.DeleteFunc(relations.Parents, hidden)
+	relations.Children = slices.DeleteFunc(relations.Children, hidden)
+	return relations, nil
+}
+
+func (g *guard) LookupReference(ctx context.Context, tableName, column, labelColumn, search string, page int) (models.LookupResult, error) {
+	if err := g.authorize(ctx, tableName, auth.OpRead); err != nil {
+		return models.LookupResult{}, err
+	}
+	result, err := g.next.LookupReference(ctx, tableName, column, labelColumn, search, page)
+	if err != nil {
+		return result, err
+	}
+	if err := g.authorize(ctx, result.Table, auth.OpRead); err != nil {
+		return models.LookupResult{}, err
+	}
+	return result, nil
+}
+
+func (g *guard) GetTableFormDataTypes() *FormDatatype {
+	return g.next.GetTableFormDataTypes()
+}
+
+func (g *guard) DeleteTable(ctx context.Context, tableName, verificationQuery string) error {
+	if err := g.authorize(ctx, tableName, auth.OpDDL); err != nil {
+		return err
+	}
+	return g.next.DeleteTable(ctx, tableName, verificationQuery)
+}
+
+// ListHistory shows statements of every table, so it needs read on all of them
+// unless it's filtered to one table.
+func (g *guard) ListHistory(ctx context.Context, filter models.HistoryFilter, page int) ([]models.History, error) {
+	if err := g.authorize(ctx, filter.TableName, auth.OpRead); err != nil {
+		return nil, err
+	}
+	return g.next.ListHistory(ctx, filter, page)
+}
+
+// RevertHistory needs the grant of the statement the revert runs, so the
+// entry is previewed first to find it.
+func (g *guard) RevertHistory(ctx context.Context, id int, preview bool) (models.RevertResult, error) {
+	result, err := g.next.RevertHistory(ctx, id, true)
+	if err != nil {
+		return result, err
+	}
+	if err := g.authorize(ctx, result.TableName, batchOperations[result.Operation]); err != nil {
+		return models.RevertResult{}, err
+	}
+	if preview {
+		return result, nil
+	}
+	return g.next.RevertHistory(ctx, id, false)
+}
+
+func (g *guard) HasNextPage(ctx context.Context, total, page int) bool {
+	return g.next.HasNextPage(ctx, total, page)
+}
+
+func (g *guard) RunQuery(ctx context.Context, id, query string) (models.QueryResult, error) {
+	if err := g.authorize(ctx, "", auth.OpSQL); err != nil {
+		return models.QueryResult{}, err
+	}
+	return g.next.RunQuery(ctx, id, query)
+}
+
+func (g *guard) ExportRows(ctx context.Context, props models.ExportProps, w repo.RowWriter) error {
+	if err := g.authorize(ctx, props.TableName, auth.OpRead); err != nil {
+		return err
+	}
+	return g.next.ExportRows(ctx, props, w)
+}
+
+func (g *guard) ExportQuery(ctx context.Context, query string, w repo.RowWriter) error {
+	if err := g.authorize(ctx, "", auth.OpSQL); err != nil {
+		return err
+	}
+	return g.next.ExportQuery(ctx, query, w)
+}
+
+func (g *guard) ImportRows(ctx context.Context, props repo.ImportProps, src importer.Source) (models.ImportResult, error) {
+	if err := g.authorize(ctx, props.TableName, auth.OpInsert); err != nil {
+		return models.ImportResult{}, err
+	}
+	return g.next.ImportRows(ctx, props, src)
+}
+
+// CancelQuery only reaches the queries the caller started, they are kept by user.
+func (g *guard) CancelQuery(ctx context.Context, id string) error {
+	return g.next.CancelQuery(ctx, id)
+}
+
+// RefreshSchema only drops cached metadata, which is read again with the
+// permissions of whoever needs it next.
+func (g *guard) RefreshSchema() {
+	g.next.RefreshSchema()
+}
+
+// CacheStats only holds counters, no data.
+func (g *guard) CacheStats() models.CacheStats {
+	return g.next.CacheStats()
+}
@@ -11,29 +11,49 @@ import (
 	"github.com/biisal/rowsql/internal/database/models"
 	"github.com/biisal/rowsql/internal/database/queries"
 	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/importer"
 )
 
 type DBService interface {
 	CheckTableExits(ctx context.Context, tableName string) error
 	ListTables(ctx context.Context) ([]models.ListTablesRow, error)
 	ListCols(ctx context.Context, tableName string) ([]models.ListDataCol, error)
-	ListRows(ctx context.Context, tableName string, page int, column string, order string) (models.ListDataRow, error)
+	ListRows(ctx context.Context, tableName string, page int, column string, order string, filter *models.Filter) (models.ListDataRow, error)
+	KeysetRows(ctx context.Context, tableName string, page int, column, order string, filter *models.Filter, cursor string) (models.RowsPage, error)
 	InsertRow(ctx context.Context, props models.InsertDataProps) error
-	GetRow(ctx context.Context, tableName string, hash string, page int) ([]any, error)
-	UpdateRow(ctx context.Context, values []models.RowItem, tableName, hash string, page int) error
+	GetRow(ctx context.Context, tableName string, rowKey string) ([]any, error)
+	UpdateRow(ctx context.Context, values []models.RowItem, tableName, rowKey, version string) (int64, error)
 	CreateTable(ctx context.Context, tableName string, inputs []database.Input) error
-	GetRowCount(ctx context.Context, tableName string) (int, error)
-	DeleteRow(ctx context.Context, tableName string, hash string, page int) error
+	AlterTable(ctx context.Context, tableName string, ops []database.AlterOp) error
+	ListIndexes(ctx context.Context, tableName string) ([]models.Index, error)
+	CreateIndex(ctx context.Context, tableName string, input database.IndexInput, preview bool) (string, error)
+	DropIndex(ctx context.Context, tableName, indexName string, preview bool) (string, error)
+	GetRowCount(ctx context.Context, tableName string, filter *models.Filter) (int, error)
+	CountRows(ctx context.Context, tableName string, filter *models.Filter, exact bool) (models.RowCount, error)
+	DeleteRow(ctx context.Context, tableName, rowKey, version string) (int64, error)
+	RunBatch(ctx context.Context, ops []models.BatchOperation) (models.BatchResult, error)
+	BulkRows(ctx context.Context, props models.BulkProps) (models.BulkResult, error)
+	GetRowRelations(ctx context.Context, tableName, rowKey string) (models.RowRelations, error)
+	LookupReference(ctx context.Context, tableName, column, labelColumn, search string, page int) (models.LookupResult, error)
 	GetTableFormDataTypes() *FormDatatype
 	DeleteTable(ctx context.Context, tableName, verificationQuery string) error
-	ListHistory(ctx context.Context, page int) ([]models.History, error)
+	ListHistory(ctx context.Context, filter models.HistoryFilter, page int) ([]models.History, error)
+	RevertHistory(ctx context.Context, id int, preview bool) (models.RevertResult, error)
 	HasNextPage(ctx context.Context, total, page int) bool
+	RunQuery(ctx context.Context, id, query string) (models.QueryResult, error)
+	ExportRows(ctx context.Context, props models.ExportProps, w repo.RowWriter) error
+	ExportQuery(ctx context.Context, query string, w repo.RowWriter) error
+	ImportRows(ctx context.Context, props repo.ImportProps, src importer.Source) (models.ImportResult, error)
+	CancelQuery(ctx context.Context, id string) error
+	RefreshSchema()
+	CacheStats() models.CacheStats
 }
 
 type svc struct {
 	repo    *repo.Queries
 	builder *queries.Builder
 	limit   int
+	running *runningQueries
 }
 
 func NewService(repo *repo.Queries, builder *queries.Builder, maxItemsPerPage int) DBService {
@@ -41,6 +61,7 @@ func NewService(repo *repo.Queries, builder *queries.Builder, maxItemsPerPage in
 		repo:    repo,
 		builder: builder,
 		limit:   maxItemsPerPage,
+		running: newRunningQueries(),
 	}
 }
 
@@ -52,6 +73,14 @@ func (s *svc) ListTables(ctx context.Context) ([]models.ListTablesRow, error) {
 	return s.repo.ListTables(ctx)
 }
 
+func (s *svc) RefreshSchema() {
+	s.repo.RefreshSchema()
+}
+
+func (s *svc) CacheStats() models.CacheStats {
+	return s.repo.CacheStats()
+}
+
 func (s *svc) CreateTable(ctx context.Context, tableName string, inputs []database.Input) error {
 	return s.repo.CreateTable(ctx, repo.CreateTableProps{
 		TableName: tableName,
@@ -59,6 +88,30 @@ func (s *svc) CreateTable(ctx context.Context, tableName string, inputs []databa
 	})
 }
 
+func (s *svc) AlterTable(ctx context.Context, tableName string, ops []database.AlterOp) error {
+	return s.repo.AlterTable(ctx, tableName, ops)
+}
+
+func (s *svc) ListIndexes(ctx context.Context, tableName string) ([]models.Index, error) {
+	return s.repo.ListIndexes(ctx, tableName)
+}
+
+// CreateIndex returns the executed statement, with preview it is only built.
+func (s *svc) CreateIndex(ctx context.Context, tableName string, input database.IndexInput, preview bool) (string, error) {
+	if preview {
+		return s.repo.CreateIndexQuery(ctx, tableName, input)
+	}
+	return s.repo.CreateIndex(ctx, tableName, input)
+}
+
+// DropIndex returns the executed statement, with preview it is only built.
+func (s *svc) DropIndex(ctx context.Context, tableName, indexName string, preview bool) (string, error) {
+	if preview {
+		return s.repo.DropIndexQuery(ctx, tableName, indexName)
+	}
+	return s.repo.DropIndex(ctx, tableName, indexName)
+}
+
 func (s *svc) DeleteTable(ctx context.Context, tableName, verificationQuery string) error {
 	q := strings.Join(strings.Fields(verificationQuery), " ")
 	deleteTableQuery := s.builder.DeleteTable(tableName)
@@ -72,45 +125,97 @@ func (s *svc) ListCols(ctx context.Context, tableName string) ([]models.ListData
 	return s.repo.ListCols(ctx, tableName)
 }
 
-func (s *svc) GetRow(ctx context.Context, tableName, hash string, page int) ([]any, error) {
-	return s.repo.GetRow(ctx, tableName, hash, s.getOffset(page), s.limit)
+func (s *svc) GetRow(ctx context.Context, tableName, rowKey string) ([]any, error) {
+	return s.repo.GetRow(ctx, tableName, rowKey)
 }
 
 func (s *svc) InsertRow(ctx context.Context, props models.InsertDataProps) error {
 	return s.repo.InsertRow(ctx, props)
 }
 
-func (s *svc) ListRows(ctx context.Context, tableName string, page int, column string, order string) (models.ListDataRow, error) {
+func (s *svc) ListRows(ctx context.Context, tableName string, page int, column string, order string, filter *models.Filter) (models.ListDataRow, error) {
 	return s.repo.ListRows(ctx, models.ListDataProps{
 		TableName: tableName,
 		Limit:     s.limit,
 		Offset:    s.getOffset(page),
 		Column:    column,
 		Order:     order,
+		Filter:    filter,
 	})
 }
 
-func (s *svc) UpdateRow(ctx context.Context, values []models.RowItem, tableName, hash string, page int) error {
-	return s.repo.UpdateRow(ctx, repo.UpdateOrDeleteRowProps{
+func (s *svc) KeysetRows(ctx context.Context, tableName string, page int, column, order string, filter *models.Filter, cursor string) (models.RowsPage, error) {
+	return s.repo.KeysetRows(ctx, models.ListDataProps{
 		TableName: tableName,
-		Hash:      hash,
 		Limit:     s.limit,
 		Offset:    s.getOffset(page),
+		Column:    column,
+		Order:     order,
+		Filter:    filter,
+	}, cursor)
+}
+
+func (s *svc) UpdateRow(ctx context.Context, values []models.RowItem, tableName, rowKey, version string) (int64, error) {
+	return s.repo.UpdateRow(ctx, repo.UpdateOrDeleteRowProps{
+		TableName: tableName,
+		Key:       rowKey,
 		Values:    values,
+		Version:   version,
 	})
 }
 
-func (s *svc) DeleteRow(ctx context.Context, tableName, hash string, page int) error {
+func (s *svc) DeleteRow(ctx context.Context, tableName, rowKey, version string) (int64, error) {
 	return s.repo.DeleteRow(ctx, repo.UpdateOrDeleteRowProps{
 		TableName: tableName,
-		Hash:      hash,
-		Limit:     s.limit,
-		Offset:    s.getOffset(page),
+		Key:       rowKey,
+		Version:   version,
 	})
 }
 
-func (s *svc) GetRowCount(ctx context.Context, tableName string) (int, error) {
-	return s.repo.GetRowCount(ctx, tableName)
+func (s *svc) RunBatch(ctx context.Context, ops []models.BatchOperation) (models.BatchResult, error) {
+	return s.repo.RunBatch(ctx, ops)
+}
+
+func (s *svc) BulkRows(ctx context.Context, props models.BulkProps) (models.BulkResult, error) {
+	return s.repo.Bulk(ctx, props)
+}
+
+func (s *svc) GetRowRelations(ctx context.Context, tableName, rowKey string) (models.RowRelations, error) {
+	return s.repo.RowRelations(ctx, tableName, rowKey)
+}
+
+func (s *svc) LookupReference(ctx context.Context, tableName, column, labelColumn, search string, page int) (models.LookupResult, error) {
+	return s.repo.Lookup(ctx, models.LookupProps{
+		TableName:   tableName,
+		Column:      column,
+		LabelColumn: labelColumn,
+		Search:      search,
+		Limit:       s.limit,
+		Offset:      s.getOffset(page),
+	})
+}
+
+func (s *svc) ExportRows(ctx context.Context, props models.ExportProps, w repo.RowWriter) error {
+	return s.repo.ExportRows(ctx, props, w)
+}
+
+func (s *svc) ExportQuery(ctx context.Context, query string, w repo.RowWriter) error {
+	if strings.TrimSpace(query) == "" {
+		return ErrorEmptyQuery
+	}
+	return s.repo.ExportQuery(ctx, query, w)
+}
+
+func (s *svc) ImportRows(ctx context.Context, props repo.ImportProps, src importer.Source) (models.ImportResult, error) {
+	return s.repo.ImportRows(ctx, props, src)
+}
+
+func (s *svc) GetRowCount(ctx context.Context, tableName string, filter *models.Filter) (int, error) {
+	return s.repo.GetRowCount(ctx, tableName, filter)
+}
+
+func (s *svc) CountRows(ctx context.Context, tableName string, filter *models.Filter, exact bool) (models.RowCount, error) {
+	return s.repo.CountRows(ctx, tableName, filter, exact)
 }
 
 type FormDatatype struct {
@@ -140,6 +245,10 @@ func (s *svc) GetTableFormDataTypes() *FormDatatype {
 	return nil
 }
 
-func (s *svc) ListHistory(ctx context.Context, page int) ([]models.History, error) {
-	return s.repo.ListHistory(ctx, s.limit, s.getOffset(page))
+func (s *svc) ListHistory(ctx context.Context, filter models.HistoryFilter, page int) ([]models.History, error) {
+	return s.repo.ListHistory(ctx, filter, s.limit, s.getOffset(page))
+}
+
+func (s *svc) RevertHistory(ctx context.Context, id int, preview bool) (models.RevertResult, error) {
+	return s.repo.RevertHistory(ctx, id, preview)
 }
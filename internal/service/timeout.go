@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biisal/rowsql/configs"
+	"github.com/biisal/rowsql/internal/database"
+	dberrors "github.com/biisal/rowsql/internal/database/db-errors"
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/database/repo"
+	"github.com/biisal/rowsql/internal/importer"
+)
+
+// deadline bounds every call to the wrapped service by the timeout of its kind
+// of operation. Exports and imports stream for as long as the client reads.
+type deadline struct {
+	next     DBService
+	timeouts configs.TimeoutConfig
+}
+
+// WithTimeouts runs the calls to next with the deadlines of timeouts, calls
+// running out of time fail with dberrors.ErrTimeout.
+func WithTimeouts(next DBService, timeouts configs.TimeoutConfig) DBService {
+	return &deadline{next: next, timeouts: timeouts}
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timedOut marks err as a timeout when ctx ran out of time, the drivers report
+// it in their own words.
+func timedOut(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && !errors.Is(err, dberrors.ErrTimeout) {
+		return fmt.Errorf("%w: %w", dberrors.ErrTimeout, err)
+	}
+	return err
+}
+
+func (d *deadline) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, d.timeouts.Read)
+}
+
+func (d *deadline) write(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, d.timeouts.Write)
+}
+
+func (d *deadline) ddl(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, d.timeouts.DDL)
+}
+
+func (d *deadline) CheckTableExits(ctx context.Context, tableName string) error {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	return timedOut(ctx, d.next.CheckTableExits(ctx, tableName))
+}
+
+func (d *deadline) ListTables(ctx context.Context) ([]models.ListTablesRow, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	tables, err := d.next.ListTables(ctx)
+	return tables, timedOut(ctx, err)
+}
+
+func (d *deadline) ListCols(ctx context.Context, tableName string) ([]models.ListDataCol, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	cols, err := d.next.ListCols(ctx, tableName)
+	return cols, timedOut(ctx, err)
+}
+
+func (d *deadline) ListRows(ctx context.Context, tableName string, page int, column string, order string, filter *models.Filter) (models.ListDataRow, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	rows, err := d.next.ListRows(ctx, tableName, page, column, order, filter)
+	return rows, timedOut(ctx, err)
+}
+
+func (d *deadline) KeysetRows(ctx context.Context, tableName string, page int, column, order string, filter *models.Filter, cursor string) (models.RowsPage, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	rows, err := d.next.KeysetRows(ctx, tableName, page, column, order, filter, cursor)
+	return rows, timedOut(ctx, err)
+}
+
+func (d *deadline) InsertRow(ctx context.Context, props models.InsertDataProps) error {
+	ctx, cancel := d.write(ctx)
+	defer cancel()
+	return timedOut(ctx, d.next.InsertRow(ctx, props))
+}
+
+func (d *deadline) GetRow(ctx context.Context, tableName string, rowKey string) ([]any, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	row, err := d.next.GetRow(ctx, tableName, rowKey)
+	return row, timedOut(ctx, err)
+}
+
+func (d *deadline) UpdateRow(ctx context.Context, values []models.RowItem, tableName, rowKey, version string) (int64, error) {
+	ctx, cancel := d.write(ctx)
+	defer cancel()
+	affected, err := d.next.UpdateRow(ctx, values, tableName, rowKey, version)
+	return affected, timedOut(ctx, err)
+}
+
+func (d *deadline) CreateTable(ctx context.Context, tableName string, inputs []database.Input) error {
+	ctx, cancel := d.ddl(ctx)
+	defer cancel()
+	return timedOut(ctx, d.next.CreateTable(ctx, tableName, inputs))
+}
+
+func (d *deadline) AlterTable(ctx context.Context, tableName string, ops []database.AlterOp) error {
+	ctx, cancel := d.ddl(ctx)
+	defer cancel()
+	return timedOut(ctx, d.next.AlterTable(ctx, tableName, ops))
+}
+
+func (d *deadline) ListIndexes(ctx context.Context, tableName string) ([]models.Index, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	indexes, err := d.next.ListIndexes(ctx, tableName)
+	return indexes, timedOut(ctx, err)
+}
+
+func (d *deadline) CreateIndex(ctx context.Context, tableName string, input database.IndexInput, preview bool) (string, error) {
+	ctx, cancel := d.ddl(ctx)
+	defer cancel()
+	query, err := d.next.CreateIndex(ctx, tableName, input, preview)
+	return query, timedOut(ctx, err)
+}
+
+func (d *deadline) DropIndex(ctx context.Context, tableName, indexName string, preview bool) (string, error) {
+	ctx, cancel := d.ddl(ctx)
+	defer cancel()
+	query, err := d.next.DropIndex(ctx, tableName, indexName, preview)
+	return query, timedOut(ctx, err)
+}
+
+func (d *deadline) GetRowCount(ctx context.Context, tableName string, filter *models.Filter) (int, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	count, err := d.next.GetRowCount(ctx, tableName, filter)
+	return count, timedOut(ctx, err)
+}
+
+func (d *deadline) CountRows(ctx context.Context, tableName string, filter *models.Filter, exact bool) (models.RowCount, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	count, err := d.next.CountRows(ctx, tableName, filter, exact)
+	return count, timedOut(ctx, err)
+}
+
+func (d *deadline) DeleteRow(ctx context.Context, tableName, rowKey, version string) (int64, error) {
+	ctx, cancel := d.write(ctx)
+	defer cancel()
+	affected, err := d.next.DeleteRow(ctx, tableName, rowKey, version)
+	return affected, timedOut(ctx, err)
+}
+
+func (d *deadline) RunBatch(ctx context.Context, ops []models.BatchOperation) (models.BatchResult, error) {
+	ctx, cancel := d.write(ctx)
+	defer cancel()
+	result, err := d.next.RunBatch(ctx, ops)
+	return result, timedOut(ctx, err)
+}
+
+func (d *deadline) BulkRows(ctx context.Context, props models.BulkProps) (models.BulkResult, error) {
+	ctx, cancel := d.write(ctx)
+	defer cancel()
+	result, err := d.next.BulkRows(ctx, props)
+	return result, timedOut(ctx, err)
+}
+
+func (d *deadline) GetRowRelations(ctx context.Context, tableName, rowKey string) (models.RowRelations, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	relations, err := d.next.GetRowRelations(ctx, tableName, rowKey)
+	return relations, timedOut(ctx, err)
+}
+
+func (d *deadline) LookupReference(ctx context.Context, tableName, column, labelColumn, search string, page int) (models.LookupResult, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	result, err := d.next.LookupReference(ctx, tableName, column, labelColumn, search, page)
+	return result, timedOut(ctx, err)
+}
+
+func (d *deadline) GetTableFormDataTypes() *FormDatatype {
+	return d.next.GetTableFormDataTypes()
+}
+
+func (d *deadline) DeleteTable(ctx context.Context, tableName, verificationQuery string) error {
+	ctx, cancel := d.ddl(ctx)
+	defer cancel()
+	return timedOut(ctx, d.next.DeleteTable(ctx, tableName, verificationQuery))
+}
+
+func (d *deadline) ListHistory(ctx context.Context, filter models.HistoryFilter, page int) ([]models.History, error) {
+	ctx, cancel := d.read(ctx)
+	defer cancel()
+	history, err := d.next.ListHistory(ctx, filter, page)
+	return history, timedOut(ctx, err)
+}
+
+func (d *deadline) RevertHistory(ctx context.Context, id int, preview bool) (models.RevertResult, error) {
+	ctx, cancel := d.write(ctx)
+	defer cancel()
+	result, err := d.next.RevertHistory(ctx, id, preview)
+	return result, timedOut(ctx, err)
+}
+
+func (d *deadline) HasNextPage(ctx context.Context, total, page int) bool {
+	return d.next.HasNextPage(ctx, total, page)
+}
+
+func (d *deadline) RunQuery(ctx context.Context, id, query string) (models.QueryResult, error) {
+	ctx, cancel := withTimeout(ctx, d.timeouts.Console)
+	defer cancel()
+	result, err := d.next.RunQuery(ctx, id, query)
+	return result, timedOut(ctx, err)
+}
+
+func (d *deadline) ExportRows(ctx context.Context, props models.ExportProps, w repo.RowWriter) error {
+	return d.next.ExportRows(ctx, props, w)
+}
+
+func (d *deadline) ExportQuery(ctx context.Context, query string, w repo.RowWriter) error {
+	return d.next.ExportQuery(ctx, query, w)
+}
+
+func (d *deadline) ImportRows(ctx context.Context, props repo.ImportProps, src importer.Source) (models.ImportResult, error) {
+	return d.next.ImportRows(ctx, props, src)
+}
+
+func (d *deadline) CancelQuery(ctx context.Context, id string) error {
+	return d.next.CancelQuery(ctx, id)
+}
+
+func (d *deadline) RefreshSchema() {
+	d.next.RefreshSchema()
+}
+
+func (d *deadline) CacheStats() models.CacheStats {
+	return d.next.CacheStats()
+}
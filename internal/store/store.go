@@ -0,0 +1,432 @@
+// Package store keeps rowsql's own data, the history, saved queries and
+// preferences, in a local SQLite file so nothing is written into the
+// databases rowsql is connected to.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/biisal/rowsql/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrorSavedQueryNotFound = errors.New("saved query not found")
+	ErrorSavedQueryExists   = errors.New("a saved query with this name already exists")
+	ErrorInvalidSavedQuery  = errors.New("saved query needs a name and a query")
+)
+
+// migrations are applied in order, PRAGMA user_version holds how many of them
+// ran. The first one uses IF NOT EXISTS since stores created before the
+// versioning never set user_version.
+var migrations = []string{`
+CREATE TABLE IF NOT EXISTS history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	connection TEXT NOT NULL,
+	message TEXT NOT NULL,
+	time DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_history_connection ON history (connection, id);
+CREATE TABLE IF NOT EXISTS saved_queries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	connection TEXT NOT NULL,
+	name TEXT NOT NULL,
+	query TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	UNIQUE (connection, name)
+);
+CREATE TABLE IF NOT EXISTS preferences (
+	user TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (user, key)
+);
+CREATE TABLE IF NOT EXISTS history_migrations (
+	connection TEXT PRIMARY KEY,
+	migrated_at DATETIME NOT NULL
+);`, `
+ALTER TABLE history ADD COLUMN operation TEXT NOT NULL DEFAULT '';
+ALTER TABLE history ADD COLUMN table_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE history ADD COLUMN query TEXT NOT NULL DEFAULT '';
+ALTER TABLE history ADD COLUMN args TEXT;
+ALTER TABLE history ADD COLUMN old_row TEXT;
+ALTER TABLE history ADD COLUMN new_values TEXT;
+ALTER TABLE history ADD COLUMN user TEXT NOT NULL DEFAULT '';
+ALTER TABLE history ADD COLUMN client_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE history ADD COLUMN duration_ms REAL NOT NULL DEFAULT 0;
+ALTER TABLE history ADD COLUMN success INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE history ADD COLUMN error TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_history_table ON history (connection, table_name, id);`, `
+ALTER TABLE history ADD COLUMN revert_of INTEGER NOT NULL DEFAULT 0;`,
+}
+
+type Store struct {
+	db *sqlx.DB
+}
+
+// Open opens or creates the store at path.
+func Open(ctx context.Context, path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sqlx.ConnectContext(ctx, "sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// a single connection serializes the writes of concurrent requests
+	db.SetMaxOpenConns(1)
+	if err := migrate(ctx, db); err != nil {
+		closeDB(db)
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func migrate(ctx context.Context, db *sqlx.DB) error {
+	var version int
+	if err := db.GetContext(ctx, &version, "PRAGMA user_version"); err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+			rollback(tx)
+			return fmt.Errorf("store migration %d: %w", version+1, err)
+		}
+		// PRAGMA doesn't take bound parameters
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			rollback(tx)
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) Close() {
+	closeDB(s.db)
+}
+
+func closeDB(db *sqlx.DB) {
+	if err := db.Close(); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+func rollback(tx *sqlx.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logger.Errorln(err)
+	}
+}
+
+// History is the history of one connection.
+type History struct {
+	s      *Store
+	connID string
+}
+
+func (s *Store) History(connID string) *History {
+	return &History{s: s, connID: connID}
+}
+
+const historyColumns = "id, message, time, operation, table_name, query, args, old_row, new_values, user, client_ip, duration_ms, success, error, revert_of"
+
+// historyRow is a history entry as stored, the row images and args are JSON.
+type historyRow struct {
+	ID        int            `db:"id"`
+	Message   string         `db:"message"`
+	Time      time.Time      `db:"time"`
+	Operation string         `db:"operation"`
+	TableName string         `db:"table_name"`
+	Query     string         `db:"query"`
+	Args      sql.NullString `db:"args"`
+	OldRow    sql.NullString `db:"old_row"`
+	NewValues sql.NullString `db:"new_values"`
+	User      string         `db:"user"`
+	ClientIP  string         `db:"client_ip"`
+	Duration  float64        `db:"duration_ms"`
+	Success   bool           `db:"success"`
+	Error     string         `db:"error"`
+	RevertOf  int            `db:"revert_of"`
+}
+
+func (h *History) InsertHistory(ctx context.Context, entry models.History) error {
+	return h.insert(ctx, h.s.db, entry)
+}
+
+func (h *History) insert(ctx context.Context, db sqlx.ExecerContext, e models.History) error {
+	args, err := marshalJSON(e.Args)
+	if err != nil {
+		return err
+	}
+	oldRow, err := marshalJSON(e.OldRow)
+	if err != nil {
+		return err
+	}
+	newValues, err := marshalJSON(e.NewValues)
+	if err != nil {
+		return err
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO history (connection, message, time, operation, table_name, query,
+		args, old_row, new_values, user, client_ip, duration_ms, success, error, revert_of)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		h.connID, e.Message, e.Time.UTC(), e.Operation, e.TableName, e.Query,
+		args, oldRow, newValues, e.User, e.ClientIP, e.Duration, e.Success, e.Error, e.RevertOf)
+	return err
+}
+
+// marshalJSON stores nil values as NULL.
+func marshalJSON[T any](v T) (sql.NullString, error) {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// unmarshalJSON keeps numbers as json.Number so large integers survive.
+func unmarshalJSON(s sql.NullString, v any) error {
+	if !s.Valid {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(s.String))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// ListHistory returns the newest entries first.
+func (h *History) ListHistory(ctx context.Context, filter models.HistoryFilter, limit, offset int) ([]models.History, error) {
+	where := []string{"connection = ?"}
+	args := []any{h.connID}
+	if filter.TableName != "" {
+		where = append(where, "table_name = ?")
+		args = append(args, filter.TableName)
+	}
+	if filter.Operation != "" {
+		where = append(where, "operation = ?")
+		args = append(args, filter.Operation)
+	}
+	if filter.User != "" {
+		where = append(where, "user = ?")
+		args = append(args, filter.User)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.RevertOf != 0 {
+		where = append(where, "revert_of = ?")
+		args = append(args, filter.RevertOf)
+	}
+	var rows []historyRow
+	err := h.s.db.SelectContext(ctx, &rows,
+		"SELECT "+historyColumns+" FROM history WHERE "+strings.Join(where, " AND ")+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]models.History, 0, len(rows))
+	for _, row := range rows {
+		item, err := row.entry()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetHistory returns sql.ErrNoRows for an unknown id.
+func (h *History) GetHistory(ctx context.Context, id int) (models.History, error) {
+	var row historyRow
+	err := h.s.db.GetContext(ctx, &row, "SELECT "+historyColumns+" FROM history WHERE connection = ? AND id = ?", h.connID, id)
+	if err != nil {
+		return models.History{}, err
+	}
+	return row.entry()
+}
+
+func (row historyRow) entry() (models.History, error) {
+	item := models.History{
+		ID:        row.ID,
+		Message:   row.Message,
+		Time:      row.Time,
+		Operation: row.Operation,
+		TableName: row.TableName,
+		Query:     row.Query,
+		User:      row.User,
+		ClientIP:  row.ClientIP,
+		Duration:  row.Duration,
+		Success:   row.Success,
+		Error:     row.Error,
+		RevertOf:  row.RevertOf,
+	}
+	if err := unmarshalJSON(row.Args, &item.Args); err != nil {
+		return item, err
+	}
+	if err := unmarshalJSON(row.OldRow, &item.OldRow); err != nil {
+		return item, err
+	}
+	if err := unmarshalJSON(row.NewValues, &item.NewValues); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// Migrated reports whether the legacy history of the connection was already imported.
+func (h *History) Migrated(ctx context.Context) (bool, error) {
+	var n int
+	err := h.s.db.GetContext(ctx, &n, "SELECT COUNT(*) FROM history_migrations WHERE connection = ?", h.connID)
+	return n > 0, err
+}
+
+// ImportHistory copies entries with their original time and marks the
+// connection as migrated, all or nothing.
+func (h *History) ImportHistory(ctx context.Context, entries []models.History) error {
+	tx, err := h.s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	for _, e := range entries {
+		if err := h.insert(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO history_migrations (connection, migrated_at) VALUES (?, ?)",
+		h.connID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) ListSavedQueries(ctx context.Context, connID string) ([]models.SavedQuery, error) {
+	items := []models.SavedQuery{}
+	err := s.db.SelectContext(ctx, &items,
+		"SELECT id, name, query, description, created_at, updated_at FROM saved_queries WHERE connection = ? ORDER BY name",
+		connID)
+	return items, err
+}
+
+func (s *Store) SaveQuery(ctx context.Context, connID string, q models.SavedQuery) (models.SavedQuery, error) {
+	if err := validateSavedQuery(&q); err != nil {
+		return q, err
+	}
+	q.CreatedAt = time.Now().UTC()
+	q.UpdatedAt = q.CreatedAt
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO saved_queries (connection, name, query, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		connID, q.Name, q.Query, q.Description, q.CreatedAt, q.UpdatedAt)
+	if err != nil {
+		return q, savedQueryError(err)
+	}
+	q.ID, err = res.LastInsertId()
+	return q, err
+}
+
+func (s *Store) UpdateSavedQuery(ctx context.Context, connID string, q models.SavedQuery) (models.SavedQuery, error) {
+	if err := validateSavedQuery(&q); err != nil {
+		return q, err
+	}
+	q.UpdatedAt = time.Now().UTC()
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE saved_queries SET name = ?, query = ?, description = ?, updated_at = ? WHERE id = ? AND connection = ?",
+		q.Name, q.Query, q.Description, q.UpdatedAt, q.ID, connID)
+	if err != nil {
+		return q, savedQueryError(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return q, ErrorSavedQueryNotFound
+	}
+	err = s.db.GetContext(ctx, &q.CreatedAt, "SELECT created_at FROM saved_queries WHERE id = ?", q.ID)
+	return q, err
+}
+
+func (s *Store) DeleteSavedQuery(ctx context.Context, connID string, id int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM saved_queries WHERE id = ? AND connection = ?", id, connID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrorSavedQueryNotFound
+	}
+	return nil
+}
+
+func validateSavedQuery(q *models.SavedQuery) error {
+	q.Name = strings.TrimSpace(q.Name)
+	if q.Name == "" || strings.TrimSpace(q.Query) == "" {
+		return ErrorInvalidSavedQuery
+	}
+	return nil
+}
+
+func savedQueryError(err error) error {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrorSavedQueryExists
+	}
+	return err
+}
+
+// Preferences returns the preferences of user, an empty user is used when
+// authentication is disabled.
+func (s *Store) Preferences(ctx context.Context, user string) (map[string]json.RawMessage, error) {
+	var rows []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, "SELECT key, value FROM preferences WHERE user = ?", user); err != nil {
+		return nil, err
+	}
+	prefs := make(map[string]json.RawMessage, len(rows))
+	for _, row := range rows {
+		prefs[row.Key] = json.RawMessage(row.Value)
+	}
+	return prefs, nil
+}
+
+// SetPreferences merges prefs into the stored ones, a null value removes the key.
+func (s *Store) SetPreferences(ctx context.Context, user string, prefs map[string]json.RawMessage) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+	for key, value := range prefs {
+		if value == nil || string(value) == "null" {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM preferences WHERE user = ? AND key = ?", user, key); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO preferences (user, key, value) VALUES (?, ?, ?) ON CONFLICT (user, key) DO UPDATE SET value = excluded.value",
+			user, key, string(value)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
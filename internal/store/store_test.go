@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/biisal/rowsql/internal/database/models"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(context.Background(), filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+	h := s.History("default")
+
+	if migrated, err := h.Migrated(ctx); err != nil || migrated {
+		t.Fatalf("new store: migrated = %t, err = %v", migrated, err)
+	}
+	old := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := h.ImportHistory(ctx, []models.History{{ID: 7, Message: "old", Time: old}}); err != nil {
+		t.Fatal(err)
+	}
+	if migrated, err := h.Migrated(ctx); err != nil || !migrated {
+		t.Fatalf("after import: migrated = %t, err = %v", migrated, err)
+	}
+	if err := h.InsertHistory(ctx, models.History{Message: "new", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.History("other").InsertHistory(ctx, models.History{Message: "elsewhere"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := h.ListHistory(ctx, models.HistoryFilter{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Message != "new" || items[1].Message != "old" {
+		t.Fatalf("got %+v, want new and old", items)
+	}
+	if !items[1].Time.Equal(old) {
+		t.Errorf("imported time = %s, want %s", items[1].Time, old)
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	ctx := context.Background()
+	h := openStore(t).History("default")
+
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []models.History{
+		{
+			Message:   "updated",
+			Time:      day,
+			Operation: models.OperationUpdate,
+			TableName: "users",
+			Query:     "UPDATE users SET name = $1 WHERE id = $2",
+			Args:      []any{"ana", 1},
+			OldRow:    map[string]any{"id": 1, "name": "jo"},
+			NewValues: map[string]any{"name": "ana"},
+			User:      "admin",
+			Success:   true,
+		},
+		{Message: "deleted", Time: day.Add(time.Hour), Operation: models.OperationDelete, TableName: "users", Error: "locked"},
+		{Message: "inserted", Time: day.Add(2 * time.Hour), Operation: models.OperationInsert, TableName: "posts", Success: true},
+	}
+	for _, e := range entries {
+		if err := h.InsertHistory(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter models.HistoryFilter
+		want   []string
+	}{
+		{"all", models.HistoryFilter{}, []string{"inserted", "deleted", "updated"}},
+		{"table", models.HistoryFilter{TableName: "users"}, []string{"deleted", "updated"}},
+		{"operation", models.HistoryFilter{TableName: "users", Operation: models.OperationUpdate}, []string{"updated"}},
+		{"user", models.HistoryFilter{User: "admin"}, []string{"updated"}},
+		{"from", models.HistoryFilter{From: day.Add(time.Hour)}, []string{"inserted", "deleted"}},
+		{"to", models.HistoryFilter{To: day.Add(time.Hour)}, []string{"updated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := h.ListHistory(ctx, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.Message)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	items, err := h.ListHistory(ctx, models.HistoryFilter{Operation: models.OperationUpdate}, 1, 0)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("got %+v, %v", items, err)
+	}
+	got := items[0]
+	if got.Query != entries[0].Query || got.User != "admin" || !got.Success ||
+		got.OldRow["name"] != "jo" || got.NewValues["name"] != "ana" || len(got.Args) != 2 || got.Args[1] != json.Number("1") {
+		t.Errorf("got %+v", got)
+	}
+	if entry, err := h.GetHistory(ctx, got.ID); err != nil || entry.Message != "updated" {
+		t.Errorf("GetHistory(%d) = %+v, %v", got.ID, entry, err)
+	}
+	if _, err := h.GetHistory(ctx, 1000); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown id: got %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestOpenMigratesOldStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	db, err := sqlx.ConnectContext(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, migrations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO history (connection, message, time) VALUES ('default', 'before', ?)", time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	closeDB(db)
+
+	s, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	items, err := s.History("default").ListHistory(ctx, models.HistoryFilter{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Message != "before" || !items[0].Success {
+		t.Errorf("got %+v", items)
+	}
+}
+
+func TestSavedQueries(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+
+	if _, err := s.SaveQuery(ctx, "default", models.SavedQuery{Name: " "}); !errors.Is(err, ErrorInvalidSavedQuery) {
+		t.Errorf("got %v, want %v", err, ErrorInvalidSavedQuery)
+	}
+	saved, err := s.SaveQuery(ctx, "default", models.SavedQuery{Name: "users", Query: "SELECT * FROM users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SaveQuery(ctx, "default", models.SavedQuery{Name: "users", Query: "SELECT 1"}); !errors.Is(err, ErrorSavedQueryExists) {
+		t.Errorf("got %v, want %v", err, ErrorSavedQueryExists)
+	}
+	if _, err := s.SaveQuery(ctx, "other", models.SavedQuery{Name: "users", Query: "SELECT 1"}); err != nil {
+		t.Errorf("same name on another connection: %s", err)
+	}
+
+	saved.Query = "SELECT id FROM users"
+	if _, err := s.UpdateSavedQuery(ctx, "other", saved); !errors.Is(err, ErrorSavedQueryNotFound) {
+		t.Errorf("update through another connection: got %v, want %v", err, ErrorSavedQueryNotFound)
+	}
+	if _, err := s.UpdateSavedQuery(ctx, "default", saved); err != nil {
+		t.Fatal(err)
+	}
+	items, err := s.ListSavedQueries(ctx, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Query != "SELECT id FROM users" {
+		t.Fatalf("got %+v", items)
+	}
+
+	if err := s.DeleteSavedQuery(ctx, "default", saved.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteSavedQuery(ctx, "default", saved.ID); !errors.Is(err, ErrorSavedQueryNotFound) {
+		t.Errorf("got %v, want %v", err, ErrorSavedQueryNotFound)
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+
+	err := s.SetPreferences(ctx, "jo", map[string]json.RawMessage{
+		"theme":    json.RawMessage(`"dark"`),
+		"pageSize": json.RawMessage(`50`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetPreferences(ctx, "jo", map[string]json.RawMessage{
+		"theme":    json.RawMessage(`"light"`),
+		"pageSize": json.RawMessage(`null`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefs, err := s.Preferences(ctx, "jo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefs) != 1 || string(prefs["theme"]) != `"light"` {
+		t.Errorf("got %s", prefs)
+	}
+	if prefs, err := s.Preferences(ctx, "ana"); err != nil || len(prefs) != 0 {
+		t.Errorf("other user: got %s, %v", prefs, err)
+	}
+}
@@ -2,11 +2,13 @@
 package utils
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/biisal/rowsql/configs"
 	"github.com/biisal/rowsql/internal/logger"
@@ -24,6 +26,22 @@ func IsSafeIdentifier(s string) bool {
 	return true
 }
 
+// NormalizeNumber turns numbers decoded with json.Decoder.UseNumber into values
+// every driver can bind.
+func NormalizeNumber(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
 func ReplaceTildeWithHomeDir(text string) (string, error) {
 	if strings.HasPrefix(text, "~") {
 		homeDir, homeErr := os.UserHomeDir()
@@ -36,6 +54,72 @@ func ReplaceTildeWithHomeDir(text string) (string, error) {
 	return text, nil
 }
 
+// ReadOnlyDBString makes every session opened with dbString read-only. Postgres
+// gets default_transaction_read_only, the same as SET SESSION CHARACTERISTICS
+// AS TRANSACTION READ ONLY, MySQL gets transaction_read_only set on connect and
+// SQLite is opened with mode=ro and query_only.
+func ReadOnlyDBString(driver configs.Driver, dbString string) (string, error) {
+	switch driver {
+	case configs.DriverPostgres:
+		return setPostgresParam(dbString, "default_transaction_read_only", "on")
+	case configs.DriverMySQL:
+		return appendParams(dbString, "transaction_read_only=1"), nil
+	case configs.DriverSQLite:
+		if dbString == ":memory:" {
+			return "file::memory:?_pragma=query_only(1)", nil
+		}
+		if rest, ok := strings.CutPrefix(dbString, "sqlite://"); ok {
+			dbString = rest
+		}
+		if !strings.HasPrefix(dbString, "file:") {
+			dbString = "file:" + dbString
+		}
+		return appendParams(dbString, "mode=ro&_pragma=query_only(1)"), nil
+	}
+	return "", fmt.Errorf("read-only mode is not supported for %s", driver)
+}
+
+// StatementTimeoutDBString has the database itself stop every statement of a
+// session opened with dbString after timeout: Postgres with statement_timeout,
+// MySQL with max_execution_time, which only covers SELECT. SQLite has no such
+// setting and only stops when the context of a query is done.
+func StatementTimeoutDBString(driver configs.Driver, dbString string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return dbString, nil
+	}
+	ms := strconv.FormatInt(timeout.Milliseconds(), 10)
+	switch driver {
+	case configs.DriverPostgres:
+		return setPostgresParam(dbString, "statement_timeout", ms)
+	case configs.DriverMySQL:
+		return appendParams(dbString, "max_execution_time="+ms), nil
+	}
+	return dbString, nil
+}
+
+// setPostgresParam adds a run-time parameter to a URL or key/value connection string.
+func setPostgresParam(dbString, key, value string) (string, error) {
+	lower := strings.ToLower(dbString)
+	if !strings.HasPrefix(lower, "postgres://") && !strings.HasPrefix(lower, "postgresql://") {
+		return dbString + " " + key + "=" + value, nil
+	}
+	u, err := url.Parse(dbString)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func appendParams(dbString, params string) string {
+	if strings.Contains(dbString, "?") {
+		return dbString + "&" + params
+	}
+	return dbString + "?" + params
+}
+
 func isPostgresConnString(connString string) bool {
 	return strings.HasPrefix(connString, "postgres://") ||
 		strings.HasPrefix(connString, "postgresql://") ||
@@ -96,6 +180,10 @@ const (
 	jsonInput     = "json"
 )
 
+// ReferenceInput is used for foreign key columns, their values are picked
+// from the referenced table.
+const ReferenceInput = "reference"
+
 var dataTypeMap = map[string]string{
 	"smallint":         numberInput,
 	"integer":          numberInput,
@@ -200,16 +288,3 @@ func GetInputType(dbType string) string {
 	logger.Warning("unknown data type: %s", dbType)
 	return textInput
 }
-
-func MakeRowHash(data []any) (string, error) {
-	h := sha256.New()
-	if _, err := fmt.Fprint(h, data); err != nil {
-		logger.Error("failed to hash data: %v", err)
-		return "", err
-	}
-	hashBytes := h.Sum(nil)
-	fullHash := hex.EncodeToString(hashBytes)
-
-	shortHash := fullHash[:8]
-	return shortHash, nil
-}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/biisal/rowsql/configs"
+)
+
+func TestReadOnlyDBString(t *testing.T) {
+	tests := []struct {
+		driver   configs.Driver
+		dbString string
+		want     string
+	}{
+		{configs.DriverPostgres, "postgres://u:p@localhost:5432/db?sslmode=disable", "postgres://u:p@localhost:5432/db?default_transaction_read_only=on&sslmode=disable"},
+		{configs.DriverPostgres, "host=localhost dbname=db", "host=localhost dbname=db default_transaction_read_only=on"},
+		{configs.DriverMySQL, "u:p@tcp(localhost:3306)/db", "u:p@tcp(localhost:3306)/db?transaction_read_only=1"},
+		{configs.DriverMySQL, "u:p@tcp(localhost:3306)/db?parseTime=true", "u:p@tcp(localhost:3306)/db?parseTime=true&transaction_read_only=1"},
+		{configs.DriverSQLite, "/tmp/test.db", "file:/tmp/test.db?mode=ro&_pragma=query_only(1)"},
+		{configs.DriverSQLite, "file:test.db?cache=shared", "file:test.db?cache=shared&mode=ro&_pragma=query_only(1)"},
+		{configs.DriverSQLite, ":memory:", "file::memory:?_pragma=query_only(1)"},
+	}
+	for _, tt := range tests {
+		got, err := ReadOnlyDBString(tt.driver, tt.dbString)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.dbString, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.dbString, got, tt.want)
+		}
+	}
+}
+
+func TestStatementTimeoutDBString(t *testing.T) {
+	tests := []struct {
+		driver   configs.Driver
+		dbString string
+		timeout  time.Duration
+		want     string
+	}{
+		{configs.DriverPostgres, "postgres://u:p@localhost:5432/db?sslmode=disable", time.Minute, "postgres://u:p@localhost:5432/db?sslmode=disable&statement_timeout=60000"},
+		{configs.DriverPostgres, "host=localhost dbname=db", 1500 * time.Millisecond, "host=localhost dbname=db statement_timeout=1500"},
+		{configs.DriverMySQL, "u:p@tcp(localhost:3306)/db?parseTime=true", 30 * time.Second, "u:p@tcp(localhost:3306)/db?parseTime=true&max_execution_time=30000"},
+		{configs.DriverSQLite, "/tmp/test.db", time.Minute, "/tmp/test.db"},
+		{configs.DriverPostgres, "host=localhost dbname=db", 0, "host=localhost dbname=db"},
+	}
+	for _, tt := range tests {
+		got, err := StatementTimeoutDBString(tt.driver, tt.dbString, tt.timeout)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.dbString, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.dbString, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := []struct {
+		in   any
+		want any
+	}{
+		{in: json.Number("9007199254740993"), want: int64(9007199254740993)},
+		{in: json.Number("1.5"), want: 1.5},
+		{in: json.Number("1e400"), want: "1e400"},
+		{in: "7", want: "7"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeNumber(tt.in); got != tt.want {
+			t.Errorf("NormalizeNumber(%#v) = %#v want %#v", tt.in, got, tt.want)
+		}
+	}
+}